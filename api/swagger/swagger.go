@@ -0,0 +1,16 @@
+// Package swagger embeds OpenAPI documents generated from proto files of the API.
+package swagger
+
+import (
+	_ "embed"
+)
+
+// V1 is OpenAPI 2.0 document of REST API v1 generated from api/proto/v1
+//
+//go:embed v1/todo-service.swagger.json
+var V1 []byte
+
+// V2 is OpenAPI 2.0 document of REST API v2 generated from api/proto/v2
+//
+//go:embed v2/todo-service.swagger.json
+var V2 []byte
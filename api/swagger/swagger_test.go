@@ -0,0 +1,103 @@
+package swagger
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// httpRule is HTTP binding of RPC in gRPC API configuration
+type httpRule struct {
+	Selector           string     `yaml:"selector"`
+	Get                string     `yaml:"get"`
+	Post               string     `yaml:"post"`
+	Put                string     `yaml:"put"`
+	Patch              string     `yaml:"patch"`
+	Delete             string     `yaml:"delete"`
+	AdditionalBindings []httpRule `yaml:"additional_bindings"`
+}
+
+// bindings returns HTTP methods and paths of rule and its additional bindings
+func (r httpRule) bindings() map[string]string {
+	b := map[string]string{}
+	for method, path := range map[string]string{"get": r.Get, "post": r.Post, "put": r.Put, "patch": r.Patch, "delete": r.Delete} {
+		if len(path) > 0 {
+			b[path+" "+method] = r.Selector
+		}
+	}
+	for _, a := range r.AdditionalBindings {
+		for k := range a.bindings() {
+			b[k] = r.Selector
+		}
+	}
+	return b
+}
+
+// pathParam is field path of path parameter without its pattern
+var pathParam = regexp.MustCompile(`{([a-z0-9_.]+)`)
+
+// jsonPath returns path with parameters named by JSON names of fields as in OpenAPI document
+func jsonPath(path string) string {
+	return pathParam.ReplaceAllStringFunc(path, func(p string) string {
+		parts := strings.Split(p, "_")
+		for i := 1; i < len(parts); i++ {
+			if len(parts[i]) > 0 {
+				parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+			}
+		}
+		return strings.Join(parts, "")
+	})
+}
+
+func TestSpecs(t *testing.T) {
+	for _, tt := range []struct {
+		version string
+		spec    []byte
+	}{
+		{version: "v1", spec: V1},
+		{version: "v2", spec: V2},
+	} {
+		t.Run(tt.version, func(t *testing.T) {
+			var doc struct {
+				Swagger string                                `json:"swagger"`
+				Info    struct{ Version string }              `json:"info"`
+				Paths   map[string]map[string]json.RawMessage `json:"paths"`
+			}
+			if err := json.Unmarshal(tt.spec, &doc); err != nil {
+				t.Fatalf("document is not JSON: %v", err)
+			}
+			if doc.Swagger != "2.0" || !strings.HasPrefix(doc.Info.Version, strings.TrimPrefix(tt.version, "v")+".") {
+				t.Errorf("document is swagger %s of API %s, want 2.0 of %s", doc.Swagger, doc.Info.Version, tt.version)
+			}
+
+			// every HTTP binding of the gateway is documented, so the document is regenerated with the API
+			data, err := os.ReadFile("../proto/" + tt.version + "/todo-service.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var config struct {
+				HTTP struct {
+					Rules []httpRule `yaml:"rules"`
+				} `yaml:"http"`
+			}
+			if err := yaml.Unmarshal(data, &config); err != nil {
+				t.Fatal(err)
+			}
+			if len(config.HTTP.Rules) == 0 {
+				t.Fatal("API configuration has no HTTP rules")
+			}
+			for _, r := range config.HTTP.Rules {
+				for binding, selector := range r.bindings() {
+					f := strings.Fields(binding)
+					if _, ok := doc.Paths[jsonPath(f[0])][f[1]]; !ok {
+						t.Errorf("%s %s of %s is not documented", strings.ToUpper(f[1]), jsonPath(f[0]), selector)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+// todoFlags are flags of task fields
+type todoFlags struct {
+	title       string
+	description string
+	reminder    string
+	due         string
+	status      string
+	priority    string
+	labels      string
+	list        int64
+	parent      int64
+	recurrence  string
+	timeZone    string
+}
+
+// register adds flags of task fields to flag set
+func (f *todoFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.title, "title", "", "Title")
+	fs.StringVar(&f.description, "description", "", "Description")
+	fs.StringVar(&f.reminder, "reminder", "", "Reminder time: RFC 3339, 'now' or duration from now like '+2h'")
+	fs.StringVar(&f.due, "due", "", "Due date: RFC 3339, 'now' or duration from now like '+48h'")
+	fs.StringVar(&f.status, "status", "", "Status: OPEN, IN_PROGRESS, DONE or CANCELLED")
+	fs.StringVar(&f.priority, "priority", "", "Priority: LOW, MEDIUM, HIGH or URGENT")
+	fs.StringVar(&f.labels, "labels", "", "Comma separated labels")
+	fs.Int64Var(&f.list, "list", 0, "ID of the list")
+	fs.Int64Var(&f.parent, "parent", 0, "ID of the parent task")
+	fs.StringVar(&f.recurrence, "recurrence", "", "iCalendar recurrence rule, e.g. 'FREQ=WEEKLY;BYDAY=MO'")
+	fs.StringVar(&f.timeZone, "tz", "", "IANA time zone of recurrence, e.g. 'Europe/Berlin'")
+}
+
+// apply sets fields of task from flags set on command line
+func (f *todoFlags) apply(fs *flag.FlagSet, td *v1.Todo) error {
+	var err error
+	fs.Visit(func(fl *flag.Flag) {
+		if err != nil {
+			return
+		}
+		switch fl.Name {
+		case "title":
+			td.Title = f.title
+		case "description":
+			td.Description = f.description
+		case "reminder":
+			t, e := parseTime(f.reminder)
+			if e == nil {
+				td.Reminder, e = ptypes.TimestampProto(t)
+			}
+			err = e
+		case "due":
+			if len(f.due) == 0 {
+				td.DueDate = nil
+				return
+			}
+			t, e := parseTime(f.due)
+			if e == nil {
+				td.DueDate, e = ptypes.TimestampProto(t)
+			}
+			err = e
+		case "status":
+			v, ok := v1.Todo_Status_value[strings.ToUpper(f.status)]
+			if !ok {
+				err = usagef("unknown status '%s'", f.status)
+			}
+			td.Status = v1.Todo_Status(v)
+		case "priority":
+			v, ok := v1.Todo_Priority_value[strings.ToUpper(f.priority)]
+			if !ok {
+				err = usagef("unknown priority '%s'", f.priority)
+			}
+			td.Priority = v1.Todo_Priority(v)
+		case "labels":
+			td.Labels = splitLabels(f.labels)
+		case "list":
+			td.ListId = f.list
+		case "parent":
+			td.ParentId = f.parent
+		case "recurrence":
+			td.Recurrence = f.recurrence
+		case "tz":
+			td.TimeZone = f.timeZone
+		}
+	})
+	return err
+}
+
+// isSet returns true if flag is set on command line
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(fl *flag.Flag) {
+		if fl.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// splitLabels returns labels of comma separated list
+func splitLabels(s string) []string {
+	labels := []string{}
+	for _, l := range strings.Split(s, ",") {
+		if l = strings.TrimSpace(l); len(l) > 0 {
+			labels = append(labels, l)
+		}
+	}
+	return labels
+}
+
+// parseID returns the only argument of command as task ID
+func parseID(fs *flag.FlagSet) (int64, error) {
+	if fs.NArg() != 1 {
+		return 0, usagef("%s requires ID of the task", fs.Name())
+	}
+	id, err := strconv.ParseInt(fs.Arg(0), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, usagef("invalid ID '%s'", fs.Arg(0))
+	}
+	return id, nil
+}
+
+// printTodo reads task and writes it
+func (c *cli) printTodo(ctx context.Context, id int64) error {
+	res, err := c.client.Read(ctx, &v1.ReadRequest{Id: id})
+	if err != nil {
+		return err
+	}
+	return c.printMessage(res, func(w *tabwriter.Writer) {
+		writeTodo(w, res.Todo)
+	})
+}
+
+var createCommand = &command{
+	name:  "create",
+	usage: "[flags] [title]",
+	help:  "Create todo task, reminder is now if not specified",
+	run: func(c *cli, cmd *command, args []string) error {
+		var f todoFlags
+		requestID := ""
+		fs := cmd.flagSet()
+		f.register(fs)
+		fs.StringVar(&requestID, "request-id", "", "Idempotency key, retry with the same key doesn't create another task, random if not specified")
+		if err := cmd.parse(fs, args); err != nil {
+			return err
+		}
+
+		td := &v1.Todo{Title: strings.Join(fs.Args(), " ")}
+		if err := f.apply(fs, td); err != nil {
+			return err
+		}
+		if len(td.Title) == 0 {
+			return usagef("title is required")
+		}
+		if td.Reminder == nil {
+			td.Reminder = ptypes.TimestampNow()
+		}
+
+		ctx, cancel := c.context()
+		defer cancel()
+
+		id, err := c.sdk.Create(ctx, td, requestID)
+		if err != nil {
+			return err
+		}
+		return c.printTodo(ctx, id)
+	},
+}
+
+var getCommand = &command{
+	name:  "get",
+	usage: "<id>",
+	help:  "Read todo task",
+	run: func(c *cli, cmd *command, args []string) error {
+		fs := cmd.flagSet()
+		if err := cmd.parse(fs, args); err != nil {
+			return err
+		}
+		id, err := parseID(fs)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := c.context()
+		defer cancel()
+
+		return c.printTodo(ctx, id)
+	},
+}
+
+var listCommand = &command{
+	name:  "list",
+	usage: "[flags]",
+	help:  "List todo tasks matched by filter",
+	run: func(c *cli, cmd *command, args []string) error {
+		fs := cmd.flagSet()
+		filter := fs.String("filter", "", `Filter expression, e.g. 'status = "OPEN" AND labels: "work"'`)
+		orderBy := fs.String("order-by", "", "Sort order, e.g. 'due_date desc'")
+		if err := cmd.parse(fs, args); err != nil {
+			return err
+		}
+		if fs.NArg() > 0 {
+			return usagef("list doesn't take arguments")
+		}
+
+		ctx, cancel := c.context()
+		defer cancel()
+
+		res, err := c.client.ListTodos(ctx, &v1.ListTodosRequest{Filter: *filter, OrderBy: *orderBy})
+		if err != nil {
+			return err
+		}
+		return c.printMessage(res, func(w *tabwriter.Writer) {
+			writeTodos(w, res.Todos)
+		})
+	},
+}
+
+var searchCommand = &command{
+	name:  "search",
+	usage: "[flags] <words>",
+	help:  "Search todo tasks by words in title and description",
+	run: func(c *cli, cmd *command, args []string) error {
+		fs := cmd.flagSet()
+		limit := fs.Int("limit", 0, "Maximum number of results, 20 if not specified")
+		if err := cmd.parse(fs, args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			return usagef("search requires words to search")
+		}
+
+		ctx, cancel := c.context()
+		defer cancel()
+
+		res, err := c.client.Search(ctx, &v1.SearchRequest{
+			Query:            strings.Join(fs.Args(), " "),
+			Limit:            int32(*limit),
+			HighlightPreTag:  "*",
+			HighlightPostTag: "*",
+		})
+		if err != nil {
+			return err
+		}
+		return c.printMessage(res, func(w *tabwriter.Writer) {
+			writeRow(w, append([]string{"SCORE"}, todoColumns...)...)
+			for _, r := range res.Results {
+				writeRow(w, append([]string{strconv.FormatFloat(r.Score, 'f', 2, 64)}, todoRow(r.Todo)...)...)
+			}
+		})
+	},
+}
+
+var updateCommand = &command{
+	name:  "update",
+	usage: "<id> [flags]",
+	help:  "Update fields of todo task set by flags",
+	run: func(c *cli, cmd *command, args []string) error {
+		var f todoFlags
+		fs := cmd.flagSet()
+		f.register(fs)
+		if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+			return usagef("update requires ID of the task before flags")
+		}
+		if err := cmd.parse(fs, args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() > 0 {
+			return usagef("unexpected arguments %v", fs.Args())
+		}
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || id <= 0 {
+			return usagef("invalid ID '%s'", args[0])
+		}
+
+		ctx, cancel := c.context()
+		defer cancel()
+
+		res, err := c.client.Read(ctx, &v1.ReadRequest{Id: id})
+		if err != nil {
+			return err
+		}
+		td := res.Todo
+		listID, parentID := td.ListId, td.ParentId
+		if err := f.apply(fs, td); err != nil {
+			return err
+		}
+
+		if _, err := c.client.Update(ctx, &v1.UpdateRequest{Todo: td}); err != nil {
+			return err
+		}
+
+		// labels, list and parent are changed by their own calls
+		if isSet(fs, "labels") {
+			if _, err := c.sdk.SetLabels(ctx, id, td.Labels); err != nil {
+				return err
+			}
+		}
+		if isSet(fs, "list") && td.ListId != listID {
+			if _, err := c.client.MoveTodo(ctx, &v1.MoveTodoRequest{Id: id, ToListId: td.ListId}); err != nil {
+				return err
+			}
+		}
+		if isSet(fs, "parent") && td.ParentId != parentID {
+			if _, err := c.client.SetParent(ctx, &v1.SetParentRequest{Id: id, ParentId: td.ParentId}); err != nil {
+				return err
+			}
+		}
+
+		return c.printTodo(ctx, id)
+	},
+}
+
+var deleteCommand = &command{
+	name:  "delete",
+	usage: "<id>",
+	help:  "Delete todo task with its subtasks",
+	run: func(c *cli, cmd *command, args []string) error {
+		fs := cmd.flagSet()
+		if err := cmd.parse(fs, args); err != nil {
+			return err
+		}
+		id, err := parseID(fs)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := c.context()
+		defer cancel()
+
+		res, err := c.client.Delete(ctx, &v1.DeleteRequest{Id: id})
+		if err != nil {
+			return err
+		}
+		return c.printMessage(res, func(w *tabwriter.Writer) {
+			writeRow(w, "DELETED")
+			writeRow(w, strconv.FormatInt(res.Deleted, 10))
+		})
+	},
+}
+
+var watchCommand = &command{
+	name:  "watch",
+	usage: "[flags]",
+	help:  "Print changes of todo tasks until interrupted",
+	run: func(c *cli, cmd *command, args []string) error {
+		fs := cmd.flagSet()
+		listID := fs.Int64("list", 0, "ID of the list to watch, all lists if not specified")
+		resumeToken := fs.String("resume-token", "", "Resume token of the last received event")
+		if err := cmd.parse(fs, args); err != nil {
+			return err
+		}
+		if fs.NArg() > 0 {
+			return usagef("watch doesn't take arguments")
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+
+		stream, err := c.client.WatchTodos(ctx, &v1.WatchTodosRequest{
+			ResumeToken: *resumeToken,
+			ListId:      *listID,
+		})
+		if err != nil {
+			return err
+		}
+
+		if c.cfg.Output == "table" {
+			fmt.Fprintf(c.out, "%-25s  %-8s  %8s  %-30s  %s\n", "TIME", "TYPE", "ID", "TITLE", "RESUME TOKEN")
+		}
+		for {
+			res, err := stream.Recv()
+			if err == io.EOF || status.Code(err) == codes.Canceled {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			ev := res.Event
+			if err := c.printLine(res, func(w *tabwriter.Writer) {
+				fmt.Fprintf(w, "%-25s  %-8s  %8d  %-30s  %s\n", formatTime(ev.EventTime), ev.Type, ev.Todo.GetId(), ev.Todo.GetTitle(), ev.ResumeToken)
+			}); err != nil {
+				return err
+			}
+		}
+	},
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/devararishivian/go-grpc/pkg/client"
+)
+
+// Config is configuration of the client read from YAML file, e.g.
+//
+//	server: todo.example.com:9090
+//	output: table
+//	timeout: 5s
+//	tls: true
+//	ca_file: /etc/todo/ca.pem
+//	cert_file: /etc/todo/client.pem
+//	key_file: /etc/todo/client-key.pem
+//	token: secret
+//
+// Token is sent over TLS only, insecure_credentials: true sends it to local server without TLS.
+type Config struct {
+	// Server is gRPC server in format host:port
+	Server string `yaml:"server"`
+	// Output is output format: table, json or yaml
+	Output string `yaml:"output"`
+	// Timeout is timeout of requests
+	Timeout time.Duration `yaml:"timeout"`
+
+	// TLS turns on TLS, server certificate is verified with CAFile or system roots
+	TLS bool `yaml:"tls"`
+	// CAFile is PEM file of CA certificates to verify server certificate
+	CAFile string `yaml:"ca_file"`
+	// CertFile and KeyFile are PEM files of client certificate and its key to authenticate by TLS
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+
+	// Token is sent as bearer token in authorization metadata if it's not empty
+	Token string `yaml:"token"`
+	// InsecureCredentials allows Token to be sent without TLS, for local development only
+	InsecureCredentials bool `yaml:"insecure_credentials"`
+}
+
+// defaultConfigPath returns path of config file if it's not specified
+func defaultConfigPath() string {
+	if p := os.Getenv("TODO_CONFIG"); len(p) > 0 {
+		return p
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "todo", "config.yaml")
+}
+
+// LoadConfig reads config file, default config file may not exist
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{
+		Output:  "table",
+		Timeout: 5 * time.Second,
+	}
+
+	explicit := len(path) > 0
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	if len(path) == 0 {
+		return cfg, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("failed to read config: %v", err)
+	}
+	if err := yaml.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config '%s': %v", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Validate checks configuration is complete
+func (cfg *Config) Validate() error {
+	if len(cfg.Server) == 0 {
+		return fmt.Errorf("server is not specified, set it in config or with -server flag")
+	}
+	switch cfg.Output {
+	case "table", "json", "yaml":
+	default:
+		return fmt.Errorf("invalid output format '%s', expected table, json or yaml", cfg.Output)
+	}
+	if cfg.Timeout <= 0 {
+		return fmt.Errorf("invalid timeout '%s'", cfg.Timeout)
+	}
+	if (len(cfg.CertFile) > 0) != (len(cfg.KeyFile) > 0) {
+		return fmt.Errorf("cert_file and key_file must be specified together")
+	}
+	if len(cfg.Token) > 0 && !cfg.TLS && !cfg.InsecureCredentials {
+		return fmt.Errorf("token is sent over TLS only, turn on tls or set insecure_credentials for local server")
+	}
+	return nil
+}
+
+// Dial returns client connected to the server
+func (cfg *Config) Dial() (*client.Client, error) {
+	opts := []client.Option{
+		client.WithTimeout(cfg.Timeout),
+		client.WithBearerToken(cfg.Token),
+	}
+	if cfg.InsecureCredentials {
+		opts = append(opts, client.WithInsecureCredentials())
+	}
+
+	if cfg.TLS {
+		tc := &tls.Config{}
+		if len(cfg.CAFile) > 0 {
+			pem, err := os.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA file: %v", err)
+			}
+			tc.RootCAs = x509.NewCertPool()
+			if !tc.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates in CA file '%s'", cfg.CAFile)
+			}
+		}
+		if len(cfg.CertFile) > 0 {
+			cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load client certificate: %v", err)
+			}
+			tc.Certificates = []tls.Certificate{cert}
+		}
+		opts = append(opts, client.WithTLS(tc))
+	}
+
+	return client.New(cfg.Server, opts...)
+}
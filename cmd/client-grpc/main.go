@@ -1,104 +1,259 @@
+// Command client-grpc is command-line client of Todo service.
+//
+// Usage:
+//
+//	todo [global flags] <command> [flags] [arguments]
+//
+// Commands are create, get, list, search, update, delete, watch and tui (interactive mode), run "todo <command> -h" for their flags.
+// Server address, TLS and credentials are read from config file, see config.go.
+//
+// Exit codes:
+//
+//	0       success
+//	1       error which is not returned by the server, e.g. connection failure
+//	2-16    gRPC status code number of error returned by the server, e.g. 5 for NotFound
+//	64      invalid usage
+//	78      invalid configuration
+//	130     call cancelled, e.g. watch interrupted with Ctrl+C
+//
+// Canceled status (1) has its own code, so it isn't mistaken for other errors.
 package main
 
 import (
 	"context"
+	"errors"
 	"flag"
-	"log"
+	"fmt"
+	"io"
+	"os"
+	"strings"
 	"time"
 
-	"github.com/golang/protobuf/ptypes"
-	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+	"github.com/devararishivian/go-grpc/pkg/client"
 )
 
 const (
-	API_VERSION = "v1"
+	// exitError is exit code of errors which are not gRPC status errors
+	exitError = 1
+	// exitUsage is exit code of invalid command line
+	exitUsage = 64
+	// exitConfig is exit code of invalid configuration
+	exitConfig = 78
+	// exitCanceled is exit code of cancelled call, the same as of shell command interrupted by SIGINT
+	exitCanceled = 130
 )
 
+// usageError is invalid command line
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// usagef returns usage error
+func usagef(format string, args ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// configError is invalid configuration
+type configError struct {
+	err error
+}
+
+func (e *configError) Error() string {
+	return e.err.Error()
+}
+
+// cli is state shared by commands
+type cli struct {
+	cfg *Config
+	out io.Writer
+	sdk *client.Client
+	// client is generated client of sdk for calls it doesn't wrap
+	client v1.TodoServiceClient
+}
+
+// command is subcommand of the client
+type command struct {
+	name  string
+	usage string
+	help  string
+	run   func(c *cli, cmd *command, args []string) error
+}
+
+var commands = []*command{
+	createCommand,
+	getCommand,
+	listCommand,
+	searchCommand,
+	updateCommand,
+	deleteCommand,
+	watchCommand,
+	tuiCommand,
+}
+
 func main() {
-	// get configuration
-	address := flag.String("server", "", "gRPC server in format host:port")
-	flag.Parse()
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
 
-	// Set up a connection to the server.
-	conn, err := grpc.Dial(*address, grpc.WithInsecure())
-	if err != nil {
-		log.Fatalf("did not connect: %v", err)
+// run runs command line and returns exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	err := runCommand(args, stdout, stderr)
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return 0
 	}
-	defer conn.Close()
 
-	c := v1.NewTodoServiceClient(conn)
+	fmt.Fprintf(stderr, "todo: %s\n", errorMessage(err))
+	return exitCode(err)
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// runCommand parses global flags and runs command
+func runCommand(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("todo", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", "", "Config file, $TODO_CONFIG or <user config dir>/todo/config.yaml if not specified")
+	server := fs.String("server", "", "gRPC server in format host:port, overrides config")
+	output := fs.String("output", "", "Output format: table, json or yaml, overrides config")
+	timeout := fs.Duration("timeout", 0, "Timeout of requests, overrides config")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: todo [global flags] <command> [flags] [arguments]\n\nCommands:\n")
+		for _, cmd := range commands {
+			fmt.Fprintf(stderr, "  %-8s %s\n", cmd.name, cmd.help)
+		}
+		fmt.Fprintf(stderr, "\nGlobal flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return usagef("%v", err)
+	}
 
-	t := time.Now().In(time.UTC)
-	reminder, _ := ptypes.TimestampProto(t)
-	pfx := t.Format(time.RFC3339Nano)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return usagef("command is required")
+	}
 
-	// Call Create
-	req1 := v1.CreateRequest{
-		Api: API_VERSION,
-		Todo: &v1.Todo{
-			Title:       "title (" + pfx + ")",
-			Description: "description (" + pfx + ")",
-			Reminder:    reminder,
-		},
+	var cmd *command
+	for _, c := range commands {
+		if c.name == fs.Arg(0) {
+			cmd = c
+		}
 	}
-	res1, err := c.Create(ctx, &req1)
-	if err != nil {
-		log.Fatalf("Create failed: %v", err)
+	if cmd == nil {
+		fs.Usage()
+		return usagef("unknown command '%s'", fs.Arg(0))
 	}
-	log.Printf("Create result: <%+v>\n\n", res1)
 
-	id := res1.Id
-
-	// Read
-	req2 := v1.ReadRequest{
-		Api: API_VERSION,
-		Id:  id,
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		return &configError{err: err}
+	}
+	if len(*server) > 0 {
+		cfg.Server = *server
+	}
+	if len(*output) > 0 {
+		cfg.Output = *output
+	}
+	if *timeout > 0 {
+		cfg.Timeout = *timeout
+	}
+	if err := cfg.Validate(); err != nil {
+		return &configError{err: err}
 	}
-	res2, err := c.Read(ctx, &req2)
+
+	sdk, err := cfg.Dial()
 	if err != nil {
-		log.Fatalf("Read failed: %v", err)
+		return &configError{err: err}
 	}
-	log.Printf("Read result: <%+v>\n\n", res2)
+	defer sdk.Close()
 
-	// Update
-	req3 := v1.UpdateRequest{
-		Api: API_VERSION,
-		Todo: &v1.Todo{
-			Id:          res2.Todo.Id,
-			Title:       res2.Todo.Title,
-			Description: res2.Todo.Description + " + updated",
-			Reminder:    res2.Todo.Reminder,
-		},
+	c := &cli{
+		cfg:    cfg,
+		out:    stdout,
+		sdk:    sdk,
+		client: sdk.Service(),
 	}
-	res3, err := c.Update(ctx, &req3)
-	if err != nil {
-		log.Fatalf("Update failed: %v", err)
+	return cmd.run(c, cmd, fs.Args()[1:])
+}
+
+// flagSet returns flag set of command
+func (cmd *command) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: todo %s %s\n\n%s\n", cmd.name, cmd.usage, cmd.help)
+		fs.PrintDefaults()
 	}
-	log.Printf("Update result: <%+v>\n\n", res3)
+	return fs
+}
 
-	// Call ReadAll
-	req4 := v1.ReadAllRequest{
-		Api: API_VERSION,
+// parse parses flags of command
+func (cmd *command) parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return usagef("%v", err)
 	}
-	res4, err := c.ReadAll(ctx, &req4)
-	if err != nil {
-		log.Fatalf("ReadAll failed: %v", err)
+	return nil
+}
+
+// context returns context of command with timeout
+func (c *cli) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.cfg.Timeout)
+}
+
+// errorMessage returns message of error, gRPC status errors are prefixed with code
+func errorMessage(err error) error {
+	if st, ok := status.FromError(err); ok && st.Code() != codes.OK {
+		return fmt.Errorf("%s: %s", st.Code(), st.Message())
 	}
-	log.Printf("ReadAll result: <%+v>\n\n", res4)
+	return err
+}
 
-	// Delete
-	req5 := v1.DeleteRequest{
-		Api: API_VERSION,
-		Id:  id,
+// exitCode returns exit code of error
+func exitCode(err error) int {
+	var ue *usageError
+	if errors.As(err, &ue) {
+		return exitUsage
 	}
-	res5, err := c.Delete(ctx, &req5)
-	if err != nil {
-		log.Fatalf("Delete failed: %v", err)
+	var ce *configError
+	if errors.As(err, &ce) {
+		return exitConfig
+	}
+	if errors.Is(err, context.Canceled) || status.Code(err) == codes.Canceled {
+		return exitCanceled
+	}
+	if st, ok := status.FromError(err); ok && st.Code() != codes.OK {
+		return int(st.Code())
+	}
+	return exitError
+}
+
+// parseTime parses time in RFC 3339 format, "now" or duration from now like "+2h" or "-30m"
+func parseTime(s string) (time.Time, error) {
+	now := time.Now().In(time.UTC)
+	switch {
+	case s == "now":
+		return now, nil
+	case strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-"):
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return time.Time{}, usagef("invalid time '%s': %v", s, err)
+		}
+		return now.Add(d), nil
+	default:
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return time.Time{}, usagef("invalid time '%s', expected RFC 3339, 'now' or '+duration'", s)
+		}
+		return t, nil
 	}
-	log.Printf("Delete result: <%+v>\n\n", res5)
 }
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "usage", err: &usageError{msg: "missing ID"}, want: exitUsage},
+		{name: "config", err: &configError{err: errors.New("invalid address")}, want: exitConfig},
+		{name: "status", err: status.Error(codes.NotFound, "not found"), want: int(codes.NotFound)},
+		{name: "canceled status", err: status.Error(codes.Canceled, "context canceled"), want: exitCanceled},
+		{name: "canceled context", err: fmt.Errorf("watch: %w", context.Canceled), want: exitCanceled},
+		{name: "other", err: errors.New("connection refused"), want: exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
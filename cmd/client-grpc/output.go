@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+// todoColumns are columns of tasks in table output
+var todoColumns = []string{"ID", "TITLE", "STATUS", "PRIORITY", "REMINDER", "DUE", "LABELS", "LIST"}
+
+// printMessage writes response in JSON or YAML, table is written by table function
+func (c *cli) printMessage(m proto.Message, table func(w *tabwriter.Writer)) error {
+	switch c.cfg.Output {
+	case "json":
+		b, err := protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(m)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(c.out, "%s\n", b)
+		return err
+	case "yaml":
+		return writeYAML(c.out, m)
+	default:
+		w := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+		table(w)
+		return w.Flush()
+	}
+}
+
+// printLine writes message in one line of JSON, it's used for streams
+func (c *cli) printLine(m proto.Message, table func(w *tabwriter.Writer)) error {
+	switch c.cfg.Output {
+	case "json":
+		b, err := protojson.Marshal(m)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(c.out, "%s\n", b)
+		return err
+	case "yaml":
+		if _, err := io.WriteString(c.out, "---\n"); err != nil {
+			return err
+		}
+		return writeYAML(c.out, m)
+	default:
+		w := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+		table(w)
+		return w.Flush()
+	}
+}
+
+// writeYAML writes message in YAML with the same field names as JSON
+func writeYAML(w io.Writer, m proto.Message) error {
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		return err
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// writeRow writes tab separated row
+func writeRow(w io.Writer, cells ...string) {
+	fmt.Fprintln(w, strings.Join(cells, "\t"))
+}
+
+// todoRow returns cells of task in table output
+func todoRow(td *v1.Todo) []string {
+	return []string{
+		strconv.FormatInt(td.Id, 10),
+		td.Title,
+		enumName(td.Status.String(), td.Status == v1.Todo_STATUS_UNSPECIFIED),
+		enumName(td.Priority.String(), td.Priority == v1.Todo_PRIORITY_UNSPECIFIED),
+		formatTime(td.Reminder),
+		formatTime(td.DueDate),
+		strings.Join(td.Labels, ","),
+		strconv.FormatInt(td.ListId, 10),
+	}
+}
+
+// writeTodos writes table of tasks
+func writeTodos(w io.Writer, todos []*v1.Todo) {
+	writeRow(w, todoColumns...)
+	for _, td := range todos {
+		writeRow(w, todoRow(td)...)
+	}
+}
+
+// writeTodo writes all fields of task as rows of name and value
+func writeTodo(w io.Writer, td *v1.Todo) {
+	rows := [][2]string{
+		{"ID", strconv.FormatInt(td.Id, 10)},
+		{"TITLE", td.Title},
+		{"DESCRIPTION", td.Description},
+		{"STATUS", enumName(td.Status.String(), td.Status == v1.Todo_STATUS_UNSPECIFIED)},
+		{"PRIORITY", enumName(td.Priority.String(), td.Priority == v1.Todo_PRIORITY_UNSPECIFIED)},
+		{"REMINDER", formatTime(td.Reminder)},
+		{"SNOOZED UNTIL", formatTime(td.SnoozedUntil)},
+		{"DUE", formatTime(td.DueDate)},
+		{"COMPLETED", formatTime(td.CompletedAt)},
+		{"LABELS", strings.Join(td.Labels, ",")},
+		{"LIST", strconv.FormatInt(td.ListId, 10)},
+		{"PARENT", strconv.FormatInt(td.ParentId, 10)},
+		{"RECURRENCE", td.Recurrence},
+		{"TIME ZONE", td.TimeZone},
+		{"CREATED", formatTime(td.CreateTime)},
+		{"UPDATED", formatTime(td.UpdateTime)},
+	}
+	for _, r := range rows {
+		if len(r[1]) > 0 {
+			writeRow(w, r[0], r[1])
+		}
+	}
+}
+
+// enumName returns name of enum value, empty if it's unspecified
+func enumName(name string, unspecified bool) string {
+	if unspecified {
+		return ""
+	}
+	return name
+}
+
+// formatTime returns time in local time zone, empty if it's not set
+func formatTime(ts *timestamp.Timestamp) string {
+	if ts == nil {
+		return ""
+	}
+	t, err := ptypes.Timestamp(ts)
+	if err != nil {
+		return ""
+	}
+	return t.Local().Format(time.RFC3339)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// keyCode is code of key pressed in terminal, printable characters are keyRune
+type keyCode int
+
+const (
+	keyRune keyCode = iota
+	keyEnter
+	keyEsc
+	keyBackspace
+	keyDelete
+	keyTab
+	keyBacktab
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyPageUp
+	keyPageDown
+	keyCtrlC
+)
+
+// key is key pressed in terminal
+type key struct {
+	code keyCode
+	r    rune
+}
+
+// escapeKeys are escape sequences of special keys sent by terminals after ESC
+var escapeKeys = map[string]keyCode{
+	"[A":  keyUp,
+	"[B":  keyDown,
+	"[C":  keyRight,
+	"[D":  keyLeft,
+	"[H":  keyHome,
+	"[F":  keyEnd,
+	"[Z":  keyBacktab,
+	"OA":  keyUp,
+	"OB":  keyDown,
+	"OC":  keyRight,
+	"OD":  keyLeft,
+	"OH":  keyHome,
+	"OF":  keyEnd,
+	"[1~": keyHome,
+	"[3~": keyDelete,
+	"[4~": keyEnd,
+	"[5~": keyPageUp,
+	"[6~": keyPageDown,
+	"[7~": keyHome,
+	"[8~": keyEnd,
+}
+
+// terminal is terminal in raw mode with alternate screen
+type terminal struct {
+	in    *os.File
+	out   *os.File
+	state *term.State
+}
+
+// openTerminal switches terminal to raw mode and alternate screen
+func openTerminal(in, out *os.File) (*terminal, error) {
+	if !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return nil, fmt.Errorf("interactive mode requires terminal")
+	}
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to switch terminal to raw mode: %v", err)
+	}
+	t := &terminal{in: in, out: out, state: state}
+	// alternate screen, hidden cursor
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	return t, nil
+}
+
+// Close restores terminal
+func (t *terminal) Close() error {
+	fmt.Fprint(t.out, "\x1b[0m\x1b[?25h\x1b[?1049l")
+	return term.Restore(int(t.in.Fd()), t.state)
+}
+
+// size returns width and height of terminal
+func (t *terminal) size() (int, int) {
+	w, h, err := term.GetSize(int(t.out.Fd()))
+	if err != nil || w <= 0 || h <= 0 {
+		return 80, 24
+	}
+	return w, h
+}
+
+// readKeys sends keys pressed in terminal until read fails
+func (t *terminal) readKeys(keys chan<- key) {
+	buf := make([]byte, 256)
+	for {
+		n, err := t.in.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+		for _, k := range decodeKeys(buf[:n]) {
+			keys <- k
+		}
+	}
+}
+
+// decodeKeys returns keys of bytes read from terminal at once,
+// ESC followed by nothing is the Esc key, otherwise it starts escape sequence
+func decodeKeys(b []byte) []key {
+	var keys []key
+	for len(b) > 0 {
+		switch c := b[0]; {
+		case c == 0x1b:
+			if len(b) == 1 || b[1] != '[' && b[1] != 'O' {
+				keys = append(keys, key{code: keyEsc})
+				b = b[1:]
+				continue
+			}
+			// sequence ends with letter or ~
+			end := 2
+			for end < len(b) && end < 8 && b[end] != 0x1b {
+				ch := b[end]
+				end++
+				if ch == '~' || ch >= 'A' && ch <= 'Z' || ch >= 'a' && ch <= 'z' {
+					break
+				}
+			}
+			if code, ok := escapeKeys[string(b[1:end])]; ok {
+				keys = append(keys, key{code: code})
+			}
+			b = b[end:]
+		case c == '\r' || c == '\n':
+			keys = append(keys, key{code: keyEnter})
+			b = b[1:]
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, key{code: keyBackspace})
+			b = b[1:]
+		case c == '\t':
+			keys = append(keys, key{code: keyTab})
+			b = b[1:]
+		case c == 0x03:
+			keys = append(keys, key{code: keyCtrlC})
+			b = b[1:]
+		case c < 0x20:
+			// other control keys are ignored
+			b = b[1:]
+		default:
+			r, size := utf8.DecodeRune(b)
+			if r != utf8.RuneError {
+				keys = append(keys, key{code: keyRune, r: r})
+			}
+			b = b[size:]
+		}
+	}
+	return keys
+}
+
+// screen is frame drawn at once to avoid flicker
+type screen struct {
+	buf    bytes.Buffer
+	width  int
+	height int
+}
+
+// line writes text at row (1 based) padded or cut to width of screen, style is SGR parameters
+func (s *screen) line(row int, style, text string) {
+	if row < 1 || row > s.height {
+		return
+	}
+	fmt.Fprintf(&s.buf, "\x1b[%d;1H", row)
+	if len(style) > 0 {
+		fmt.Fprintf(&s.buf, "\x1b[%sm", style)
+	}
+	s.buf.WriteString(fit(text, s.width))
+	s.buf.WriteString("\x1b[0m")
+}
+
+// cursor shows cursor at row and column (1 based)
+func (s *screen) cursor(row, col int) {
+	fmt.Fprintf(&s.buf, "\x1b[%d;%dH\x1b[?25h", row, col)
+}
+
+// flush writes frame to terminal
+func (s *screen) flush(t *terminal) error {
+	_, err := t.out.Write(s.buf.Bytes())
+	return err
+}
+
+// fit returns single line text padded with spaces or cut to width
+func fit(s string, width int) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return ' '
+		}
+		return r
+	}, s)
+	n := utf8.RuneCountInString(s)
+	if n <= width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	if width <= 0 {
+		return ""
+	}
+	r := []rune(s)
+	return string(r[:width-1]) + "…"
+}
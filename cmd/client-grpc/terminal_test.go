@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []key
+	}{
+		{name: "runes", in: "aé", want: []key{{code: keyRune, r: 'a'}, {code: keyRune, r: 'é'}}},
+		{name: "controls", in: "\r\t\x7f\x03\x01", want: []key{{code: keyEnter}, {code: keyTab}, {code: keyBackspace}, {code: keyCtrlC}}},
+		{name: "esc", in: "\x1b", want: []key{{code: keyEsc}}},
+		{name: "esc before rune", in: "\x1bq", want: []key{{code: keyEsc}, {code: keyRune, r: 'q'}}},
+		{name: "arrows", in: "\x1b[A\x1bOB\x1b[Z", want: []key{{code: keyUp}, {code: keyDown}, {code: keyBacktab}}},
+		{name: "tilde sequences", in: "\x1b[3~\x1b[6~x", want: []key{{code: keyDelete}, {code: keyPageDown}, {code: keyRune, r: 'x'}}},
+		{name: "unknown sequence", in: "\x1b[15~a", want: []key{{code: keyRune, r: 'a'}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeKeys([]byte(tt.in)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeKeys(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		in    string
+		width int
+		want  string
+	}{
+		{in: "abc", width: 5, want: "abc  "},
+		{in: "abcdef", width: 4, want: "abc…"},
+		{in: "a\tb\nc", width: 5, want: "a b c"},
+		{in: "žluť", width: 4, want: "žluť"},
+		{in: "abc", width: 0, want: ""},
+	}
+	for _, tt := range tests {
+		if got := fit(tt.in, tt.width); got != tt.want {
+			t.Errorf("fit(%q, %d) = %q, want %q", tt.in, tt.width, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,755 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+const (
+	// tuiResizeInterval is interval of checking size of terminal
+	tuiResizeInterval = 250 * time.Millisecond
+	// tuiWatchMaxBackoff is maximum delay before reconnect of watch
+	tuiWatchMaxBackoff = 30 * time.Second
+)
+
+// tuiMode is what the interactive mode shows
+type tuiMode int
+
+const (
+	modeList tuiMode = iota
+	modeDetails
+	modeForm
+	modeConfirm
+	modeFilter
+	modeHelp
+)
+
+// tuiHelp is shown by '?' key
+var tuiHelp = []string{
+	"Up/k, Down/j      Move selection",
+	"PgUp, PgDn        Move selection by page",
+	"Home/g, End/G     Select first or last task",
+	"Enter             Show details of selected task",
+	"n                 Create task",
+	"e                 Edit selected task",
+	"Space/c           Complete selected task or reopen completed one",
+	"d                 Delete selected task with its subtasks",
+	"/                 Change filter expression",
+	"r                 Reload tasks",
+	"?                 Show this help",
+	"q, Ctrl-C         Quit",
+	"",
+	"In form: Tab/Down and Shift-Tab/Up move between fields, Enter saves, Esc cancels.",
+	"Tasks are reloaded when they are changed on the server.",
+}
+
+// formField is text input of form
+type formField struct {
+	label string
+	hint  string
+	value []rune
+	pos   int
+}
+
+// todoForm is form to create or edit task
+type todoForm struct {
+	// id is ID of edited task, 0 for new task
+	id     int64
+	fields []*formField
+	focus  int
+}
+
+// watchState is state of watch reported to interactive mode, err is nil when stream is connected
+type watchState struct {
+	err error
+}
+
+// tui is state of interactive mode
+type tui struct {
+	c       *cli
+	term    *terminal
+	filter  string
+	orderBy string
+	listID  int64
+
+	todos  []*v1.Todo
+	cursor int
+	offset int
+	width  int
+	height int
+
+	mode    tuiMode
+	form    *todoForm
+	input   *formField
+	message string
+	failed  bool
+	live    string
+}
+
+var tuiCommand = &command{
+	name:  "tui",
+	usage: "[flags]",
+	help:  "Browse and edit todo tasks interactively, tasks are refreshed on changes",
+	run: func(c *cli, cmd *command, args []string) error {
+		fs := cmd.flagSet()
+		filter := fs.String("filter", "", `Filter expression, e.g. 'status = "OPEN"'`)
+		orderBy := fs.String("order-by", "", "Sort order, e.g. 'due_date desc'")
+		listID := fs.Int64("list", 0, "ID of the list to show, all lists if not specified")
+		if err := cmd.parse(fs, args); err != nil {
+			return err
+		}
+		if fs.NArg() > 0 {
+			return usagef("tui doesn't take arguments")
+		}
+
+		t := &tui{
+			c:       c,
+			filter:  *filter,
+			orderBy: *orderBy,
+			listID:  *listID,
+			live:    "connecting",
+		}
+		// check filter before terminal is switched to raw mode
+		if err := t.reload(); err != nil {
+			return err
+		}
+
+		term, err := openTerminal(os.Stdin, os.Stdout)
+		if err != nil {
+			return err
+		}
+		defer term.Close()
+		t.term = term
+
+		return t.loop()
+	},
+}
+
+// loop handles keys and changes until user quits
+func (t *tui) loop() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	keys := make(chan key)
+	go t.term.readKeys(keys)
+
+	// changes has buffer of one to merge changes made while tasks are reloaded
+	changes := make(chan struct{}, 1)
+	states := make(chan watchState)
+	go t.watch(ctx, changes, states)
+
+	ticker := time.NewTicker(tuiResizeInterval)
+	defer ticker.Stop()
+
+	t.width, t.height = t.term.size()
+	for {
+		if err := t.draw(); err != nil {
+			return err
+		}
+
+		select {
+		case k, ok := <-keys:
+			if !ok || !t.handleKey(k) {
+				return nil
+			}
+		case <-changes:
+			if err := t.reload(); err != nil {
+				t.setError(err)
+			}
+		case s := <-states:
+			if s.err != nil {
+				t.live = "disconnected: " + errorMessage(s.err).Error()
+			} else {
+				t.live = "live"
+			}
+		case <-ticker.C:
+			w, h := t.term.size()
+			if w == t.width && h == t.height {
+				continue
+			}
+			t.width, t.height = w, h
+		}
+	}
+}
+
+// watch notifies about changes of tasks, it reconnects with resume token if stream fails
+func (t *tui) watch(ctx context.Context, changes chan<- struct{}, states chan<- watchState) {
+	resumeToken := ""
+	backoff := time.Second
+	report := func(err error) bool {
+		select {
+		case states <- watchState{err: err}:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for {
+		stream, err := t.c.client.WatchTodos(ctx, &v1.WatchTodosRequest{
+			ResumeToken: resumeToken,
+			ListId:      t.listID,
+		})
+		if err == nil {
+			if !report(nil) {
+				return
+			}
+			for {
+				var res *v1.WatchTodosResponse
+				res, err = stream.Recv()
+				if err != nil {
+					break
+				}
+				resumeToken = res.Event.GetResumeToken()
+				backoff = time.Second
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+		if ctx.Err() != nil || status.Code(err) == codes.Canceled {
+			return
+		}
+		if !report(err) {
+			return
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		if backoff *= 2; backoff > tuiWatchMaxBackoff {
+			backoff = tuiWatchMaxBackoff
+		}
+	}
+}
+
+// reload reads tasks matched by filter and keeps selected task selected
+func (t *tui) reload() error {
+	ctx, cancel := t.c.context()
+	defer cancel()
+
+	filter := t.filter
+	if t.listID > 0 {
+		filter = fmt.Sprintf("list_id = %d", t.listID)
+		if len(t.filter) > 0 {
+			filter += " AND (" + t.filter + ")"
+		}
+	}
+	res, err := t.c.client.ListTodos(ctx, &v1.ListTodosRequest{Filter: filter, OrderBy: t.orderBy})
+	if err != nil {
+		return err
+	}
+
+	selected := int64(0)
+	if td := t.selected(); td != nil {
+		selected = td.Id
+	}
+	t.todos = res.Todos
+	for i, td := range t.todos {
+		if td.Id == selected {
+			t.cursor = i
+		}
+	}
+	t.move(0)
+	return nil
+}
+
+// selected returns selected task, nil if there are no tasks
+func (t *tui) selected() *v1.Todo {
+	if t.cursor < 0 || t.cursor >= len(t.todos) {
+		return nil
+	}
+	return t.todos[t.cursor]
+}
+
+// rows returns number of rows of task list
+func (t *tui) rows() int {
+	// title, column header, message and key hints
+	if n := t.height - 4; n > 0 {
+		return n
+	}
+	return 1
+}
+
+// move moves selection by delta and scrolls list to keep it visible
+func (t *tui) move(delta int) {
+	t.cursor += delta
+	if t.cursor >= len(t.todos) {
+		t.cursor = len(t.todos) - 1
+	}
+	if t.cursor < 0 {
+		t.cursor = 0
+	}
+	if t.cursor < t.offset {
+		t.offset = t.cursor
+	}
+	if rows := t.rows(); t.cursor >= t.offset+rows {
+		t.offset = t.cursor - rows + 1
+	}
+}
+
+// setMessage shows message in status line
+func (t *tui) setMessage(format string, args ...interface{}) {
+	t.message = fmt.Sprintf(format, args...)
+	t.failed = false
+}
+
+// setError shows error in status line
+func (t *tui) setError(err error) {
+	t.message = errorMessage(err).Error()
+	t.failed = true
+}
+
+// handleKey handles key in current mode, it returns false to quit
+func (t *tui) handleKey(k key) bool {
+	if k.code == keyCtrlC {
+		return false
+	}
+
+	switch t.mode {
+	case modeForm:
+		t.handleFormKey(k)
+	case modeFilter:
+		t.handleFilterKey(k)
+	case modeConfirm:
+		if k.code == keyRune && (k.r == 'y' || k.r == 'Y') {
+			t.delete()
+		} else {
+			t.setMessage("")
+		}
+		t.mode = modeList
+	case modeDetails, modeHelp:
+		if k.code == keyRune && k.r == 'q' {
+			return false
+		}
+		t.mode = modeList
+	default:
+		return t.handleListKey(k)
+	}
+	return true
+}
+
+// handleListKey handles key in task list
+func (t *tui) handleListKey(k key) bool {
+	switch k.code {
+	case keyUp:
+		t.move(-1)
+	case keyDown:
+		t.move(1)
+	case keyPageUp:
+		t.move(-t.rows())
+	case keyPageDown:
+		t.move(t.rows())
+	case keyHome:
+		t.move(-len(t.todos))
+	case keyEnd:
+		t.move(len(t.todos))
+	case keyEnter:
+		if t.selected() != nil {
+			t.mode = modeDetails
+		}
+	case keyRune:
+		switch k.r {
+		case 'q':
+			return false
+		case 'k':
+			t.move(-1)
+		case 'j':
+			t.move(1)
+		case 'g':
+			t.move(-len(t.todos))
+		case 'G':
+			t.move(len(t.todos))
+		case 'n':
+			t.openForm(nil)
+		case 'e':
+			if td := t.selected(); td != nil {
+				t.openForm(td)
+			}
+		case ' ', 'c':
+			t.toggleComplete()
+		case 'd':
+			if td := t.selected(); td != nil {
+				t.mode = modeConfirm
+				t.setMessage("Delete #%d %q with its subtasks? (y/n)", td.Id, td.Title)
+			}
+		case '/':
+			t.input = newFormField("Filter", "", t.filter)
+			t.mode = modeFilter
+		case 'r':
+			if err := t.reload(); err != nil {
+				t.setError(err)
+			} else {
+				t.setMessage("Reloaded %d tasks", len(t.todos))
+			}
+		case '?':
+			t.mode = modeHelp
+		}
+	}
+	return true
+}
+
+// handleFilterKey handles key while filter is edited
+func (t *tui) handleFilterKey(k key) {
+	switch k.code {
+	case keyEsc:
+		t.mode = modeList
+	case keyEnter:
+		previous := t.filter
+		t.filter = strings.TrimSpace(string(t.input.value))
+		if err := t.reload(); err != nil {
+			t.filter = previous
+			t.setError(err)
+			return
+		}
+		t.mode = modeList
+		t.setMessage("%d tasks matched", len(t.todos))
+	default:
+		t.input.edit(k)
+	}
+}
+
+// toggleComplete completes selected task or reopens it if it's done
+func (t *tui) toggleComplete() {
+	td := t.selected()
+	if td == nil {
+		return
+	}
+
+	ctx, cancel := t.c.context()
+	defer cancel()
+
+	if td.Status == v1.Todo_DONE {
+		if _, err := t.c.client.Reopen(ctx, &v1.ReopenRequest{Id: td.Id}); err != nil {
+			t.setError(err)
+			return
+		}
+		t.setMessage("Reopened #%d", td.Id)
+	} else {
+		res, err := t.c.client.Complete(ctx, &v1.CompleteRequest{Id: td.Id})
+		if err != nil {
+			t.setError(err)
+			return
+		}
+		t.setMessage("Completed #%d", td.Id)
+		if res.Next != nil {
+			t.setMessage("Completed #%d, next occurrence is #%d", td.Id, res.Next.Id)
+		}
+	}
+	if err := t.reload(); err != nil {
+		t.setError(err)
+	}
+}
+
+// delete deletes selected task
+func (t *tui) delete() {
+	td := t.selected()
+	if td == nil {
+		return
+	}
+
+	ctx, cancel := t.c.context()
+	defer cancel()
+
+	res, err := t.c.client.Delete(ctx, &v1.DeleteRequest{Id: td.Id})
+	if err != nil {
+		t.setError(err)
+		return
+	}
+	t.setMessage("Deleted %d tasks", res.Deleted)
+	if err := t.reload(); err != nil {
+		t.setError(err)
+	}
+}
+
+// newFormField returns field with value and cursor at its end
+func newFormField(label, hint, value string) *formField {
+	f := &formField{label: label, hint: hint, value: []rune(value)}
+	f.pos = len(f.value)
+	return f
+}
+
+// edit changes value of field by key
+func (f *formField) edit(k key) {
+	switch k.code {
+	case keyLeft:
+		if f.pos > 0 {
+			f.pos--
+		}
+	case keyRight:
+		if f.pos < len(f.value) {
+			f.pos++
+		}
+	case keyHome:
+		f.pos = 0
+	case keyEnd:
+		f.pos = len(f.value)
+	case keyBackspace:
+		if f.pos > 0 {
+			f.value = append(f.value[:f.pos-1], f.value[f.pos:]...)
+			f.pos--
+		}
+	case keyDelete:
+		if f.pos < len(f.value) {
+			f.value = append(f.value[:f.pos], f.value[f.pos+1:]...)
+		}
+	case keyRune:
+		f.value = append(f.value[:f.pos], append([]rune{k.r}, f.value[f.pos:]...)...)
+		f.pos++
+	}
+}
+
+// openForm opens form to edit task, to create task if it's nil
+func (t *tui) openForm(td *v1.Todo) {
+	form := &todoForm{}
+	title, description, priority, due, labels := "", "", "", "", ""
+	if td != nil {
+		form.id = td.Id
+		title, description = td.Title, td.Description
+		priority = enumName(td.Priority.String(), td.Priority == v1.Todo_PRIORITY_UNSPECIFIED)
+		due = formatTime(td.DueDate)
+		labels = strings.Join(td.Labels, ",")
+	}
+	form.fields = []*formField{
+		newFormField("Title", "", title),
+		newFormField("Description", "", description),
+		newFormField("Priority", "LOW, MEDIUM, HIGH or URGENT", priority),
+		newFormField("Due", "RFC 3339, 'now' or '+48h'", due),
+		newFormField("Labels", "comma separated", labels),
+	}
+	t.form = form
+	t.mode = modeForm
+	t.setMessage("")
+}
+
+// handleFormKey handles key in form
+func (t *tui) handleFormKey(k key) {
+	form := t.form
+	switch k.code {
+	case keyEsc:
+		t.mode = modeList
+		t.setMessage("")
+	case keyTab, keyDown:
+		form.focus = (form.focus + 1) % len(form.fields)
+	case keyBacktab, keyUp:
+		form.focus = (form.focus + len(form.fields) - 1) % len(form.fields)
+	case keyEnter:
+		if err := t.save(); err != nil {
+			t.setError(err)
+			return
+		}
+		t.mode = modeList
+		if err := t.reload(); err != nil {
+			t.setError(err)
+		}
+	default:
+		form.fields[form.focus].edit(k)
+	}
+}
+
+// save creates or updates task of form
+func (t *tui) save() error {
+	form := t.form
+	value := func(i int) string {
+		return strings.TrimSpace(string(form.fields[i].value))
+	}
+
+	title := value(0)
+	if len(title) == 0 {
+		return fmt.Errorf("title is required")
+	}
+	priority := v1.Todo_PRIORITY_UNSPECIFIED
+	if p := value(2); len(p) > 0 {
+		v, ok := v1.Todo_Priority_value[strings.ToUpper(p)]
+		if !ok {
+			return fmt.Errorf("unknown priority '%s'", p)
+		}
+		priority = v1.Todo_Priority(v)
+	}
+	var due *timestamp.Timestamp
+	if d := value(3); len(d) > 0 {
+		tm, err := parseTime(d)
+		if err != nil {
+			return err
+		}
+		if due, err = ptypes.TimestampProto(tm); err != nil {
+			return err
+		}
+	}
+	labels := splitLabels(value(4))
+
+	ctx, cancel := t.c.context()
+	defer cancel()
+
+	if form.id == 0 {
+		id, err := t.c.sdk.Create(ctx, &v1.Todo{
+			Title:       title,
+			Description: value(1),
+			Priority:    priority,
+			DueDate:     due,
+			Labels:      labels,
+			ListId:      t.listID,
+			Reminder:    ptypes.TimestampNow(),
+		}, "")
+		if err != nil {
+			return err
+		}
+		t.setMessage("Created #%d", id)
+		return nil
+	}
+
+	res, err := t.c.client.Read(ctx, &v1.ReadRequest{Id: form.id})
+	if err != nil {
+		return err
+	}
+	td := res.Todo
+	td.Title, td.Description, td.Priority, td.DueDate = title, value(1), priority, due
+	if _, err := t.c.client.Update(ctx, &v1.UpdateRequest{Todo: td}); err != nil {
+		return err
+	}
+	if _, err := t.c.sdk.SetLabels(ctx, td.Id, labels); err != nil {
+		return err
+	}
+	t.setMessage("Updated #%d", td.Id)
+	return nil
+}
+
+// draw draws current mode
+func (t *tui) draw() error {
+	s := &screen{width: t.width, height: t.height}
+	s.buf.WriteString("\x1b[?25l")
+
+	title := fmt.Sprintf(" Todo %s  %d tasks  [%s]", t.c.cfg.Server, len(t.todos), t.live)
+	if len(t.filter) > 0 {
+		title += "  filter: " + t.filter
+	}
+	s.line(1, "7", title)
+
+	var lines []string
+	hints := "n new  e edit  space complete  d delete  / filter  enter details  ? help  q quit"
+	switch t.mode {
+	case modeDetails:
+		lines = t.details()
+		hints = "any key to return"
+	case modeHelp:
+		lines = tuiHelp
+		hints = "any key to return"
+	case modeForm:
+		lines = t.formLines()
+		hints = "tab next field  enter save  esc cancel"
+	default:
+		t.drawList(s)
+	}
+	for i := 0; i < t.rows()+1 && lines != nil; i++ {
+		text := ""
+		if i < len(lines) {
+			text = lines[i]
+		}
+		s.line(i+2, "", " "+text)
+	}
+
+	if t.mode == modeFilter {
+		s.line(t.height-1, "", " "+t.input.label+": "+string(t.input.value))
+		s.cursor(t.height-1, utf8.RuneCountInString(t.input.label)+4+t.input.pos)
+	} else if t.failed {
+		s.line(t.height-1, "31", " "+t.message)
+	} else {
+		s.line(t.height-1, "", " "+t.message)
+	}
+	s.line(t.height, "7", " "+hints)
+
+	if t.mode == modeForm {
+		// labels are padded to the same width by formLines
+		f := t.form.fields[t.form.focus]
+		s.cursor(t.form.focus*2+3, 16+f.pos)
+	}
+	return s.flush(t.term)
+}
+
+// drawList draws table of tasks
+func (t *tui) drawList(s *screen) {
+	s.line(2, "1", fmt.Sprintf(" %6s  %-3s  %-8s  %-16s  %s", "ID", "", "PRIORITY", "DUE", "TITLE"))
+	for i := 0; i < t.rows(); i++ {
+		n := t.offset + i
+		if n >= len(t.todos) {
+			s.line(i+3, "", "")
+			continue
+		}
+		td := t.todos[n]
+		mark := "[ ]"
+		switch td.Status {
+		case v1.Todo_IN_PROGRESS:
+			mark = "[~]"
+		case v1.Todo_DONE:
+			mark = "[x]"
+		case v1.Todo_CANCELLED:
+			mark = "[-]"
+		}
+		due := ""
+		if tm, err := ptypes.Timestamp(td.DueDate); err == nil {
+			due = tm.Local().Format("2006-01-02 15:04")
+		}
+		text := fmt.Sprintf(" %6d  %s  %-8s  %-16s  %s", td.Id, mark,
+			enumName(td.Priority.String(), td.Priority == v1.Todo_PRIORITY_UNSPECIFIED), due, td.Title)
+		if len(td.Labels) > 0 {
+			text += "  [" + strings.Join(td.Labels, ",") + "]"
+		}
+
+		style := ""
+		if td.Status == v1.Todo_DONE || td.Status == v1.Todo_CANCELLED {
+			style = "2"
+		}
+		if n == t.cursor {
+			style = "7"
+		}
+		s.line(i+3, style, text)
+	}
+}
+
+// details returns lines of all fields of selected task
+func (t *tui) details() []string {
+	td := t.selected()
+	if td == nil {
+		return []string{}
+	}
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	writeTodo(w, td)
+	w.Flush()
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+// formLines returns lines of form, each field is followed by its hint
+func (t *tui) formLines() []string {
+	heading := "New task"
+	if t.form.id > 0 {
+		heading = "Edit task #" + strconv.FormatInt(t.form.id, 10)
+	}
+	lines := []string{heading}
+	for _, f := range t.form.fields {
+		lines = append(lines, fmt.Sprintf("%-12s  %s", f.label+":", string(f.value)))
+		hint := ""
+		if len(f.hint) > 0 {
+			hint = fmt.Sprintf("%-12s  (%s)", "", f.hint)
+		}
+		lines = append(lines, hint)
+	}
+	return lines
+}
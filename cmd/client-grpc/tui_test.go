@@ -0,0 +1,129 @@
+package main
+
+import (
+	"testing"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+// runes returns keys typing text
+func runes(text string) []key {
+	var keys []key
+	for _, r := range text {
+		keys = append(keys, key{code: keyRune, r: r})
+	}
+	return keys
+}
+
+func TestFormFieldEdit(t *testing.T) {
+	f := newFormField("Title", "", "Cal Bob")
+	keys := []key{{code: keyHome}, {code: keyRight}, {code: keyRight}}
+	keys = append(keys, runes("l")...)
+	keys = append(keys, key{code: keyEnd}, key{code: keyBackspace}, key{code: keyBackspace}, key{code: keyBackspace})
+	keys = append(keys, runes("Bob!")...)
+	keys = append(keys, key{code: keyLeft}, key{code: keyDelete}, key{code: keyDelete})
+	for _, k := range keys {
+		f.edit(k)
+	}
+	if got := string(f.value); got != "Call Bob" || f.pos != len(f.value) {
+		t.Errorf("edited field = %q with cursor at %d, want \"Call Bob\" with cursor at its end", got, f.pos)
+	}
+}
+
+func TestTUIMove(t *testing.T) {
+	tu := &tui{height: 7}
+	for i := int64(1); i <= 10; i++ {
+		tu.todos = append(tu.todos, &v1.Todo{Id: i})
+	}
+
+	// list shows 3 rows, selection is kept visible
+	tests := []struct {
+		key        key
+		wantCursor int
+		wantOffset int
+	}{
+		{key: key{code: keyDown}, wantCursor: 1, wantOffset: 0},
+		{key: key{code: keyRune, r: 'j'}, wantCursor: 2, wantOffset: 0},
+		{key: key{code: keyRune, r: 'j'}, wantCursor: 3, wantOffset: 1},
+		{key: key{code: keyPageDown}, wantCursor: 6, wantOffset: 4},
+		{key: key{code: keyRune, r: 'G'}, wantCursor: 9, wantOffset: 7},
+		{key: key{code: keyDown}, wantCursor: 9, wantOffset: 7},
+		{key: key{code: keyRune, r: 'k'}, wantCursor: 8, wantOffset: 7},
+		{key: key{code: keyPageUp}, wantCursor: 5, wantOffset: 5},
+		{key: key{code: keyHome}, wantCursor: 0, wantOffset: 0},
+		{key: key{code: keyUp}, wantCursor: 0, wantOffset: 0},
+	}
+	for _, tt := range tests {
+		if !tu.handleKey(tt.key) {
+			t.Fatalf("handleKey(%v) quit", tt.key)
+		}
+		if tu.cursor != tt.wantCursor || tu.offset != tt.wantOffset {
+			t.Fatalf("after key %v cursor = %d, offset = %d, want %d, %d", tt.key, tu.cursor, tu.offset, tt.wantCursor, tt.wantOffset)
+		}
+	}
+}
+
+func TestTUIModes(t *testing.T) {
+	tu := &tui{height: 24, todos: []*v1.Todo{{Id: 3, Title: "Call Bob", Priority: v1.Todo_HIGH, Labels: []string{"home", "phone"}}}}
+
+	if tu.handleKey(key{code: keyEnter}); tu.mode != modeDetails {
+		t.Fatalf("mode after Enter = %v, want details", tu.mode)
+	}
+	if tu.handleKey(key{code: keyEsc}); tu.mode != modeList {
+		t.Fatalf("mode after key in details = %v, want list", tu.mode)
+	}
+
+	// task without confirmation isn't deleted
+	tu.handleKey(key{code: keyRune, r: 'd'})
+	if tu.mode != modeConfirm {
+		t.Fatalf("mode after 'd' = %v, want confirmation", tu.mode)
+	}
+	if tu.handleKey(key{code: keyRune, r: 'n'}); tu.mode != modeList || len(tu.message) > 0 {
+		t.Fatalf("after declined delete mode = %v, message = %q, want list without message", tu.mode, tu.message)
+	}
+
+	// edit form is filled with selected task, Tab and Shift-Tab wrap around fields
+	tu.handleKey(key{code: keyRune, r: 'e'})
+	if tu.mode != modeForm || tu.form.id != 3 {
+		t.Fatalf("after 'e' mode = %v, form %+v, want form of task 3", tu.mode, tu.form)
+	}
+	want := []string{"Call Bob", "", "HIGH", "", "home,phone"}
+	for i, f := range tu.form.fields {
+		if string(f.value) != want[i] {
+			t.Errorf("field %s = %q, want %q", f.label, string(f.value), want[i])
+		}
+	}
+	tu.handleKey(key{code: keyBacktab})
+	if tu.form.focus != len(want)-1 {
+		t.Errorf("focus after Shift-Tab = %d, want last field", tu.form.focus)
+	}
+	tu.handleKey(key{code: keyTab})
+	tu.handleKey(key{code: keyTab})
+	tu.handleKey(key{code: keyRune, r: '!'})
+	if got := string(tu.form.fields[1].value); got != "!" {
+		t.Errorf("description = %q, want typed text", got)
+	}
+	if lines := tu.formLines(); lines[0] != "Edit task #3" {
+		t.Errorf("form heading = %q, want edited task", lines[0])
+	}
+
+	// form with empty title isn't saved
+	tu.form.fields[0].value = nil
+	tu.handleKey(key{code: keyEnter})
+	if tu.mode != modeForm || !tu.failed || tu.message != "title is required" {
+		t.Errorf("after saving empty title mode = %v, message = %q, want form with error", tu.mode, tu.message)
+	}
+	if tu.handleKey(key{code: keyEsc}); tu.mode != modeList {
+		t.Errorf("mode after Esc in form = %v, want list", tu.mode)
+	}
+
+	if tu.handleKey(key{code: keyRune, r: '?'}); tu.mode != modeHelp {
+		t.Errorf("mode after '?' = %v, want help", tu.mode)
+	}
+	if tu.handleKey(key{code: keyRune, r: 'q'}) {
+		t.Error("'q' in help didn't quit")
+	}
+	if tu.handleKey(key{code: keyCtrlC}) {
+		t.Error("Ctrl-C didn't quit")
+	}
+}
@@ -0,0 +1,5 @@
+package v1
+
+// IdempotencyKeyHeader is metadata key of idempotency key of Create request,
+// HTTP gateway passes Idempotency-Key header in it
+const IdempotencyKeyHeader = "idempotency-key"
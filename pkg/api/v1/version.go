@@ -0,0 +1,5 @@
+package v1
+
+// APIVersion is version of the API generated from todo-service.proto,
+// clients send it in api field of requests and servers check it
+const APIVersion = "v1"
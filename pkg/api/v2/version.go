@@ -0,0 +1,5 @@
+package v2
+
+// APIVersion is version of the API generated from todo-service.proto,
+// it's the proto package of the services
+const APIVersion = "v2"
@@ -0,0 +1,219 @@
+// Package client is Go client of Todo service.
+//
+// Client dials the server, stamps API version on requests, applies timeouts and retries
+// of DefaultServiceConfig and returns errors of type *Error:
+//
+//	c, err := client.New("todo.example.com:9090", client.WithTLS(nil), client.WithBearerToken(token),
+//		client.WithTimeout(5*time.Second))
+//	if err != nil {
+//		return err
+//	}
+//	defer c.Close()
+//
+//	td, err := c.Get(ctx, 42)
+//	if errors.Is(err, client.ErrNotFound) {
+//		...
+//	}
+//
+// Methods not wrapped by Client are available on generated client returned by Service,
+// it shares the same connection and behavior.
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+// Client is client of Todo service
+type Client struct {
+	conn     *grpc.ClientConn
+	todo     v1.TodoServiceClient
+	webhooks v1.WebhookServiceClient
+}
+
+// New connects to the server at target in format host:port
+func New(target string, opts ...Option) (*Client, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	if len(o.token) > 0 && o.tls == nil && !o.insecureCreds {
+		return nil, fmt.Errorf("bearer token requires TLS, set WithTLS or WithInsecureCredentials for local server")
+	}
+
+	conn, err := grpc.Dial(target, o.dialOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to '%s': %v", target, err)
+	}
+
+	return &Client{
+		conn:     conn,
+		todo:     v1.NewTodoServiceClient(conn),
+		webhooks: v1.NewWebhookServiceClient(conn),
+	}, nil
+}
+
+// Close closes connection to the server
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Conn returns connection to the server
+func (c *Client) Conn() *grpc.ClientConn {
+	return c.conn
+}
+
+// Service returns generated client of Todo service using connection of Client,
+// api field of requests is set if it's empty
+func (c *Client) Service() v1.TodoServiceClient {
+	return c.todo
+}
+
+// Webhooks returns generated client of Webhook service using connection of Client
+func (c *Client) Webhooks() v1.WebhookServiceClient {
+	return c.webhooks
+}
+
+// Create creates task and returns its ID. requestID is idempotency key, random key is
+// generated if it's empty, so Create is safe to retry
+func (c *Client) Create(ctx context.Context, td *v1.Todo, requestID string) (int64, error) {
+	if len(requestID) == 0 {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return 0, fmt.Errorf("failed to generate request ID: %v", err)
+		}
+		requestID = hex.EncodeToString(b)
+	}
+	res, err := c.todo.Create(ctx, &v1.CreateRequest{Todo: td, RequestId: requestID})
+	if err != nil {
+		return 0, err
+	}
+	return res.Id, nil
+}
+
+// Get reads task by ID
+func (c *Client) Get(ctx context.Context, id int64) (*v1.Todo, error) {
+	res, err := c.todo.Read(ctx, &v1.ReadRequest{Id: id})
+	if err != nil {
+		return nil, err
+	}
+	return res.Todo, nil
+}
+
+// Update updates fields of task except labels, list and parent which are changed by their own methods
+func (c *Client) Update(ctx context.Context, td *v1.Todo) error {
+	_, err := c.todo.Update(ctx, &v1.UpdateRequest{Todo: td})
+	return err
+}
+
+// Delete deletes task with its subtasks and returns number of deleted tasks
+func (c *Client) Delete(ctx context.Context, id int64) (int64, error) {
+	res, err := c.todo.Delete(ctx, &v1.DeleteRequest{Id: id})
+	if err != nil {
+		return 0, err
+	}
+	return res.Deleted, nil
+}
+
+// Complete completes task, next is the next occurrence of recurring task or nil
+func (c *Client) Complete(ctx context.Context, id int64) (td *v1.Todo, next *v1.Todo, err error) {
+	res, err := c.todo.Complete(ctx, &v1.CompleteRequest{Id: id})
+	if err != nil {
+		return nil, nil, err
+	}
+	return res.Todo, res.Next, nil
+}
+
+// Reopen reopens completed or cancelled task
+func (c *Client) Reopen(ctx context.Context, id int64) (*v1.Todo, error) {
+	res, err := c.todo.Reopen(ctx, &v1.ReopenRequest{Id: id})
+	if err != nil {
+		return nil, err
+	}
+	return res.Todo, nil
+}
+
+// SetLabels adds and removes labels of task to make them equal to labels
+func (c *Client) SetLabels(ctx context.Context, id int64, labels []string) (*v1.Todo, error) {
+	td, err := c.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	has := map[string]bool{}
+	for _, l := range td.Labels {
+		has[l] = true
+	}
+	want := map[string]bool{}
+	var add, remove []string
+	for _, l := range labels {
+		want[l] = true
+		if !has[l] {
+			add = append(add, l)
+		}
+	}
+	for _, l := range td.Labels {
+		if !want[l] {
+			remove = append(remove, l)
+		}
+	}
+
+	if len(add) > 0 {
+		res, err := c.todo.AddLabels(ctx, &v1.AddLabelsRequest{Id: id, Labels: add})
+		if err != nil {
+			return nil, err
+		}
+		td = res.Todo
+	}
+	if len(remove) > 0 {
+		res, err := c.todo.RemoveLabels(ctx, &v1.RemoveLabelsRequest{Id: id, Labels: remove})
+		if err != nil {
+			return nil, err
+		}
+		td = res.Todo
+	}
+	return td, nil
+}
+
+// Move moves task with its subtasks to list
+func (c *Client) Move(ctx context.Context, id, listID int64) (*v1.Todo, error) {
+	res, err := c.todo.MoveTodo(ctx, &v1.MoveTodoRequest{Id: id, ToListId: listID})
+	if err != nil {
+		return nil, err
+	}
+	return res.Todo, nil
+}
+
+// SetParent makes task subtask of parent, 0 makes it top-level
+func (c *Client) SetParent(ctx context.Context, id, parentID int64) (*v1.Todo, error) {
+	res, err := c.todo.SetParent(ctx, &v1.SetParentRequest{Id: id, ParentId: parentID})
+	if err != nil {
+		return nil, err
+	}
+	return res.Todo, nil
+}
+
+// Snooze snoozes reminder of task until the time
+func (c *Client) Snooze(ctx context.Context, id int64, until time.Time) (*v1.Todo, error) {
+	res, err := c.todo.SnoozeReminder(ctx, &v1.SnoozeReminderRequest{Id: id, Until: Timestamp(until)})
+	if err != nil {
+		return nil, err
+	}
+	return res.Todo, nil
+}
+
+// Search returns tasks matched by words in title and description, limit is 20 if it's 0
+func (c *Client) Search(ctx context.Context, query string, limit int) ([]*v1.SearchResult, error) {
+	res, err := c.todo.Search(ctx, &v1.SearchRequest{Query: query, Limit: int32(limit)})
+	if err != nil {
+		return nil, err
+	}
+	return res.Results, nil
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Error is error returned by the server or gRPC, it keeps status so status.FromError works with it.
+// Use errors.Is with Err* values to check kind of error
+type Error struct {
+	// Code is gRPC status code
+	Code codes.Code
+	// Message is message of the server without code
+	Message string
+
+	status *status.Status
+}
+
+// Errors of status codes returned by Todo service
+var (
+	ErrInvalidArgument    = &Error{Code: codes.InvalidArgument}
+	ErrNotFound           = &Error{Code: codes.NotFound}
+	ErrAlreadyExists      = &Error{Code: codes.AlreadyExists}
+	ErrPermissionDenied   = &Error{Code: codes.PermissionDenied}
+	ErrUnauthenticated    = &Error{Code: codes.Unauthenticated}
+	ErrFailedPrecondition = &Error{Code: codes.FailedPrecondition}
+	ErrResourceExhausted  = &Error{Code: codes.ResourceExhausted}
+	ErrDeadlineExceeded   = &Error{Code: codes.DeadlineExceeded}
+	ErrCanceled           = &Error{Code: codes.Canceled}
+	ErrUnavailable        = &Error{Code: codes.Unavailable}
+	// ErrUnsupportedVersion is returned if the server doesn't implement API version of the client
+	ErrUnsupportedVersion = &Error{Code: codes.Unimplemented}
+	ErrInternal           = &Error{Code: codes.Internal}
+)
+
+func (e *Error) Error() string {
+	if len(e.Message) == 0 {
+		return e.Code.String()
+	}
+	return e.Code.String() + ": " + e.Message
+}
+
+// Is reports whether target is Err* value of the same code
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.status == nil && t.Code == e.Code
+}
+
+// GRPCStatus returns status of error
+func (e *Error) GRPCStatus() *status.Status {
+	if e.status == nil {
+		return status.New(e.Code, e.Message)
+	}
+	return e.status
+}
+
+// Details returns details of status, e.g. errdetails.BadRequest
+func (e *Error) Details() []interface{} {
+	return e.GRPCStatus().Details()
+}
+
+// Code returns status code of error, codes.OK for nil and codes.Unknown for errors not returned by gRPC
+func Code(err error) codes.Code {
+	return status.Code(err)
+}
+
+// convertError returns status error as *Error, nil, io.EOF and other errors are returned as is
+func convertError(err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	if _, ok := err.(*Error); ok {
+		return err
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	return &Error{Code: st.Code(), Message: st.Message(), status: st}
+}
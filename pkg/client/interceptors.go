@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+// setAPIVersion sets api field of request to the version of the client if it's empty
+func setAPIVersion(req interface{}) {
+	m, ok := req.(proto.Message)
+	if !ok {
+		return
+	}
+	r := m.ProtoReflect()
+	f := r.Descriptor().Fields().ByName("api")
+	if f == nil || f.Kind() != protoreflect.StringKind || f.IsList() {
+		return
+	}
+	if len(r.Get(f).String()) == 0 {
+		r.Set(f, protoreflect.ValueOfString(v1.APIVersion))
+	}
+}
+
+// versionUnaryInterceptor stamps API version on requests
+func versionUnaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	setAPIVersion(req)
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// errorUnaryInterceptor converts status errors to *Error
+func errorUnaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return convertError(invoker(ctx, method, req, reply, cc, opts...))
+}
+
+// timeoutUnaryInterceptor sets deadline of calls made with context without deadline
+func timeoutUnaryInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if _, ok := ctx.Deadline(); !ok && timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// versionStreamInterceptor stamps API version on requests sent to streams
+func versionStreamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	s, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &versionStream{ClientStream: s}, nil
+}
+
+// versionStream is stream stamping API version on sent requests
+type versionStream struct {
+	grpc.ClientStream
+}
+
+func (s *versionStream) SendMsg(m interface{}) error {
+	setAPIVersion(m)
+	return s.ClientStream.SendMsg(m)
+}
+
+// errorStreamInterceptor converts status errors of streams to *Error
+func errorStreamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	s, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return &errorStream{ClientStream: s}, nil
+}
+
+// errorStream is stream converting status errors to *Error, io.EOF is returned as is
+type errorStream struct {
+	grpc.ClientStream
+}
+
+func (s *errorStream) SendMsg(m interface{}) error {
+	return convertError(s.ClientStream.SendMsg(m))
+}
+
+func (s *errorStream) RecvMsg(m interface{}) error {
+	return convertError(s.ClientStream.RecvMsg(m))
+}
+
+func (s *errorStream) CloseSend() error {
+	return convertError(s.ClientStream.CloseSend())
+}
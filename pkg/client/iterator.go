@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+const (
+	// DefaultPageSize is number of tasks read by one call of iterator if ListOptions doesn't set it
+	DefaultPageSize = 100
+)
+
+// ListOptions are conditions of List
+type ListOptions struct {
+	// Filter is filter expression, e.g. `status = "OPEN" AND labels: "work"`
+	Filter string
+	// OrderBy is sort order, e.g. "due_date desc", tasks are sorted by id if it's empty
+	OrderBy string
+	// PageSize is number of tasks read by one call, DefaultPageSize if it's 0
+	PageSize int
+}
+
+// TodoIterator reads tasks page by page:
+//
+//	it := c.List(ctx, client.ListOptions{Filter: `status = "OPEN"`})
+//	for it.Next() {
+//		td := it.Todo()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type TodoIterator struct {
+	ctx  context.Context
+	c    *Client
+	req  *v1.ListTodosRequest
+	page []*v1.Todo
+	todo *v1.Todo
+	done bool
+	err  error
+}
+
+// List returns iterator of tasks matched by filter
+func (c *Client) List(ctx context.Context, opts ListOptions) *TodoIterator {
+	size := opts.PageSize
+	if size <= 0 {
+		size = DefaultPageSize
+	}
+	return &TodoIterator{
+		ctx: ctx,
+		c:   c,
+		req: &v1.ListTodosRequest{
+			Filter:   opts.Filter,
+			OrderBy:  opts.OrderBy,
+			PageSize: int32(size),
+		},
+	}
+}
+
+// ListAll returns all tasks matched by filter
+func (c *Client) ListAll(ctx context.Context, opts ListOptions) ([]*v1.Todo, error) {
+	var todos []*v1.Todo
+	it := c.List(ctx, opts)
+	for it.Next() {
+		todos = append(todos, it.Todo())
+	}
+	return todos, it.Err()
+}
+
+// Next moves to the next task, it reads the next page when the current one ends.
+// It returns false at the end or on error
+func (it *TodoIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			it.todo = nil
+			return false
+		}
+		it.fetch()
+	}
+	it.todo, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// fetch reads the next page
+func (it *TodoIterator) fetch() {
+	res, err := it.c.todo.ListTodos(it.ctx, it.req)
+	if err != nil {
+		it.err = err
+		return
+	}
+	it.page = res.Todos
+	it.req.PageToken = res.NextPageToken
+	it.done = len(res.NextPageToken) == 0
+}
+
+// Todo returns the current task
+func (it *TodoIterator) Todo() *v1.Todo {
+	return it.todo
+}
+
+// Err returns error of reading pages, nil if iteration ended without error
+func (it *TodoIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+)
+
+// Option configures Client
+type Option func(*options)
+
+// options are settings of Client set by Option
+type options struct {
+	tls           *tls.Config
+	token         string
+	insecureCreds bool
+	timeout       time.Duration
+	serviceConfig string
+	noRetry       bool
+	keepalive     *keepalive.ClientParameters
+	extra         []grpc.DialOption
+}
+
+// defaultOptions returns settings of Client without options
+func defaultOptions() *options {
+	return &options{
+		serviceConfig: DefaultServiceConfig,
+	}
+}
+
+// WithTLS connects to the server with TLS, nil config verifies server by system roots.
+// Client certificates in config authenticate the client
+func WithTLS(config *tls.Config) Option {
+	return func(o *options) {
+		if config == nil {
+			config = &tls.Config{}
+		}
+		o.tls = config
+	}
+}
+
+// WithBearerToken sends token in authorization metadata of every call,
+// the token is sent over TLS only unless WithInsecureCredentials is set
+func WithBearerToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// WithInsecureCredentials allows bearer token to be sent over connection without TLS,
+// anyone on the network can read it, so it's meant for local development only
+func WithInsecureCredentials() Option {
+	return func(o *options) {
+		o.insecureCreds = true
+	}
+}
+
+// WithTimeout sets timeout of unary calls made with context without deadline,
+// shorter timeout of method in service config has priority
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithServiceConfig replaces DefaultServiceConfig by service config in JSON,
+// it's used if name resolver doesn't provide service config
+func WithServiceConfig(config string) Option {
+	return func(o *options) {
+		o.serviceConfig = config
+	}
+}
+
+// WithoutRetry turns off retries of service config
+func WithoutRetry() Option {
+	return func(o *options) {
+		o.noRetry = true
+	}
+}
+
+// WithKeepalive pings the server after interval without activity and closes
+// connection if ping isn't answered within timeout, interval must not be less than
+// -keepalive-min-time of the server or the server closes connection
+func WithKeepalive(interval, timeout time.Duration) Option {
+	return func(o *options) {
+		o.keepalive = &keepalive.ClientParameters{
+			Time:                interval,
+			Timeout:             timeout,
+			PermitWithoutStream: true,
+		}
+	}
+}
+
+// WithDialOptions adds gRPC dial options, e.g. interceptors or custom dialer
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.extra = append(o.extra, opts...)
+	}
+}
+
+// dialOptions returns gRPC dial options of settings
+func (o *options) dialOptions() []grpc.DialOption {
+	opts := []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(
+			versionUnaryInterceptor,
+			errorUnaryInterceptor,
+			timeoutUnaryInterceptor(o.timeout),
+		),
+		grpc.WithChainStreamInterceptor(
+			versionStreamInterceptor,
+			errorStreamInterceptor,
+		),
+		grpc.WithDefaultServiceConfig(o.serviceConfig),
+	}
+	if o.noRetry {
+		opts = append(opts, grpc.WithDisableRetry())
+	}
+
+	if o.tls != nil {
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(o.tls)))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+	if len(o.token) > 0 {
+		opts = append(opts, grpc.WithPerRPCCredentials(bearerToken{token: o.token, secure: !o.insecureCreds}))
+	}
+	if o.keepalive != nil {
+		opts = append(opts, grpc.WithKeepaliveParams(*o.keepalive))
+	}
+
+	return append(opts, o.extra...)
+}
+
+// bearerToken is per-call credentials sending token in authorization metadata
+type bearerToken struct {
+	token  string
+	secure bool
+}
+
+// GetRequestMetadata returns authorization metadata
+func (t bearerToken) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + t.token}, nil
+}
+
+// RequireTransportSecurity requires TLS unless WithInsecureCredentials is set
+func (t bearerToken) RequireTransportSecurity() bool {
+	return t.secure
+}
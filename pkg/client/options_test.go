@@ -0,0 +1,48 @@
+package client
+
+import (
+	"testing"
+
+	"google.golang.org/grpc"
+)
+
+func TestBearerTokenRequiresTLS(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+	}{
+		{name: "without token"},
+		{name: "token over TLS", opts: []Option{WithBearerToken("secret"), WithTLS(nil)}},
+		{name: "token without TLS", opts: []Option{WithBearerToken("secret")}, wantErr: true},
+		{name: "token with insecure credentials", opts: []Option{WithBearerToken("secret"), WithInsecureCredentials()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New("localhost:9090", tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				c.Close()
+			}
+		})
+	}
+}
+
+func TestBearerTokenTransportSecurity(t *testing.T) {
+	// gRPC itself refuses to send the token over plain connection unless it's allowed
+	for _, insecure := range []bool{false, true} {
+		o := defaultOptions()
+		o.token = "secret"
+		o.insecureCreds = insecure
+
+		conn, err := grpc.Dial("localhost:9090", o.dialOptions()...)
+		if (err == nil) != insecure {
+			t.Errorf("Dial() with insecure credentials %v error = %v", insecure, err)
+		}
+		if err == nil {
+			conn.Close()
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+// faultyServer fails the first failures calls of every method with Unavailable
+// and counts calls by method
+type faultyServer struct {
+	v1.UnimplementedTodoServiceServer
+
+	mu       sync.Mutex
+	failures int
+	calls    map[string]int
+}
+
+// call counts call of method and returns injected fault
+func (s *faultyServer) call(method string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[method]++
+	if s.calls[method] <= s.failures {
+		return status.Error(codes.Unavailable, "injected fault")
+	}
+	return nil
+}
+
+func (s *faultyServer) Read(ctx context.Context, req *v1.ReadRequest) (*v1.ReadResponse, error) {
+	if err := s.call("Read"); err != nil {
+		return nil, err
+	}
+	return &v1.ReadResponse{Api: v1.APIVersion, Todo: &v1.Todo{Id: req.Id}}, nil
+}
+
+func (s *faultyServer) Create(ctx context.Context, req *v1.CreateRequest) (*v1.CreateResponse, error) {
+	if err := s.call("Create"); err != nil {
+		return nil, err
+	}
+	return &v1.CreateResponse{Api: v1.APIVersion, Id: 1}, nil
+}
+
+// dialFaulty returns client of local server failing the first failures calls of every method
+func dialFaulty(t *testing.T, failures int, opts ...Option) (*Client, *faultyServer) {
+	t.Helper()
+	l := bufconn.Listen(1 << 20)
+	srv := &faultyServer{failures: failures, calls: map[string]int{}}
+	s := grpc.NewServer()
+	v1.RegisterTodoServiceServer(s, srv)
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+
+	opts = append(opts, WithDialOptions(grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return l.DialContext(ctx)
+	})))
+	c, err := New("bufnet", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c, srv
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		opts      []Option
+		create    bool
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "read succeeds after retries", failures: 2, wantCalls: 3},
+		{name: "read gives up after max attempts", failures: 10, wantCalls: 4, wantErr: ErrUnavailable},
+		{name: "read without retry", failures: 2, opts: []Option{WithoutRetry()}, wantCalls: 1, wantErr: ErrUnavailable},
+		{name: "create isn't retried", failures: 1, create: true, wantCalls: 1, wantErr: ErrUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, srv := dialFaulty(t, tt.failures, tt.opts...)
+
+			method := "Read"
+			var err error
+			if tt.create {
+				method = "Create"
+				_, err = c.Create(context.Background(), &v1.Todo{Title: "Call Bob"}, "")
+			} else {
+				_, err = c.Get(context.Background(), 1)
+			}
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("%s error = %v, want %v", method, err, tt.wantErr)
+			}
+			if got := srv.calls[method]; got != tt.wantCalls {
+				t.Errorf("%s calls = %d, want %d", method, got, tt.wantCalls)
+			}
+		})
+	}
+}
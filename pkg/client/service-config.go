@@ -0,0 +1,64 @@
+package client
+
+// DefaultServiceConfig is gRPC service config of Todo v1 and v2 and Webhook services used by the client
+// and the HTTP gateway unless name resolver provides one.
+//
+// Read-only calls are retried up to 4 attempts with exponential backoff when server is unavailable,
+// retries are throttled when most of calls fail to avoid retry storms. Other calls aren't retried
+// since they change tasks, use request_id to retry Create safely. Hedging isn't used since grpc-go
+// doesn't implement it. Retries are turned off by WithoutRetry or GRPC_GO_RETRY=off environment variable.
+//
+// Reads time out in 5 seconds, other unary calls in 10 seconds. Streams have no timeout,
+// WatchTodos runs until it's cancelled and import or export take as long as data needs.
+const DefaultServiceConfig = `{
+	"methodConfig": [
+		{
+			"name": [
+				{"service": "v1.TodoService", "method": "Read"},
+				{"service": "v1.TodoService", "method": "ReadAll"},
+				{"service": "v1.TodoService", "method": "ReadByTitle"},
+				{"service": "v1.TodoService", "method": "ListDeleted"},
+				{"service": "v1.TodoService", "method": "ListTodos"},
+				{"service": "v1.TodoService", "method": "Search"},
+				{"service": "v1.TodoService", "method": "ListLabels"},
+				{"service": "v1.TodoService", "method": "GetList"},
+				{"service": "v1.TodoService", "method": "ListLists"},
+				{"service": "v1.TodoService", "method": "GetTree"},
+				{"service": "v1.TodoService", "method": "ListOccurrences"},
+				{"service": "v1.TodoService", "method": "ListCalendarFeeds"},
+				{"service": "v1.WebhookService", "method": "GetWebhook"},
+				{"service": "v1.WebhookService", "method": "ListWebhooks"},
+				{"service": "v1.WebhookService", "method": "ListWebhookDeliveries"},
+				{"service": "v2.TodoService", "method": "GetTodo"},
+				{"service": "v2.TodoService", "method": "ListTodos"}
+			],
+			"timeout": "5s",
+			"retryPolicy": {
+				"maxAttempts": 4,
+				"initialBackoff": "0.1s",
+				"maxBackoff": "2s",
+				"backoffMultiplier": 2,
+				"retryableStatusCodes": ["UNAVAILABLE"]
+			}
+		},
+		{
+			"name": [
+				{"service": "v1.TodoService", "method": "WatchTodos"},
+				{"service": "v1.TodoService", "method": "ExportTodos"},
+				{"service": "v1.TodoService", "method": "ImportTodos"}
+			]
+		},
+		{
+			"name": [
+				{"service": "v1.TodoService"},
+				{"service": "v1.WebhookService"},
+				{"service": "v2.TodoService"}
+			],
+			"timeout": "10s"
+		}
+	],
+	"retryThrottling": {
+		"maxTokens": 10,
+		"tokenRatio": 0.1
+	}
+}`
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+// Timestamp returns time as proto timestamp, nil for zero time
+func Timestamp(t time.Time) *timestamp.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	ts, err := ptypes.TimestampProto(t)
+	if err != nil {
+		return nil
+	}
+	return ts
+}
+
+// Time returns proto timestamp as time in UTC, zero time for nil or invalid timestamp
+func Time(ts *timestamp.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	t, err := ptypes.Timestamp(ts)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// Reminder returns reminder time of task, zero time if it's not set
+func Reminder(td *v1.Todo) time.Time {
+	return Time(td.GetReminder())
+}
+
+// SetReminder sets reminder time of task, zero time clears it
+func SetReminder(td *v1.Todo, t time.Time) {
+	td.Reminder = Timestamp(t)
+}
+
+// RemindIn sets reminder of task to duration from now
+func RemindIn(td *v1.Todo, d time.Duration) {
+	SetReminder(td, time.Now().Add(d))
+}
+
+// DueDate returns due date of task, zero time if it's not set
+func DueDate(td *v1.Todo) time.Time {
+	return Time(td.GetDueDate())
+}
+
+// SetDueDate sets due date of task, zero time clears it
+func SetDueDate(td *v1.Todo, t time.Time) {
+	td.DueDate = Timestamp(t)
+}
@@ -5,13 +5,24 @@ import (
 	"database/sql"
 	"flag"
 	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
 
 	// mysql driver
 	_ "github.com/go-sql-driver/mysql"
 
+	"github.com/devararishivian/go-grpc/api/swagger"
+	"github.com/devararishivian/go-grpc/pkg/outbox"
 	"github.com/devararishivian/go-grpc/pkg/protocol/grpc"
+	"github.com/devararishivian/go-grpc/pkg/protocol/grpc/middleware"
 	"github.com/devararishivian/go-grpc/pkg/protocol/rest"
+	"github.com/devararishivian/go-grpc/pkg/reminder"
 	v1 "github.com/devararishivian/go-grpc/pkg/service/v1"
+	v2 "github.com/devararishivian/go-grpc/pkg/service/v2"
 )
 
 // Config is configuration for Server
@@ -20,9 +31,43 @@ type Config struct {
 	// gRPC is TCP port to listen by gRPC server
 	GRPCPort string
 
+	// Reflection registers gRPC server reflection service
+	Reflection bool
+
+	// gRPC connection limits parameters section
+	// KeepaliveTime is how long connection is idle before server pings client
+	KeepaliveTime time.Duration
+	// KeepaliveTimeout is how long server waits for ping ack before it closes connection
+	KeepaliveTimeout time.Duration
+	// KeepaliveMinTime is minimum interval of client pings, connection of client pinging more often is closed
+	KeepaliveMinTime time.Duration
+	// KeepalivePermitWithoutStream allows client pings when there are no active calls
+	KeepalivePermitWithoutStream bool
+	// MaxConnectionIdle is how long connection without calls is kept open, 0 is infinity
+	MaxConnectionIdle time.Duration
+	// MaxConnectionAge is how long connection is kept open before client has to reconnect, 0 is infinity
+	MaxConnectionAge time.Duration
+	// MaxConnectionAgeGrace is how long calls can finish after MaxConnectionAge, 0 is infinity
+	MaxConnectionAgeGrace time.Duration
+	// MaxConcurrentStreams is limit of concurrent calls of one connection, 0 is unlimited
+	MaxConcurrentStreams uint
+	// MaxRecvMsgSize is limit of request message size in bytes
+	MaxRecvMsgSize int
+	// MaxSendMsgSize is limit of response message size in bytes, gRPC doesn't limit it by default,
+	// it is above MaxRecvMsgSize as ListTodos without page size returns all tasks in one response
+	MaxSendMsgSize int
+	// MaxConnections is limit of open connections, 0 is unlimited
+	MaxConnections int
+
+	// API versions parameters section
+	// V1Sunset is date API v1 is removed at in format "2006-01-02", sent with deprecation notice of v1 calls
+	V1Sunset string
+
 	// HTTP/REST gateway start parameters section
 	// HTTPPort is TCP port to listen by HTTP/REST gateway
 	HTTPPort string
+	// SwaggerUIAssets is URL of Swagger UI scripts and styles
+	SwaggerUIAssets string
 
 	// DB Datastore parameters section
 	// DatastoreDBHost is host of database
@@ -33,6 +78,56 @@ type Config struct {
 	DatastoreDBPassword string
 	// DatastoreDBSchema is schema of database
 	DatastoreDBSchema string
+
+	// Soft delete parameters section
+	// DeletedRetention is how long deleted todo tasks are kept before they are purged
+	DeletedRetention time.Duration
+	// PurgeInterval is how often deleted todo tasks are checked for purge
+	PurgeInterval time.Duration
+	// EventRetention is how long change events are kept for WatchTodos to resume
+	EventRetention time.Duration
+
+	// Idempotency parameters section
+	// IdempotencyWindow is how long Create returns the same task for retries with the same idempotency key
+	IdempotencyWindow time.Duration
+
+	// Rate limiting parameters section
+	// RateLimits is per-method quotas in format "Method=rate:burst,...", empty disables rate limiting
+	RateLimits string
+
+	// Reminder dispatch parameters section
+	// ReminderInterval is how often due reminders are scanned, 0 disables reminder dispatch
+	ReminderInterval time.Duration
+	// ReminderLookback is how old reminders are still delivered
+	ReminderLookback time.Duration
+	// ReminderLog enables writing reminders to the server log
+	ReminderLog bool
+	// ReminderWebhookURL is URL reminders are posted to as JSON, empty disables webhook notifier
+	ReminderWebhookURL string
+	// ReminderSMTPAddr is SMTP server address in format "host:port", empty disables e-mail notifier
+	ReminderSMTPAddr string
+	// ReminderSMTPUser is username to authenticate to SMTP server, empty disables authentication
+	ReminderSMTPUser string
+	// ReminderSMTPPassword is password to authenticate to SMTP server
+	ReminderSMTPPassword string
+	// ReminderSMTPFrom is sender address of reminder e-mails
+	ReminderSMTPFrom string
+	// ReminderSMTPTo is comma separated list of recipient addresses of reminder e-mails
+	ReminderSMTPTo string
+
+	// Webhook parameters section
+	// WebhookInterval is how often new change events and pending webhook deliveries are checked, 0 disables webhooks
+	WebhookInterval time.Duration
+	// WebhookMaxAttempts is number of delivery attempts before delivery is moved to dead letter log
+	WebhookMaxAttempts int
+
+	// Outbox relay parameters section
+	// OutboxLog enables relay of change events to the server log
+	OutboxLog bool
+	// OutboxWebhookURL is URL change events are relayed to as JSON, empty disables the relay
+	OutboxWebhookURL string
+	// OutboxCursorTimeout is how long events are kept for outbox sink which doesn't relay them
+	OutboxCursorTimeout time.Duration
 }
 
 // RunServer runs gRPC server and HTTP gateway
@@ -42,11 +137,44 @@ func RunServer() error {
 	// get configuration
 	var cfg Config
 	flag.StringVar(&cfg.GRPCPort, "grpc-port", "", "gRPC port to bind")
+	flag.BoolVar(&cfg.Reflection, "grpc-reflection", false, "Register gRPC server reflection for tools like grpcurl")
+	flag.DurationVar(&cfg.KeepaliveTime, "keepalive-time", 2*time.Hour, "How long connection is idle before server pings client")
+	flag.DurationVar(&cfg.KeepaliveTimeout, "keepalive-timeout", 20*time.Second, "How long server waits for ping ack before it closes connection")
+	flag.DurationVar(&cfg.KeepaliveMinTime, "keepalive-min-time", 10*time.Second, "Minimum interval of client pings, clients pinging more often are disconnected")
+	flag.BoolVar(&cfg.KeepalivePermitWithoutStream, "keepalive-permit-without-stream", true, "Allow client pings when there are no active calls")
+	flag.DurationVar(&cfg.MaxConnectionIdle, "max-connection-idle", 0, "How long connection without calls is kept open, 0 is infinity")
+	flag.DurationVar(&cfg.MaxConnectionAge, "max-connection-age", 0, "How long connection is kept open before client has to reconnect, 0 is infinity")
+	flag.DurationVar(&cfg.MaxConnectionAgeGrace, "max-connection-age-grace", 0, "How long calls can finish after max connection age, 0 is infinity")
+	flag.UintVar(&cfg.MaxConcurrentStreams, "max-concurrent-streams", 100, "Limit of concurrent calls of one connection, 0 is unlimited, the HTTP gateway isn't limited")
+	flag.IntVar(&cfg.MaxRecvMsgSize, "max-recv-msg-size", 4<<20, "Limit of request message size in bytes")
+	flag.IntVar(&cfg.MaxSendMsgSize, "max-send-msg-size", 16<<20, "Limit of response message size in bytes, above request limit as list without page size returns all tasks at once")
+	flag.IntVar(&cfg.MaxConnections, "max-connections", 0, "Limit of open gRPC connections, 0 is unlimited")
+	flag.StringVar(&cfg.V1Sunset, "v1-sunset", "", "Date API v1 is removed at in format 2006-01-02, announced to v1 clients")
 	flag.StringVar(&cfg.HTTPPort, "http-port", "", "HTTP port to bind")
+	flag.StringVar(&cfg.SwaggerUIAssets, "swagger-ui-assets", rest.DefaultSwaggerUIAssets, "URL of Swagger UI scripts and styles served at /swagger/")
 	flag.StringVar(&cfg.DatastoreDBHost, "db-host", "", "Database host")
 	flag.StringVar(&cfg.DatastoreDBUser, "db-user", "", "Database user")
 	flag.StringVar(&cfg.DatastoreDBPassword, "db-password", "", "Database password")
 	flag.StringVar(&cfg.DatastoreDBSchema, "db-schema", "", "Database schema")
+	flag.DurationVar(&cfg.DeletedRetention, "deleted-retention", 30*24*time.Hour, "How long deleted todo tasks are kept before purge")
+	flag.DurationVar(&cfg.PurgeInterval, "purge-interval", time.Hour, "How often deleted todo tasks are checked for purge")
+	flag.DurationVar(&cfg.EventRetention, "event-retention", 24*time.Hour, "How long todo change events are kept to resume watch")
+	flag.DurationVar(&cfg.IdempotencyWindow, "idempotency-window", v1.DefaultIdempotencyWindow, "How long idempotency keys of create requests are kept")
+	flag.StringVar(&cfg.RateLimits, "rate-limits", "", "Per-client rate limits in format 'Method=rate:burst,...', '*' is default for all methods")
+	flag.DurationVar(&cfg.ReminderInterval, "reminder-interval", 30*time.Second, "How often due reminders are scanned, 0 disables reminders")
+	flag.DurationVar(&cfg.ReminderLookback, "reminder-lookback", 24*time.Hour, "How old reminders are still delivered")
+	flag.BoolVar(&cfg.ReminderLog, "reminder-log", true, "Write reminders to the server log")
+	flag.StringVar(&cfg.ReminderWebhookURL, "reminder-webhook-url", "", "URL reminders are posted to")
+	flag.StringVar(&cfg.ReminderSMTPAddr, "reminder-smtp-addr", "", "SMTP server address to send reminder e-mails")
+	flag.StringVar(&cfg.ReminderSMTPUser, "reminder-smtp-user", "", "SMTP username")
+	flag.StringVar(&cfg.ReminderSMTPPassword, "reminder-smtp-password", "", "SMTP password")
+	flag.StringVar(&cfg.ReminderSMTPFrom, "reminder-smtp-from", "", "Sender address of reminder e-mails")
+	flag.StringVar(&cfg.ReminderSMTPTo, "reminder-smtp-to", "", "Comma separated recipient addresses of reminder e-mails")
+	flag.DurationVar(&cfg.WebhookInterval, "webhook-interval", 5*time.Second, "How often change events are posted to webhooks, 0 disables webhooks")
+	flag.IntVar(&cfg.WebhookMaxAttempts, "webhook-max-attempts", 8, "Number of webhook delivery attempts before dead letter log")
+	flag.BoolVar(&cfg.OutboxLog, "outbox-log", false, "Relay todo change events to the server log")
+	flag.StringVar(&cfg.OutboxWebhookURL, "outbox-webhook-url", "", "URL todo change events are relayed to")
+	flag.DurationVar(&cfg.OutboxCursorTimeout, "outbox-cursor-timeout", 7*24*time.Hour, "How long events are kept for disabled or failing outbox sink")
 	flag.Parse()
 
 	if len(cfg.GRPCPort) == 0 {
@@ -57,6 +185,69 @@ func RunServer() error {
 		return fmt.Errorf("invalid TCP port for HTTP gateway: '%s'", cfg.HTTPPort)
 	}
 
+	if cfg.MaxConcurrentStreams > math.MaxUint32 {
+		return fmt.Errorf("invalid max concurrent streams: '%d'", cfg.MaxConcurrentStreams)
+	}
+
+	if cfg.MaxRecvMsgSize <= 0 || cfg.MaxSendMsgSize <= 0 {
+		return fmt.Errorf("invalid max message sizes: receive '%d', send '%d'", cfg.MaxRecvMsgSize, cfg.MaxSendMsgSize)
+	}
+
+	if cfg.MaxConnections < 0 {
+		return fmt.Errorf("invalid max connections: '%d'", cfg.MaxConnections)
+	}
+
+	if cfg.DeletedRetention <= 0 {
+		return fmt.Errorf("invalid deleted retention: '%s'", cfg.DeletedRetention)
+	}
+
+	if cfg.EventRetention <= 0 {
+		return fmt.Errorf("invalid event retention: '%s'", cfg.EventRetention)
+	}
+
+	if cfg.OutboxCursorTimeout <= 0 {
+		return fmt.Errorf("invalid outbox cursor timeout: '%s'", cfg.OutboxCursorTimeout)
+	}
+
+	if cfg.PurgeInterval <= 0 {
+		return fmt.Errorf("invalid purge interval: '%s'", cfg.PurgeInterval)
+	}
+
+	if cfg.IdempotencyWindow <= 0 {
+		return fmt.Errorf("invalid idempotency window: '%s'", cfg.IdempotencyWindow)
+	}
+
+	versions := &middleware.VersionPolicy{
+		Supported: []string{"v1", "v2"},
+		Deprecated: map[string]middleware.Deprecation{
+			"v1": {Successor: rest.SwaggerSpecPath("v2")},
+		},
+	}
+	if len(cfg.V1Sunset) > 0 {
+		sunset, err := time.Parse("2006-01-02", cfg.V1Sunset)
+		if err != nil {
+			return fmt.Errorf("invalid sunset date of API v1: '%s'", cfg.V1Sunset)
+		}
+		versions.Deprecated["v1"] = middleware.Deprecation{Sunset: sunset, Successor: rest.SwaggerSpecPath("v2")}
+	}
+
+	quotas, err := middleware.ParseQuotas(cfg.RateLimits)
+	if err != nil {
+		return err
+	}
+
+	notifiers, err := reminderNotifiers(&cfg)
+	if err != nil {
+		return err
+	}
+
+	docs, err := rest.NewSwaggerHandler(cfg.SwaggerUIAssets,
+		rest.SwaggerSpec{Version: "v1", Spec: swagger.V1},
+		rest.SwaggerSpec{Version: "v2", Spec: swagger.V2})
+	if err != nil {
+		return fmt.Errorf("failed to load Swagger UI: %v", err)
+	}
+
 	// add MySQL driver specific parameter to parse date/time
 	// Drop it for another database
 	param := "parseTime=true"
@@ -73,12 +264,106 @@ func RunServer() error {
 	}
 	defer db.Close()
 
-	v1API := v1.NewTodoServiceServer(db)
+	v1API := v1.NewTodoServiceServer(db, cfg.IdempotencyWindow)
+	webhookAPI := v1.NewWebhookServiceServer(db)
+	v2API := v2.NewTodoServiceServer(v1API)
+
+	// purge deleted todo tasks, change events and idempotency keys after retention
+	go v1.RunPurge(ctx, db, cfg.DeletedRetention, cfg.EventRetention, cfg.OutboxCursorTimeout, cfg.IdempotencyWindow, cfg.PurgeInterval)
+
+	// deliver due reminders
+	if cfg.ReminderInterval > 0 && len(notifiers) > 0 {
+		scheduler := reminder.NewScheduler(db, reminder.SystemClock{}, notifiers)
+		scheduler.Interval = cfg.ReminderInterval
+		scheduler.Lookback = cfg.ReminderLookback
+		go scheduler.Run(ctx)
+	}
+
+	// post change events to webhooks
+	if cfg.WebhookInterval > 0 {
+		dispatcher := v1.NewWebhookDispatcher(db)
+		dispatcher.Interval = cfg.WebhookInterval
+		dispatcher.MaxAttempts = cfg.WebhookMaxAttempts
+		go dispatcher.Run(ctx)
+	}
+
+	// relay change events from outbox to sinks
+	if cfg.OutboxLog {
+		go v1.NewOutboxRelay(db, "log", outbox.LogSink{}).Run(ctx)
+	}
+	if len(cfg.OutboxWebhookURL) > 0 {
+		sink := &outbox.WebhookSink{URL: cfg.OutboxWebhookURL, Client: &http.Client{Timeout: 10 * time.Second}}
+		go v1.NewOutboxRelay(db, "webhook", sink).Run(ctx)
+	}
 
-	// run HTTP gateway
+	// run HTTP gateway, it calls gRPC server over in-process connection,
+	// it receives what server sends and sends what server receives
+	gateway := grpc.NewGatewayListener()
 	go func() {
-		_ = rest.RunServer(ctx, cfg.GRPCPort, cfg.HTTPPort)
+		_ = rest.RunServer(ctx, gateway.DialContext, cfg.HTTPPort, cfg.MaxSendMsgSize, cfg.MaxRecvMsgSize,
+			rest.Route{Pattern: v1.CalendarPathPrefix, Handler: v1.NewCalendarHandler(db)},
+			rest.Route{Pattern: rest.SwaggerPathPrefix, Handler: docs})
 	}()
 
-	return grpc.RunServer(ctx, v1API, cfg.GRPCPort)
+	opts := middleware.AddVersionHeaders(versions, nil)
+	if len(quotas) > 0 {
+		opts = middleware.AddRateLimit(middleware.NewRateLimiter(quotas), opts)
+	}
+
+	limits := grpc.Limits{
+		KeepaliveTime:                cfg.KeepaliveTime,
+		KeepaliveTimeout:             cfg.KeepaliveTimeout,
+		KeepaliveMinTime:             cfg.KeepaliveMinTime,
+		KeepalivePermitWithoutStream: cfg.KeepalivePermitWithoutStream,
+		MaxConnectionIdle:            cfg.MaxConnectionIdle,
+		MaxConnectionAge:             cfg.MaxConnectionAge,
+		MaxConnectionAgeGrace:        cfg.MaxConnectionAgeGrace,
+		MaxConcurrentStreams:         uint32(cfg.MaxConcurrentStreams),
+		MaxRecvMsgSize:               cfg.MaxRecvMsgSize,
+		MaxSendMsgSize:               cfg.MaxSendMsgSize,
+		MaxConnections:               cfg.MaxConnections,
+	}
+
+	return grpc.RunServer(ctx, v1API, webhookAPI, v2API, cfg.GRPCPort, gateway, cfg.Reflection, limits, opts...)
+}
+
+// reminderNotifiers returns configured reminder notifiers by name
+func reminderNotifiers(cfg *Config) (map[string]reminder.Notifier, error) {
+	notifiers := map[string]reminder.Notifier{}
+
+	if cfg.ReminderLog {
+		notifiers["log"] = reminder.LogNotifier{}
+	}
+
+	if len(cfg.ReminderWebhookURL) > 0 {
+		notifiers["webhook"] = &reminder.WebhookNotifier{
+			URL:    cfg.ReminderWebhookURL,
+			Client: &http.Client{Timeout: 10 * time.Second},
+		}
+	}
+
+	if len(cfg.ReminderSMTPAddr) > 0 {
+		if len(cfg.ReminderSMTPFrom) == 0 || len(cfg.ReminderSMTPTo) == 0 {
+			return nil, fmt.Errorf("reminder e-mail sender and recipients are required for SMTP server '%s'", cfg.ReminderSMTPAddr)
+		}
+
+		host, _, err := net.SplitHostPort(cfg.ReminderSMTPAddr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMTP server address '%s': %v", cfg.ReminderSMTPAddr, err)
+		}
+
+		n := &reminder.SMTPNotifier{
+			Addr: cfg.ReminderSMTPAddr,
+			From: cfg.ReminderSMTPFrom,
+		}
+		for _, to := range strings.Split(cfg.ReminderSMTPTo, ",") {
+			n.To = append(n.To, strings.TrimSpace(to))
+		}
+		if len(cfg.ReminderSMTPUser) > 0 {
+			n.Auth = smtp.PlainAuth("", cfg.ReminderSMTPUser, cfg.ReminderSMTPPassword, host)
+		}
+		notifiers["smtp"] = n
+	}
+
+	return notifiers, nil
 }
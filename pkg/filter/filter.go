@@ -0,0 +1,357 @@
+// Package filter parses filter and sort expressions of list calls
+// into parameterised SQL clauses over a whitelist of fields.
+//
+// Filter grammar:
+//
+//	expr       = term { "OR" term }
+//	term       = factor { "AND" factor }
+//	factor     = "NOT" factor | "(" expr ")" | comparison
+//	comparison = field op value
+//	op         = "=" | "!=" | "<" | "<=" | ">" | ">=" | ":"
+//	value      = string | number | identifier | "null"
+//
+// ":" means "contains" for string fields and "has" for set fields.
+// Time values are RFC 3339 strings, enum values are names or numbers.
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxLength is maximum length of filter expression in bytes
+	MaxLength = 8192
+
+	// MaxDepth is maximum nesting of parentheses and NOT in filter expression
+	MaxDepth = 32
+)
+
+// Type is type of filtered field value
+type Type int
+
+const (
+	// String is text field
+	String Type = iota
+	// Int is integer field
+	Int
+	// Time is date and time field, values are RFC 3339 strings
+	Time
+	// Enum is integer field with named values
+	Enum
+	// Set is field with multiple string values, Column is SQL condition with one "?"
+	// which is true if the value is in the set. Only "=", "!=" and ":" can be used
+	Set
+)
+
+// Field describes field which can be used in filter and order expressions
+type Field struct {
+	// Column is SQL column (or expression) of the field
+	Column string
+	// Type is type of field value
+	Type Type
+	// Nullable is true if the field can be compared with null
+	Nullable bool
+	// Values maps names of Enum field values to numbers
+	Values map[string]int32
+	// Normalize converts Set value to form values are stored in, nil keeps value as is
+	Normalize func(string) string
+}
+
+// Fields is whitelist of fields by name used in expressions
+type Fields map[string]Field
+
+// Parse converts filter expression to SQL condition with "?" placeholders and its arguments.
+// Empty expression returns empty condition
+func Parse(expr string, fields Fields) (string, []interface{}, error) {
+	if len(expr) > MaxLength {
+		return "", nil, fmt.Errorf("filter is longer than %d bytes", MaxLength)
+	}
+
+	tokens, err := lex(expr)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(tokens) == 0 {
+		return "", nil, nil
+	}
+
+	p := &parser{tokens: tokens, fields: fields}
+	where, err := p.expr()
+	if err != nil {
+		return "", nil, err
+	}
+	if !p.done() {
+		return "", nil, fmt.Errorf("unexpected '%s' at position %d", p.peek().text, p.peek().pos)
+	}
+
+	return where, p.args, nil
+}
+
+// OrderBy converts sort expression in format "field [asc|desc], ..." to SQL ORDER BY list.
+// Empty expression returns empty list
+func OrderBy(orderBy string, fields Fields) (string, error) {
+	var items []string
+	for _, item := range strings.Split(orderBy, ",") {
+		parts := strings.Fields(item)
+		if len(parts) == 0 {
+			if len(strings.TrimSpace(orderBy)) > 0 {
+				return "", fmt.Errorf("empty field in order by '%s'", orderBy)
+			}
+			continue
+		}
+		if len(parts) > 2 {
+			return "", fmt.Errorf("invalid order by item '%s'", strings.TrimSpace(item))
+		}
+
+		f, ok := fields[parts[0]]
+		if !ok || f.Type == Set {
+			return "", fmt.Errorf("unknown field '%s' in order by", parts[0])
+		}
+
+		dir := "ASC"
+		if len(parts) == 2 {
+			switch strings.ToLower(parts[1]) {
+			case "asc":
+			case "desc":
+				dir = "DESC"
+			default:
+				return "", fmt.Errorf("invalid sort direction '%s' in order by", parts[1])
+			}
+		}
+
+		items = append(items, f.Column+" "+dir)
+	}
+
+	return strings.Join(items, ", "), nil
+}
+
+// parser is recursive descent parser of filter expression
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+	fields Fields
+	args   []interface{}
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	if p.done() {
+		return token{kind: tokenEOF, text: "end of filter", pos: -1}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// keyword returns true and consumes the token if it is the keyword
+func (p *parser) keyword(kw string) bool {
+	t := p.peek()
+	if t.kind == tokenIdent && strings.EqualFold(t.text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expr() (string, error) {
+	return p.join("OR", p.term)
+}
+
+func (p *parser) term() (string, error) {
+	return p.join("AND", p.factor)
+}
+
+// join parses operands separated by the keyword, conditions of several operands are
+// written once into parentheses so output is built in linear time
+func (p *parser) join(kw string, operand func() (string, error)) (string, error) {
+	first, err := operand()
+	if err != nil {
+		return "", err
+	}
+	if !p.keyword(kw) {
+		return first, nil
+	}
+
+	var b strings.Builder
+	b.WriteString("(")
+	b.WriteString(first)
+	for {
+		next, err := operand()
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(" " + kw + " ")
+		b.WriteString(next)
+		if !p.keyword(kw) {
+			break
+		}
+	}
+	b.WriteString(")")
+	return b.String(), nil
+}
+
+func (p *parser) factor() (string, error) {
+	if p.peek().kind == tokenLParen || (p.peek().kind == tokenIdent && strings.EqualFold(p.peek().text, "NOT")) {
+		p.depth++
+		defer func() { p.depth-- }()
+		if p.depth > MaxDepth {
+			return "", fmt.Errorf("filter is nested deeper than %d levels at position %d", MaxDepth, p.peek().pos)
+		}
+	}
+
+	if p.keyword("NOT") {
+		f, err := p.factor()
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + f, nil
+	}
+
+	if p.peek().kind == tokenLParen {
+		p.next()
+		e, err := p.expr()
+		if err != nil {
+			return "", err
+		}
+		if t := p.next(); t.kind != tokenRParen {
+			return "", fmt.Errorf("expected ')' but found '%s'", t.text)
+		}
+		return "(" + e + ")", nil
+	}
+
+	return p.comparison()
+}
+
+func (p *parser) comparison() (string, error) {
+	name := p.next()
+	if name.kind != tokenIdent {
+		return "", fmt.Errorf("expected field name but found '%s'", name.text)
+	}
+	f, ok := p.fields[name.text]
+	if !ok {
+		return "", fmt.Errorf("unknown field '%s'", name.text)
+	}
+
+	op := p.next()
+	if op.kind != tokenOp {
+		return "", fmt.Errorf("expected comparison operator after '%s' but found '%s'", name.text, op.text)
+	}
+
+	v := p.next()
+	if v.kind != tokenString && v.kind != tokenNumber && v.kind != tokenIdent {
+		return "", fmt.Errorf("expected value after '%s %s' but found '%s'", name.text, op.text, v.text)
+	}
+
+	// null comparison
+	if v.kind == tokenIdent && strings.EqualFold(v.text, "null") {
+		if !f.Nullable {
+			return "", fmt.Errorf("field '%s' can't be null", name.text)
+		}
+		switch op.text {
+		case "=":
+			return f.Column + " IS NULL", nil
+		case "!=":
+			return f.Column + " IS NOT NULL", nil
+		default:
+			return "", fmt.Errorf("operator '%s' can't be used with null", op.text)
+		}
+	}
+
+	if f.Type == Set {
+		if v.kind != tokenString {
+			return "", fmt.Errorf("expected string value for '%s' but found '%s'", name.text, v.text)
+		}
+		arg := v.text
+		if f.Normalize != nil {
+			arg = f.Normalize(arg)
+		}
+		p.args = append(p.args, arg)
+		switch op.text {
+		case "=", ":":
+			return f.Column, nil
+		case "!=":
+			return "NOT (" + f.Column + ")", nil
+		default:
+			return "", fmt.Errorf("operator '%s' can't be used with '%s'", op.text, name.text)
+		}
+	}
+
+	if op.text == ":" {
+		if f.Type != String {
+			return "", fmt.Errorf("operator ':' can be used with text fields only, '%s' is not", name.text)
+		}
+		if v.kind != tokenString {
+			return "", fmt.Errorf("expected string value for '%s' but found '%s'", name.text, v.text)
+		}
+		p.args = append(p.args, "%"+EscapeLike(v.text)+"%")
+		return f.Column + " LIKE ?", nil
+	}
+
+	arg, err := value(name.text, f, v)
+	if err != nil {
+		return "", err
+	}
+	p.args = append(p.args, arg)
+
+	return f.Column + " " + op.text + " ?", nil
+}
+
+// value converts literal token to SQL argument of field type
+func value(name string, f Field, v token) (interface{}, error) {
+	switch f.Type {
+	case String:
+		if v.kind != tokenString {
+			return nil, fmt.Errorf("expected string value for '%s' but found '%s'", name, v.text)
+		}
+		return v.text, nil
+
+	case Int:
+		n, err := strconv.ParseInt(v.text, 10, 64)
+		if v.kind != tokenNumber || err != nil {
+			return nil, fmt.Errorf("expected integer value for '%s' but found '%s'", name, v.text)
+		}
+		return n, nil
+
+	case Time:
+		if v.kind != tokenString {
+			return nil, fmt.Errorf("expected RFC 3339 string value for '%s' but found '%s'", name, v.text)
+		}
+		t, err := time.Parse(time.RFC3339Nano, v.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time value for '%s': %v", name, err)
+		}
+		return t.In(time.UTC), nil
+
+	case Enum:
+		if v.kind == tokenNumber {
+			n, err := strconv.ParseInt(v.text, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value '%s' for '%s'", v.text, name)
+			}
+			return int32(n), nil
+		}
+		n, ok := f.Values[strings.ToUpper(v.text)]
+		if !ok {
+			return nil, fmt.Errorf("invalid value '%s' for '%s'", v.text, name)
+		}
+		return n, nil
+	}
+
+	return nil, fmt.Errorf("unsupported type of field '%s'", name)
+}
+
+// EscapeLike escapes LIKE pattern wildcards in s so it is matched literally
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,140 @@
+package filter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testFields = Fields{
+	"id":       {Column: "id", Type: Int},
+	"title":    {Column: "title", Type: String},
+	"due_date": {Column: "due_date", Type: Time, Nullable: true},
+	"status":   {Column: "status", Type: Enum, Values: map[string]int32{"OPEN": 1, "DONE": 2}},
+	"labels":   {Column: "EXISTS (SELECT 1 FROM todo_label l WHERE l.todo_id = id AND l.label = ?)", Type: Set},
+	"tags":     {Column: "tag = ?", Type: Set, Normalize: strings.ToLower},
+}
+
+func TestParse(t *testing.T) {
+	due := time.Date(2021, 10, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		expr     string
+		want     string
+		wantArgs []interface{}
+		wantErr  bool
+	}{
+		{name: "empty", expr: "  ", want: ""},
+		{name: "comparison", expr: "id > 5", want: "id > ?", wantArgs: []interface{}{int64(5)}},
+		{name: "string", expr: `title = "a \"b\""`, want: "title = ?", wantArgs: []interface{}{`a "b"`}},
+		{name: "contains", expr: `title: "50%_"`, want: "title LIKE ?", wantArgs: []interface{}{`%50\%\_%`}},
+		{name: "time", expr: `due_date < "2021-10-01T12:00:00+02:00"`, want: "due_date < ?", wantArgs: []interface{}{due}},
+		{name: "null", expr: "due_date = null", want: "due_date IS NULL"},
+		{name: "not null", expr: "due_date != NULL", want: "due_date IS NOT NULL"},
+		{name: "enum name", expr: "status = done", want: "status = ?", wantArgs: []interface{}{int32(2)}},
+		{name: "enum number", expr: "status = 1", want: "status = ?", wantArgs: []interface{}{int32(1)}},
+		{name: "set", expr: `labels: "work"`, want: testFields["labels"].Column, wantArgs: []interface{}{"work"}},
+		{name: "normalized set value", expr: `tags: "Work"`, want: "tag = ?", wantArgs: []interface{}{"work"}},
+		{name: "not in set", expr: `labels != "work"`, want: "NOT (" + testFields["labels"].Column + ")", wantArgs: []interface{}{"work"}},
+		{
+			name:     "and binds tighter than or",
+			expr:     "id = 1 OR id = 2 AND id = 3 or id = 4",
+			want:     "(id = ? OR (id = ? AND id = ?) OR id = ?)",
+			wantArgs: []interface{}{int64(1), int64(2), int64(3), int64(4)},
+		},
+		{
+			name:     "parentheses and not",
+			expr:     "NOT (id = 1 OR id = 2) AND id = 3",
+			want:     "(NOT ((id = ? OR id = ?)) AND id = ?)",
+			wantArgs: []interface{}{int64(1), int64(2), int64(3)},
+		},
+		{name: "unknown field", expr: "owner = 1", wantErr: true},
+		{name: "missing operator", expr: "id 1", wantErr: true},
+		{name: "missing value", expr: "id =", wantErr: true},
+		{name: "wrong value type", expr: `id = "1"`, wantErr: true},
+		{name: "invalid time", expr: `due_date < "tomorrow"`, wantErr: true},
+		{name: "invalid enum", expr: "status = LATER", wantErr: true},
+		{name: "not nullable", expr: "title = null", wantErr: true},
+		{name: "null with order operator", expr: "due_date < null", wantErr: true},
+		{name: "contains on number", expr: `id: "1"`, wantErr: true},
+		{name: "order operator on set", expr: `labels < "a"`, wantErr: true},
+		{name: "unbalanced", expr: "(id = 1", wantErr: true},
+		{name: "trailing token", expr: "id = 1)", wantErr: true},
+		{name: "unterminated string", expr: `title = "a`, wantErr: true},
+		{name: "unexpected character", expr: "id = 1 & id = 2", wantErr: true},
+		{name: "too long", expr: strings.Repeat(" ", MaxLength) + "id = 1", wantErr: true},
+		{name: "max depth", expr: strings.Repeat("(", MaxDepth) + "id = 1" + strings.Repeat(")", MaxDepth), want: strings.Repeat("(", MaxDepth) + "id = ?" + strings.Repeat(")", MaxDepth), wantArgs: []interface{}{int64(1)}},
+		{name: "too deep", expr: strings.Repeat("(", MaxDepth+1) + "id = 1" + strings.Repeat(")", MaxDepth+1), wantErr: true},
+		{name: "too many not", expr: strings.Repeat("NOT ", MaxDepth+1) + "id = 1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args, err := Parse(tt.expr, testFields)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Parse() = %s, want %s", got, tt.want)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("Parse() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestParseLongExpression(t *testing.T) {
+	// the longest expression allowed is parsed into one flat condition
+	term := "id = 1 OR "
+	expr := strings.Repeat(term, MaxLength/len(term)-1) + "id = 1"
+	got, args, err := Parse(expr, testFields)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(args) != MaxLength/len(term) {
+		t.Errorf("Parse() args = %d, want %d", len(args), MaxLength/len(term))
+	}
+	if strings.Count(got, "(") != 1 {
+		t.Errorf("Parse() = %s, want one pair of parentheses", got)
+	}
+}
+
+func TestOrderBy(t *testing.T) {
+	tests := []struct {
+		name    string
+		orderBy string
+		want    string
+		wantErr bool
+	}{
+		{name: "empty", orderBy: "", want: ""},
+		{name: "default direction", orderBy: "id", want: "id ASC"},
+		{name: "several fields", orderBy: "due_date desc, title ASC", want: "due_date DESC, title ASC"},
+		{name: "unknown field", orderBy: "owner", wantErr: true},
+		{name: "set field", orderBy: "labels", wantErr: true},
+		{name: "invalid direction", orderBy: "id up", wantErr: true},
+		{name: "empty item", orderBy: "id,", wantErr: true},
+		{name: "too many words", orderBy: "id asc desc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := OrderBy(tt.orderBy, testFields)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("OrderBy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("OrderBy() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	if got, want := EscapeLike(`a\b%c_d`), `a\\b\%c\_d`; got != want {
+		t.Errorf("EscapeLike() = %s, want %s", got, want)
+	}
+}
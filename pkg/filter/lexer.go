@@ -0,0 +1,97 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// tokenKind is kind of filter expression token
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+// token is lexical token of filter expression
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lex splits filter expression to tokens
+func lex(s string) ([]token, error) {
+	var tokens []token
+	r := []rune(s)
+	for i := 0; i < len(r); {
+		c := r[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+
+		case c == '"' || c == '\'':
+			// quoted string, backslash escapes the next character
+			start := i
+			var b strings.Builder
+			i++
+			for ; i < len(r) && r[i] != c; i++ {
+				if r[i] == '\\' && i+1 < len(r) {
+					i++
+				}
+				b.WriteRune(r[i])
+			}
+			if i >= len(r) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, text: b.String(), pos: start})
+
+		case strings.ContainsRune("=!<>:", c):
+			start := i
+			op := string(c)
+			if i+1 < len(r) && r[i+1] == '=' && c != '=' && c != ':' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("unexpected '!' at position %d", start)
+			}
+			i += len(op)
+			tokens = append(tokens, token{kind: tokenOp, text: op, pos: start})
+
+		case c == '-' || unicode.IsDigit(c):
+			start := i
+			i++
+			for i < len(r) && unicode.IsDigit(r[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(r[start:i]), pos: start})
+
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(r) && (r[i] == '_' || r[i] == '.' || unicode.IsLetter(r[i]) || unicode.IsDigit(r[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(r[start:i]), pos: start})
+
+		default:
+			return nil, fmt.Errorf("unexpected '%c' at position %d", c, i)
+		}
+	}
+
+	return tokens, nil
+}
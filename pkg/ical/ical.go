@@ -0,0 +1,521 @@
+// Package ical reads and writes iCalendar (RFC 5545) data.
+//
+// Data is parsed to a tree of components with properties, the package doesn't know
+// meaning of components, except for helpers to convert text, date-time and duration values.
+// Lines are unfolded on read and folded at 75 octets on write.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLineLength is maximum length of content line in octets without line break
+const maxLineLength = 75
+
+// MaxDepth is maximum nesting of components, VCALENDAR with VTODO with VALARM is 3 levels deep.
+// Decoder rejects deeper data, so crafted input can't exhaust the stack
+const MaxDepth = 8
+
+// Property is content line of component
+type Property struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// Param returns value of property parameter, empty if it's not set
+func (p *Property) Param(name string) string {
+	return p.Params[strings.ToUpper(name)]
+}
+
+// Component is iCalendar object, e.g. VCALENDAR, VTODO or VALARM
+type Component struct {
+	Name       string
+	Properties []*Property
+	Components []*Component
+}
+
+// NewComponent returns empty component
+func NewComponent(name string) *Component {
+	return &Component{Name: strings.ToUpper(name)}
+}
+
+// Add appends property to component
+func (c *Component) Add(name, value string, params ...string) *Property {
+	p := &Property{Name: strings.ToUpper(name), Value: value}
+	if len(params) > 0 {
+		p.Params = map[string]string{}
+		for i := 0; i+1 < len(params); i += 2 {
+			p.Params[strings.ToUpper(params[i])] = params[i+1]
+		}
+	}
+	c.Properties = append(c.Properties, p)
+	return p
+}
+
+// Get returns the first property with the name, nil if there is no such property
+func (c *Component) Get(name string) *Property {
+	name = strings.ToUpper(name)
+	for _, p := range c.Properties {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Value returns value of the first property with the name, empty if there is no such property
+func (c *Component) Value(name string) string {
+	if p := c.Get(name); p != nil {
+		return p.Value
+	}
+	return ""
+}
+
+// Children returns subcomponents with the name
+func (c *Component) Children(name string) []*Component {
+	name = strings.ToUpper(name)
+	var list []*Component
+	for _, sub := range c.Components {
+		if sub.Name == name {
+			list = append(list, sub)
+		}
+	}
+	return list
+}
+
+// Decoder reads components from iCalendar stream
+type Decoder struct {
+	r    *bufio.Reader
+	line int
+	// next is the first physical line of the next content line, read ahead to unfold lines
+	next    string
+	hasNext bool
+}
+
+// NewDecoder returns decoder reading from r
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Decode returns the next top-level component, io.EOF if there are no more components
+func (d *Decoder) Decode() (*Component, error) {
+	p, err := d.readProperty()
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, io.EOF
+	}
+	if p.Name != "BEGIN" {
+		return nil, d.errorf("expected BEGIN, got %s", p.Name)
+	}
+	return d.readComponent(strings.ToUpper(p.Value), 1)
+}
+
+// readComponent reads properties and subcomponents until END of component nested depth levels deep
+func (d *Decoder) readComponent(name string, depth int) (*Component, error) {
+	c := NewComponent(name)
+	for {
+		p, err := d.readProperty()
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			return nil, d.errorf("unexpected end of data in %s", name)
+		}
+
+		switch p.Name {
+		case "BEGIN":
+			if depth >= MaxDepth {
+				return nil, d.errorf("components are nested deeper than %d levels", MaxDepth)
+			}
+			sub, err := d.readComponent(strings.ToUpper(p.Value), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			c.Components = append(c.Components, sub)
+		case "END":
+			if !strings.EqualFold(p.Value, name) {
+				return nil, d.errorf("expected END:%s, got END:%s", name, p.Value)
+			}
+			return c, nil
+		default:
+			c.Properties = append(c.Properties, p)
+		}
+	}
+}
+
+// readProperty reads and parses the next content line, nil at the end of data
+func (d *Decoder) readProperty() (*Property, error) {
+	line, err := d.readLine()
+	if err != nil || line == "" {
+		return nil, err
+	}
+	return d.parseProperty(line)
+}
+
+// readLine returns the next unfolded content line skipping empty lines, empty at the end of data
+func (d *Decoder) readLine() (string, error) {
+	var b strings.Builder
+	for {
+		var l string
+		if d.hasNext {
+			l, d.hasNext = d.next, false
+		} else {
+			var err error
+			l, err = d.readPhysicalLine()
+			if err == io.EOF {
+				return b.String(), nil
+			}
+			if err != nil {
+				return "", err
+			}
+		}
+
+		// line starting with space or tab continues the previous one
+		if len(l) > 0 && (l[0] == ' ' || l[0] == '\t') {
+			if b.Len() == 0 {
+				return "", d.errorf("continuation line without content line")
+			}
+			b.WriteString(l[1:])
+			continue
+		}
+
+		if b.Len() > 0 {
+			d.next, d.hasNext = l, true
+			return b.String(), nil
+		}
+		if len(l) > 0 {
+			b.WriteString(l)
+		}
+	}
+}
+
+// readPhysicalLine returns the next line without line break
+func (d *Decoder) readPhysicalLine() (string, error) {
+	l, err := d.r.ReadString('\n')
+	if err == io.EOF && len(l) > 0 {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	d.line++
+	return strings.TrimRight(l, "\r\n"), nil
+}
+
+// parseProperty parses content line "NAME;PARAM=VALUE:value"
+func (d *Decoder) parseProperty(line string) (*Property, error) {
+	p := &Property{}
+
+	i := strings.IndexAny(line, ";:")
+	if i <= 0 {
+		return nil, d.errorf("invalid content line '%s'", line)
+	}
+	p.Name = strings.ToUpper(line[:i])
+	line = line[i:]
+
+	for line[0] == ';' {
+		line = line[1:]
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			return nil, d.errorf("invalid parameter of %s", p.Name)
+		}
+		name := strings.ToUpper(line[:eq])
+		line = line[eq+1:]
+
+		var value string
+		if strings.HasPrefix(line, `"`) {
+			end := strings.IndexByte(line[1:], '"')
+			if end < 0 {
+				return nil, d.errorf("unterminated quoted parameter %s of %s", name, p.Name)
+			}
+			value, line = line[1:end+1], line[end+2:]
+		} else {
+			end := strings.IndexAny(line, ";:")
+			if end < 0 {
+				return nil, d.errorf("missing value of %s", p.Name)
+			}
+			value, line = line[:end], line[end:]
+		}
+		if p.Params == nil {
+			p.Params = map[string]string{}
+		}
+		p.Params[name] = value
+
+		if len(line) == 0 {
+			return nil, d.errorf("missing value of %s", p.Name)
+		}
+	}
+
+	if line[0] != ':' {
+		return nil, d.errorf("missing value of %s", p.Name)
+	}
+	p.Value = line[1:]
+
+	return p, nil
+}
+
+// errorf returns error with the current line number
+func (d *Decoder) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", d.line, fmt.Sprintf(format, args...))
+}
+
+// Encode writes component with its subcomponents to w
+func Encode(w io.Writer, c *Component) error {
+	if err := EncodeBegin(w, c); err != nil {
+		return err
+	}
+	for _, sub := range c.Components {
+		if err := Encode(w, sub); err != nil {
+			return err
+		}
+	}
+	return EncodeEnd(w, c)
+}
+
+// EncodeBegin writes BEGIN and properties of component, subcomponents may be written after it
+// one by one to stream large component, EncodeEnd completes the component
+func EncodeBegin(w io.Writer, c *Component) error {
+	if err := writeLine(w, "BEGIN:"+c.Name); err != nil {
+		return err
+	}
+	for _, p := range c.Properties {
+		if err := writeLine(w, formatProperty(p)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncodeEnd writes END of component
+func EncodeEnd(w io.Writer, c *Component) error {
+	return writeLine(w, "END:"+c.Name)
+}
+
+// formatProperty returns content line of property, parameters are sorted by name
+func formatProperty(p *Property) string {
+	var b strings.Builder
+	b.WriteString(p.Name)
+
+	names := make([]string, 0, len(p.Params))
+	for name := range p.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v := p.Params[name]
+		b.WriteString(";" + name + "=")
+		if strings.ContainsAny(v, ";:,") {
+			b.WriteString(`"` + strings.ReplaceAll(v, `"`, "") + `"`)
+		} else {
+			b.WriteString(v)
+		}
+	}
+
+	b.WriteString(":" + p.Value)
+	return b.String()
+}
+
+// writeLine writes content line folded at maxLineLength octets, not splitting UTF-8 characters
+func writeLine(w io.Writer, line string) error {
+	var b strings.Builder
+	limit := maxLineLength
+	for len(line) > limit {
+		i := limit
+		for i > 0 && !utf8.RuneStart(line[i]) {
+			i--
+		}
+		b.WriteString(line[:i] + "\r\n ")
+		line = line[i:]
+		// the leading space of continuation line counts to its length
+		limit = maxLineLength - 1
+	}
+	b.WriteString(line + "\r\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// EscapeText escapes TEXT value
+func EscapeText(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return r.Replace(s)
+}
+
+// UnescapeText unescapes TEXT value
+func UnescapeText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// SplitText splits list of TEXT values separated by unescaped commas and unescapes them
+func SplitText(s string) []string {
+	var list []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ',':
+			list = append(list, UnescapeText(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(list, UnescapeText(s[start:]))
+}
+
+// JoinText escapes TEXT values and joins them to list
+func JoinText(list []string) string {
+	escaped := make([]string, len(list))
+	for i, s := range list {
+		escaped[i] = EscapeText(s)
+	}
+	return strings.Join(escaped, ",")
+}
+
+// FormatTime returns DATE-TIME value in UTC
+func FormatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// ParseTime parses DATE or DATE-TIME value of property. Time without "Z" is in time zone
+// of TZID parameter or in loc if the parameter is not set, DATE is midnight in the same zone
+func ParseTime(p *Property, loc *time.Location) (time.Time, error) {
+	if tzid := p.Param("TZID"); len(tzid) > 0 {
+		l, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unknown TZID '%s' of %s", tzid, p.Name)
+		}
+		loc = l
+	}
+
+	v := p.Value
+	switch {
+	case strings.EqualFold(p.Param("VALUE"), "DATE") || len(v) == 8:
+		t, err := time.ParseInLocation("20060102", v, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date '%s' of %s", v, p.Name)
+		}
+		return t, nil
+	case strings.HasSuffix(v, "Z"):
+		t, err := time.Parse("20060102T150405Z", v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date-time '%s' of %s", v, p.Name)
+		}
+		return t, nil
+	default:
+		t, err := time.ParseInLocation("20060102T150405", v, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date-time '%s' of %s", v, p.Name)
+		}
+		return t, nil
+	}
+}
+
+// FormatDuration returns DURATION value, e.g. "-PT15M"
+func FormatDuration(d time.Duration) string {
+	var b strings.Builder
+	if d < 0 {
+		b.WriteByte('-')
+		d = -d
+	}
+	b.WriteByte('P')
+
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	if days > 0 {
+		b.WriteString(strconv.FormatInt(int64(days), 10) + "D")
+	}
+	if d > 0 || days == 0 {
+		b.WriteByte('T')
+		h, m, s := d/time.Hour, d%time.Hour/time.Minute, d%time.Minute/time.Second
+		if h > 0 {
+			b.WriteString(strconv.FormatInt(int64(h), 10) + "H")
+		}
+		if m > 0 {
+			b.WriteString(strconv.FormatInt(int64(m), 10) + "M")
+		}
+		if s > 0 || (h == 0 && m == 0) {
+			b.WriteString(strconv.FormatInt(int64(s), 10) + "S")
+		}
+	}
+	return b.String()
+}
+
+// ParseDuration parses DURATION value, e.g. "-PT15M" or "P1DT2H"
+func ParseDuration(s string) (time.Duration, error) {
+	v := s
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(v, "-"):
+		sign, v = -1, v[1:]
+	case strings.HasPrefix(v, "+"):
+		v = v[1:]
+	}
+	if !strings.HasPrefix(v, "P") || len(v) == 1 {
+		return 0, fmt.Errorf("invalid duration '%s'", s)
+	}
+	v = v[1:]
+
+	var d time.Duration
+	inTime := false
+	for len(v) > 0 {
+		if v[0] == 'T' {
+			inTime, v = true, v[1:]
+			continue
+		}
+		i := 0
+		for i < len(v) && v[i] >= '0' && v[i] <= '9' {
+			i++
+		}
+		if i == 0 || i == len(v) {
+			return 0, fmt.Errorf("invalid duration '%s'", s)
+		}
+		n, err := strconv.ParseInt(v[:i], 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration '%s'", s)
+		}
+
+		var unit time.Duration
+		switch {
+		case v[i] == 'W' && !inTime:
+			unit = 7 * 24 * time.Hour
+		case v[i] == 'D' && !inTime:
+			unit = 24 * time.Hour
+		case v[i] == 'H' && inTime:
+			unit = time.Hour
+		case v[i] == 'M' && inTime:
+			unit = time.Minute
+		case v[i] == 'S' && inTime:
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration '%s'", s)
+		}
+		d += time.Duration(n) * unit
+		v = v[i+1:]
+	}
+
+	return sign * d, nil
+}
@@ -0,0 +1,213 @@
+package ical
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// nested returns data with components nested depth levels deep
+func nested(depth int) string {
+	var b strings.Builder
+	for i := 0; i < depth; i++ {
+		b.WriteString("BEGIN:X\r\n")
+	}
+	for i := 0; i < depth; i++ {
+		b.WriteString("END:X\r\n")
+	}
+	return b.String()
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    *Component
+		wantErr bool
+	}{
+		{
+			name: "calendar",
+			data: "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VTODO\r\nSUMMARY;LANGUAGE=en:Call Bob\r\n" +
+				"BEGIN:VALARM\r\nTRIGGER:-PT15M\r\nEND:VALARM\r\nEND:VTODO\r\nEND:VCALENDAR\r\n",
+			want: &Component{
+				Name:       "VCALENDAR",
+				Properties: []*Property{{Name: "VERSION", Value: "2.0"}},
+				Components: []*Component{{
+					Name:       "VTODO",
+					Properties: []*Property{{Name: "SUMMARY", Params: map[string]string{"LANGUAGE": "en"}, Value: "Call Bob"}},
+					Components: []*Component{{Name: "VALARM", Properties: []*Property{{Name: "TRIGGER", Value: "-PT15M"}}}},
+				}},
+			},
+		},
+		{
+			name: "folded lines and lower case",
+			data: "begin:vtodo\nsummary:Call\n  Bob\n\t and Alice\n\nend:VTODO",
+			want: &Component{Name: "VTODO", Properties: []*Property{{Name: "SUMMARY", Value: "Call Bob and Alice"}}},
+		},
+		{
+			name: "quoted parameter",
+			data: "BEGIN:VTODO\r\nDTSTART;TZID=\"Europe/Berlin\";VALUE=DATE-TIME:20211001T090000\r\nEND:VTODO\r\n",
+			want: &Component{Name: "VTODO", Properties: []*Property{{Name: "DTSTART",
+				Params: map[string]string{"TZID": "Europe/Berlin", "VALUE": "DATE-TIME"}, Value: "20211001T090000"}}},
+		},
+		{name: "maximum nesting", data: nested(MaxDepth), want: func() *Component {
+			c := NewComponent("X")
+			for i := 1; i < MaxDepth; i++ {
+				c = &Component{Name: "X", Components: []*Component{c}}
+			}
+			return c
+		}()},
+		{name: "too deep nesting", data: nested(MaxDepth + 1), wantErr: true},
+		{name: "deep nesting without end", data: strings.Repeat("BEGIN:X\r\n", 100000), wantErr: true},
+		{name: "missing begin", data: "SUMMARY:Call Bob\r\n", wantErr: true},
+		{name: "mismatched end", data: "BEGIN:VTODO\r\nEND:VEVENT\r\n", wantErr: true},
+		{name: "unexpected end of data", data: "BEGIN:VTODO\r\nSUMMARY:Call Bob\r\n", wantErr: true},
+		{name: "continuation without content line", data: " BEGIN:VTODO\r\n", wantErr: true},
+		{name: "missing value", data: "BEGIN:VTODO\r\nSUMMARY;LANGUAGE=en\r\nEND:VTODO\r\n", wantErr: true},
+		{name: "unterminated quote", data: "BEGIN:VTODO\r\nSUMMARY;X=\"a:b\r\nEND:VTODO\r\n", wantErr: true},
+		{name: "invalid parameter", data: "BEGIN:VTODO\r\nSUMMARY;X:a\r\nEND:VTODO\r\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewDecoder(strings.NewReader(tt.data)).Decode()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeEOF(t *testing.T) {
+	d := NewDecoder(strings.NewReader("BEGIN:VTODO\r\nEND:VTODO\r\n\r\nBEGIN:VTODO\r\nEND:VTODO\r\n"))
+	for i := 0; i < 2; i++ {
+		if _, err := d.Decode(); err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+	}
+	if _, err := d.Decode(); err != io.EOF {
+		t.Errorf("Decode() error = %v, want EOF", err)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	c := NewComponent("vtodo")
+	c.Add("summary", strings.Repeat("ä", 50), "language", "de", "x-list", "a;b")
+	c.Components = append(c.Components, NewComponent("valarm"))
+
+	var b strings.Builder
+	if err := Encode(&b, c); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		if len(line) > maxLineLength {
+			t.Errorf("line %q is longer than %d octets", line, maxLineLength)
+		}
+	}
+
+	got, err := NewDecoder(strings.NewReader(b.String())).Decode()
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, c) {
+		t.Errorf("Decode() = %+v, want %+v", got, c)
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		text    string
+		escaped string
+	}{
+		{text: "plain", escaped: "plain"},
+		{text: "a;b,c\\d", escaped: `a\;b\,c\\d`},
+		{text: "line\nbreak", escaped: `line\nbreak`},
+	}
+	for _, tt := range tests {
+		if got := EscapeText(tt.text); got != tt.escaped {
+			t.Errorf("EscapeText(%q) = %q, want %q", tt.text, got, tt.escaped)
+		}
+		if got := UnescapeText(tt.escaped); got != tt.text {
+			t.Errorf("UnescapeText(%q) = %q, want %q", tt.escaped, got, tt.text)
+		}
+	}
+
+	list := []string{"work", "a,b", ""}
+	if got := SplitText(JoinText(list)); !reflect.DeepEqual(got, list) {
+		t.Errorf("SplitText(JoinText(%q)) = %q", list, got)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone database is not available")
+	}
+
+	tests := []struct {
+		name    string
+		prop    *Property
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "utc", prop: &Property{Value: "20211001T090000Z"}, want: time.Date(2021, 10, 1, 9, 0, 0, 0, time.UTC)},
+		{name: "floating", prop: &Property{Value: "20211001T090000"}, want: time.Date(2021, 10, 1, 9, 0, 0, 0, berlin)},
+		{name: "date", prop: &Property{Value: "20211001"}, want: time.Date(2021, 10, 1, 0, 0, 0, 0, berlin)},
+		{
+			name: "time zone",
+			prop: &Property{Params: map[string]string{"TZID": "UTC"}, Value: "20211001T090000"},
+			want: time.Date(2021, 10, 1, 9, 0, 0, 0, time.UTC),
+		},
+		{name: "unknown time zone", prop: &Property{Params: map[string]string{"TZID": "Mars/Base"}, Value: "20211001T090000"}, wantErr: true},
+		{name: "invalid", prop: &Property{Value: "tomorrow"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTime(tt.prop, berlin)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseTime() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		s       string
+		d       time.Duration
+		format  bool
+		wantErr bool
+	}{
+		{s: "-PT15M", d: -15 * time.Minute, format: true},
+		{s: "P1DT2H", d: 26 * time.Hour, format: true},
+		{s: "PT0S", d: 0, format: true},
+		{s: "P2W", d: 14 * 24 * time.Hour},
+		{s: "+PT1H30M5S", d: time.Hour + 30*time.Minute + 5*time.Second},
+		{s: "P", wantErr: true},
+		{s: "PT1D", wantErr: true},
+		{s: "P1H", wantErr: true},
+		{s: "15M", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.s)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseDuration(%q) error = %v, wantErr %v", tt.s, err, tt.wantErr)
+			continue
+		}
+		if got != tt.d {
+			t.Errorf("ParseDuration(%q) = %s, want %s", tt.s, got, tt.d)
+		}
+		if tt.format {
+			if got := FormatDuration(tt.d); got != tt.s {
+				t.Errorf("FormatDuration(%s) = %q, want %q", tt.d, got, tt.s)
+			}
+		}
+	}
+}
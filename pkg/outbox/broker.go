@@ -0,0 +1,67 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+// Broker is in-memory Sink which passes events to subscribers in the same process
+type Broker struct {
+	mu   sync.Mutex
+	subs map[*subscription]struct{}
+}
+
+// subscription is channel of subscriber, done is closed when subscription is cancelled
+type subscription struct {
+	ch   chan *v1.TodoEvent
+	done chan struct{}
+}
+
+// NewBroker returns Broker without subscribers
+func NewBroker() *Broker {
+	return &Broker{subs: map[*subscription]struct{}{}}
+}
+
+// Subscribe returns channel of events published after subscription with buffer of size
+// and function to cancel the subscription
+func (b *Broker) Subscribe(size int) (<-chan *v1.TodoEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &subscription{ch: make(chan *v1.TodoEvent, size), done: make(chan struct{})}
+	b.subs[sub] = struct{}{}
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, sub)
+			b.mu.Unlock()
+			close(sub.done)
+		})
+	}
+}
+
+// Publish passes event to every subscriber, it waits while subscriber's buffer is full,
+// so slow subscriber slows down relay instead of losing events
+func (b *Broker) Publish(ctx context.Context, ev *v1.TodoEvent) error {
+	b.mu.Lock()
+	subs := make([]*subscription, 0, len(b.subs))
+	for sub := range b.subs {
+		subs = append(subs, sub)
+	}
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		select {
+		case sub.ch <- ev:
+		case <-sub.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+func TestBrokerPublish(t *testing.T) {
+	b := NewBroker()
+	ch, cancel := b.Subscribe(2)
+	defer cancel()
+
+	for id := int64(1); id <= 2; id++ {
+		if err := b.Publish(context.Background(), &v1.TodoEvent{Todo: &v1.Todo{Id: id}}); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+	for want := int64(1); want <= 2; want++ {
+		if ev := <-ch; ev.Todo.Id != want {
+			t.Errorf("received Todo with ID=%d, want %d", ev.Todo.Id, want)
+		}
+	}
+}
+
+func TestBrokerFullSubscriber(t *testing.T) {
+	b := NewBroker()
+	_, cancel := b.Subscribe(1)
+
+	ev := &v1.TodoEvent{Todo: &v1.Todo{Id: 1}}
+	if err := b.Publish(context.Background(), ev); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	// full subscriber holds relay back until context is done instead of losing the event
+	ctx, stop := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer stop()
+	if err := b.Publish(ctx, ev); err != context.DeadlineExceeded {
+		t.Errorf("Publish() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// cancelled subscriber doesn't block publishing
+	cancel()
+	cancel()
+	if err := b.Publish(context.Background(), ev); err != nil {
+		t.Errorf("Publish() error = %v after cancel", err)
+	}
+}
@@ -0,0 +1,72 @@
+// Package outbox provides sinks change events of todo tasks are relayed to
+// from the outbox table written in the same transaction as the change.
+//
+// Relay is at least once: event can be published again after failure or restart,
+// resume_token of event is unique and can be used to drop duplicates.
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Sink publishes change events
+type Sink interface {
+	// Publish publishes event, error means the event is published again later
+	Publish(ctx context.Context, ev *v1.TodoEvent) error
+}
+
+// LogSink writes events to the server log
+type LogSink struct{}
+
+// Publish writes event to log
+func (LogSink) Publish(ctx context.Context, ev *v1.TodoEvent) error {
+	log.Printf("todo event %s: %s Todo with ID='%d'", ev.ResumeToken, ev.Type, ev.Todo.GetId())
+	return nil
+}
+
+// WebhookSink posts events as JSON to URL
+type WebhookSink struct {
+	// URL events are posted to
+	URL string
+	// Client is HTTP client, http.DefaultClient if nil
+	Client *http.Client
+}
+
+// Publish posts event, any response status except 2xx is error
+func (s *WebhookSink) Publish(ctx context.Context, ev *v1.TodoEvent) error {
+	body, err := protojson.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("sink responded with status %s", resp.Status)
+	}
+
+	return nil
+}
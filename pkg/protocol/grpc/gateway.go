@@ -0,0 +1,76 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/devararishivian/go-grpc/pkg/protocol/grpc/middleware"
+)
+
+// GatewayListener is in-process listener of connections of the local HTTP gateway,
+// other processes can't connect to it, so the rate limiter trusts client address set by the gateway
+// on its connections only
+type GatewayListener struct {
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+// NewGatewayListener creates listener of the local HTTP gateway
+func NewGatewayListener() *GatewayListener {
+	return &GatewayListener{
+		conns:  make(chan net.Conn),
+		closed: make(chan struct{}),
+	}
+}
+
+// Accept waits for connection of the gateway
+func (l *GatewayListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close closes listener, connections already accepted stay open
+func (l *GatewayListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return nil
+}
+
+// Addr returns address of the gateway
+func (l *GatewayListener) Addr() net.Addr {
+	return gatewayAddr{}
+}
+
+// DialContext connects the gateway to listener, it is dialer of gRPC client of the gateway
+func (l *GatewayListener) DialContext(ctx context.Context, _ string) (net.Conn, error) {
+	server, client := net.Pipe()
+	select {
+	case l.conns <- gatewayConn{server}:
+		return client, nil
+	case <-l.closed:
+		client.Close()
+		return nil, net.ErrClosed
+	case <-ctx.Done():
+		client.Close()
+		return nil, ctx.Err()
+	}
+}
+
+// gatewayConn is server side of connection of the gateway
+type gatewayConn struct {
+	net.Conn
+}
+
+func (gatewayConn) LocalAddr() net.Addr  { return gatewayAddr{} }
+func (gatewayConn) RemoteAddr() net.Addr { return gatewayAddr{} }
+
+// gatewayAddr is address of the gateway
+type gatewayAddr struct{}
+
+func (gatewayAddr) Network() string { return middleware.GatewayNetwork }
+func (gatewayAddr) String() string  { return middleware.GatewayNetwork }
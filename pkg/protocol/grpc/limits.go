@@ -0,0 +1,84 @@
+package grpc
+
+import (
+	"net"
+	"time"
+
+	"golang.org/x/net/netutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+// Limits is connection and message limits of gRPC server, zero value of a limit keeps gRPC default
+type Limits struct {
+	// KeepaliveTime is how long connection is idle before server pings client, 0 is 2 hours
+	KeepaliveTime time.Duration
+	// KeepaliveTimeout is how long server waits for ping ack before it closes connection, 0 is 20 seconds
+	KeepaliveTimeout time.Duration
+	// KeepaliveMinTime is minimum interval of client pings, connection of client pinging more often is closed,
+	// 0 is 5 minutes
+	KeepaliveMinTime time.Duration
+	// KeepalivePermitWithoutStream allows client pings when there are no active calls
+	KeepalivePermitWithoutStream bool
+
+	// MaxConnectionIdle is how long connection without calls is kept open, 0 is infinity
+	MaxConnectionIdle time.Duration
+	// MaxConnectionAge is how long connection is kept open, so clients reconnect and are rebalanced, 0 is infinity
+	MaxConnectionAge time.Duration
+	// MaxConnectionAgeGrace is how long calls can finish after MaxConnectionAge before connection is closed,
+	// 0 is infinity
+	MaxConnectionAgeGrace time.Duration
+
+	// MaxConcurrentStreams is limit of concurrent calls of one connection, 0 is unlimited
+	MaxConcurrentStreams uint32
+	// MaxRecvMsgSize is limit of request message size in bytes, 0 is 4 MB
+	MaxRecvMsgSize int
+	// MaxSendMsgSize is limit of response message size in bytes, 0 is unlimited
+	MaxSendMsgSize int
+
+	// MaxConnections is limit of open connections, further clients wait until a connection is closed,
+	// 0 is unlimited
+	MaxConnections int
+}
+
+// ServerOptions returns grpc.Server options applying limits
+func (l Limits) ServerOptions() []grpc.ServerOption {
+	opts := []grpc.ServerOption{
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionIdle:     l.MaxConnectionIdle,
+			MaxConnectionAge:      l.MaxConnectionAge,
+			MaxConnectionAgeGrace: l.MaxConnectionAgeGrace,
+			Time:                  l.KeepaliveTime,
+			Timeout:               l.KeepaliveTimeout,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             l.KeepaliveMinTime,
+			PermitWithoutStream: l.KeepalivePermitWithoutStream,
+		}),
+	}
+	if l.MaxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(l.MaxConcurrentStreams))
+	}
+	if l.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(l.MaxRecvMsgSize))
+	}
+	if l.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(l.MaxSendMsgSize))
+	}
+	return opts
+}
+
+// GatewayServerOptions returns grpc.Server options applying limits to the local HTTP gateway,
+// its one connection carries calls of all HTTP clients, so MaxConcurrentStreams isn't applied
+func (l Limits) GatewayServerOptions() []grpc.ServerOption {
+	l.MaxConcurrentStreams = 0
+	return l.ServerOptions()
+}
+
+// Listener returns listener accepting up to MaxConnections connections
+func (l Limits) Listener(listen net.Listener) net.Listener {
+	if l.MaxConnections <= 0 {
+		return listen
+	}
+	return netutil.LimitListener(listen, l.MaxConnections)
+}
@@ -0,0 +1,136 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+
+	"github.com/devararishivian/go-grpc/pkg/protocol/grpc/middleware"
+)
+
+func TestServerOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits Limits
+		want   int
+	}{
+		{name: "gRPC defaults", want: 2},
+		{name: "streams", limits: Limits{MaxConcurrentStreams: 10}, want: 3},
+		{name: "message sizes", limits: Limits{MaxRecvMsgSize: 1 << 20, MaxSendMsgSize: 1 << 20}, want: 4},
+		{name: "connections are limited by listener", limits: Limits{MaxConnections: 10}, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(tt.limits.ServerOptions()); got != tt.want {
+				t.Errorf("ServerOptions() returned %d options, want %d", got, tt.want)
+			}
+		})
+	}
+
+	limits := Limits{MaxConcurrentStreams: 10, MaxRecvMsgSize: 1 << 20}
+	if got := len(limits.GatewayServerOptions()); got != 3 {
+		t.Errorf("GatewayServerOptions() returned %d options, want 3 without limit of streams", got)
+	}
+	if limits.MaxConcurrentStreams != 10 {
+		t.Errorf("GatewayServerOptions() changed limits to %v", limits)
+	}
+}
+
+func TestListener(t *testing.T) {
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listen.Close()
+
+	if got := (Limits{}).Listener(listen); got != listen {
+		t.Errorf("Listener() = %v, want listener as is without limit", got)
+	}
+
+	limited := Limits{MaxConnections: 1}.Listener(listen)
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			c, err := limited.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- c
+		}
+	}()
+
+	for i := 0; i < 2; i++ {
+		c, err := net.Dial("tcp", listen.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+	}
+
+	first := <-accepted
+	select {
+	case <-accepted:
+		t.Fatal("second connection accepted while the first one is open")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	first.Close()
+	select {
+	case c := <-accepted:
+		c.Close()
+	case <-time.After(time.Second):
+		t.Fatal("second connection not accepted after the first one is closed")
+	}
+}
+
+func TestGatewayListener(t *testing.T) {
+	gateway := NewGatewayListener()
+	defer gateway.Close()
+
+	// calls of the gateway aren't limited by MaxConcurrentStreams and their peer is the gateway
+	peers := make(chan net.Addr, 2)
+	opts := append(Limits{MaxConcurrentStreams: 1}.GatewayServerOptions(),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if p, ok := peer.FromContext(ss.Context()); ok {
+				peers <- p.Addr
+			}
+			return handler(srv, ss)
+		}))
+	server := grpc.NewServer(opts...)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(gateway)
+	defer server.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, "gateway", grpc.WithInsecure(), grpc.WithContextDialer(gateway.DialContext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	client := healthpb.NewHealthClient(conn)
+	for i := 0; i < 2; i++ {
+		watch, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := watch.Recv(); err != nil {
+			t.Fatalf("concurrent call %d failed: %v", i+1, err)
+		}
+		if addr := <-peers; addr.Network() != middleware.GatewayNetwork {
+			t.Errorf("peer network = %s, want %s", addr.Network(), middleware.GatewayNetwork)
+		}
+	}
+
+	gateway.Close()
+	if _, err := gateway.DialContext(ctx, ""); err == nil {
+		t.Error("DialContext() error = nil after Close")
+	}
+}
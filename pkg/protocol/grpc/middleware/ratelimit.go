@@ -0,0 +1,227 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	// defaultQuotaKey is the quota applied to methods without their own entry
+	defaultQuotaKey = "*"
+
+	// idleBucketTTL is how long a full bucket is kept before it is evicted
+	idleBucketTTL = 10 * time.Minute
+
+	// ClientIPHeader is metadata key the local HTTP gateway sets to address of HTTP client it proxies,
+	// the gateway drops this key from headers sent by clients so they can't choose their bucket
+	ClientIPHeader = "x-gateway-client-ip"
+
+	// GatewayNetwork is network of peer address of the local HTTP gateway connected in-process,
+	// ClientIPHeader is trusted on its calls only
+	GatewayNetwork = "gateway"
+)
+
+// Quota is token bucket parameters: Rate tokens per second refilled up to Burst
+type Quota struct {
+	Rate  float64
+	Burst int
+}
+
+// ParseQuotas parses per-method quotas in format "Method=rate:burst,..."
+// Method is either short ("Create") or full ("/v1.TodoService/Create") gRPC method name,
+// "*" sets the default quota for all other methods
+func ParseQuotas(s string) (map[string]Quota, error) {
+	quotas := map[string]Quota{}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid rate limit '%s': expected Method=rate:burst", item)
+		}
+		rb := strings.SplitN(kv[1], ":", 2)
+		if len(rb) != 2 {
+			return nil, fmt.Errorf("invalid rate limit '%s': expected Method=rate:burst", item)
+		}
+
+		rate, err := strconv.ParseFloat(rb[0], 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("invalid rate in rate limit '%s'", item)
+		}
+		burst, err := strconv.Atoi(rb[1])
+		if err != nil || burst <= 0 {
+			return nil, fmt.Errorf("invalid burst in rate limit '%s'", item)
+		}
+
+		quotas[strings.TrimSpace(kv[0])] = Quota{Rate: rate, Burst: burst}
+	}
+
+	return quotas, nil
+}
+
+// bucket is token bucket state of one client for one method
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter limits calls per client and per method using token buckets
+type RateLimiter struct {
+	quotas map[string]Quota
+	now    func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewRateLimiter creates rate limiter with per-method quotas
+func NewRateLimiter(quotas map[string]Quota) *RateLimiter {
+	return &RateLimiter{
+		quotas:  quotas,
+		now:     time.Now,
+		buckets: map[string]*bucket{},
+	}
+}
+
+// quota returns quota for the full gRPC method name
+func (l *RateLimiter) quota(method string) (Quota, bool) {
+	if q, ok := l.quotas[method]; ok {
+		return q, true
+	}
+	if i := strings.LastIndex(method, "/"); i >= 0 {
+		if q, ok := l.quotas[method[i+1:]]; ok {
+			return q, true
+		}
+	}
+	q, ok := l.quotas[defaultQuotaKey]
+	return q, ok
+}
+
+// Allow takes token from the client bucket for the method.
+// It returns false and the delay until the next token is available when the bucket is empty
+func (l *RateLimiter) Allow(client, method string) (bool, time.Duration) {
+	q, ok := l.quota(method)
+	if !ok {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	key := client + " " + method
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(q.Burst), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(q.Burst), b.tokens+now.Sub(b.last).Seconds()*q.Rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / q.Rate * float64(time.Second))
+		return false, wait
+	}
+
+	b.tokens--
+	return true, 0
+}
+
+// sweep evicts buckets of clients which have been idle long enough to be refilled
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleBucketTTL {
+		return
+	}
+	l.lastSweep = now
+
+	for k, b := range l.buckets {
+		if now.Sub(b.last) >= idleBucketTTL {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// ClientKey returns key identifying the caller: the verified TLS client certificate subject
+// if present, otherwise the peer IP address. Calls proxied by the local HTTP gateway over its in-process
+// connection are identified by ClientIPHeader address of the original HTTP client set by the gateway,
+// the header is ignored on other connections, local ones too
+func ClientKey(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "unknown"
+	}
+
+	if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+		if chains := tlsInfo.State.VerifiedChains; len(chains) > 0 && len(chains[0]) > 0 {
+			return "cn:" + chains[0][0].Subject.CommonName
+		}
+	}
+
+	if p.Addr.Network() == GatewayNetwork {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if addr := md.Get(ClientIPHeader); len(addr) > 0 {
+				// the gateway appends its value after metadata of client headers
+				return "ip:" + addr[len(addr)-1]
+			}
+		}
+	}
+
+	host := p.Addr.String()
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return "ip:" + host
+}
+
+// rateLimitError returns ResourceExhausted error with RetryInfo details
+func rateLimitError(method string, wait time.Duration) error {
+	st := status.New(codes.ResourceExhausted, fmt.Sprintf("rate limit exceeded for '%s', retry in %s", method, wait))
+	ds, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)})
+	if err != nil {
+		return st.Err()
+	}
+	return ds.Err()
+}
+
+// AddRateLimit returns grpc.Server config option that turns on per-client rate limiting
+func AddRateLimit(l *RateLimiter, opts []grpc.ServerOption) []grpc.ServerOption {
+	// Add unary interceptor
+	opts = append(opts, grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if ok, wait := l.Allow(ClientKey(ctx), info.FullMethod); !ok {
+			return nil, rateLimitError(info.FullMethod, wait)
+		}
+		return handler(ctx, req)
+	}))
+
+	// Add stream interceptor
+	opts = append(opts, grpc.ChainStreamInterceptor(func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if ok, wait := l.Allow(ClientKey(stream.Context()), info.FullMethod); !ok {
+			return rateLimitError(info.FullMethod, wait)
+		}
+		return handler(srv, stream)
+	}))
+
+	return opts
+}
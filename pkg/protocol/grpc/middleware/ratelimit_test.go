@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseQuotas(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    map[string]Quota
+		wantErr bool
+	}{
+		{name: "empty", s: "", want: map[string]Quota{}},
+		{
+			name: "short and full names",
+			s:    "Create=1:5, /v1.TodoService/Read=2.5:10,*=10:20",
+			want: map[string]Quota{
+				"Create":               {Rate: 1, Burst: 5},
+				"/v1.TodoService/Read": {Rate: 2.5, Burst: 10},
+				"*":                    {Rate: 10, Burst: 20},
+			},
+		},
+		{name: "no rate", s: "Create", wantErr: true},
+		{name: "no burst", s: "Create=1", wantErr: true},
+		{name: "zero rate", s: "Create=0:1", wantErr: true},
+		{name: "negative burst", s: "Create=1:-1", wantErr: true},
+		{name: "invalid number", s: "Create=x:1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQuotas(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseQuotas() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseQuotas() = %v, want %v", got, tt.want)
+			}
+			for k, q := range tt.want {
+				if got[k] != q {
+					t.Errorf("ParseQuotas()[%s] = %v, want %v", k, got[k], q)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+	now := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+	l := NewRateLimiter(map[string]Quota{
+		"Create": {Rate: 1, Burst: 2},
+		"*":      {Rate: 10, Burst: 1},
+	})
+	l.now = func() time.Time { return now }
+
+	tests := []struct {
+		name     string
+		advance  time.Duration
+		client   string
+		method   string
+		want     bool
+		wantWait time.Duration
+	}{
+		{name: "first of burst", client: "a", method: "/v1.TodoService/Create", want: true},
+		{name: "second of burst", client: "a", method: "/v1.TodoService/Create", want: true},
+		{name: "burst used", client: "a", method: "/v1.TodoService/Create", want: false, wantWait: time.Second},
+		{name: "other client has own bucket", client: "b", method: "/v1.TodoService/Create", want: true},
+		{name: "default quota", client: "a", method: "/v1.TodoService/Read", want: true},
+		{name: "default quota used", client: "a", method: "/v1.TodoService/Read", want: false, wantWait: 100 * time.Millisecond},
+		{name: "refilled", advance: time.Second, client: "a", method: "/v1.TodoService/Create", want: true},
+		{name: "refilled only one", client: "a", method: "/v1.TodoService/Create", want: false, wantWait: time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.advance)
+			got, wait := l.Allow(tt.client, tt.method)
+			if got != tt.want || wait != tt.wantWait {
+				t.Errorf("Allow() = %v, %v, want %v, %v", got, wait, tt.want, tt.wantWait)
+			}
+		})
+	}
+}
+
+func TestRateLimiterWithoutQuota(t *testing.T) {
+	l := NewRateLimiter(map[string]Quota{"Create": {Rate: 1, Burst: 1}})
+	for i := 0; i < 10; i++ {
+		if ok, _ := l.Allow("a", "/v1.TodoService/Read"); !ok {
+			t.Fatalf("Allow() = false for method without quota")
+		}
+	}
+}
+
+// gatewayAddr is peer address of the in-process gateway connection
+type gatewayAddr struct{}
+
+func (gatewayAddr) Network() string { return GatewayNetwork }
+func (gatewayAddr) String() string  { return GatewayNetwork }
+
+func TestClientKey(t *testing.T) {
+	tcp := func(s string) net.Addr {
+		addr, err := net.ResolveTCPAddr("tcp", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return addr
+	}
+
+	tests := []struct {
+		name string
+		addr net.Addr
+		md   metadata.MD
+		want string
+	}{
+		{name: "remote peer", addr: tcp("10.0.0.1:5000"), want: "ip:10.0.0.1"},
+		{
+			name: "remote peer can't set gateway address",
+			addr: tcp("10.0.0.1:5000"),
+			md:   metadata.Pairs(ClientIPHeader, "10.9.9.9"),
+			want: "ip:10.0.0.1",
+		},
+		{name: "local peer", addr: tcp("127.0.0.1:5000"), want: "ip:127.0.0.1"},
+		{
+			name: "local peer can't set gateway address",
+			addr: tcp("127.0.0.1:5000"),
+			md:   metadata.Pairs(ClientIPHeader, "10.9.9.9"),
+			want: "ip:127.0.0.1",
+		},
+		{
+			name: "gateway",
+			addr: gatewayAddr{},
+			md:   metadata.Pairs(ClientIPHeader, "10.0.0.2"),
+			want: "ip:10.0.0.2",
+		},
+		{
+			name: "gateway address is the last one",
+			addr: gatewayAddr{},
+			md:   metadata.Pairs(ClientIPHeader, "10.9.9.9", ClientIPHeader, "10.0.0.2"),
+			want: "ip:10.0.0.2",
+		},
+		{
+			name: "forwarded for header is ignored",
+			addr: gatewayAddr{},
+			md:   metadata.Pairs("x-forwarded-for", "10.9.9.9", ClientIPHeader, "10.0.0.2"),
+			want: "ip:10.0.0.2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: tt.addr})
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+			if got := ClientKey(ctx); got != tt.want {
+				t.Errorf("ClientKey() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitError(t *testing.T) {
+	st := status.Convert(rateLimitError("/v1.TodoService/Create", 2*time.Second))
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("code = %s, want %s", st.Code(), codes.ResourceExhausted)
+	}
+	if len(st.Details()) != 1 {
+		t.Fatalf("details = %v, want RetryInfo", st.Details())
+	}
+}
@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// APIVersionHeader is response metadata key with API version of the called method
+	APIVersionHeader = "api-version"
+
+	// SupportedVersionsHeader is response metadata key with comma separated API versions served by server,
+	// clients use it to find out if they can move to a newer version
+	SupportedVersionsHeader = "api-supported-versions"
+
+	// DeprecationHeader is response metadata key set to "true" for calls of deprecated API version
+	DeprecationHeader = "deprecation"
+
+	// SunsetHeader is response metadata key with HTTP-date the deprecated API version is removed at
+	SunsetHeader = "sunset"
+
+	// LinkHeader is response metadata key with link to the successor of the deprecated API version
+	LinkHeader = "link"
+)
+
+// Deprecation is notice of deprecated API version
+type Deprecation struct {
+	// Sunset is time the version is removed at, zero if it's not scheduled
+	Sunset time.Time
+	// Successor is URL of documentation of the version replacing deprecated one, empty if there is none
+	Successor string
+}
+
+// VersionPolicy is API versions served by server. API version is proto package of the called
+// method, e.g. "v1" for "/v1.TodoService/Read", so every version is served concurrently by
+// its own services and clients choose the version by the package they call.
+type VersionPolicy struct {
+	// Supported is API versions served by server, the newest last
+	Supported []string
+	// Deprecated is deprecation notices of API versions still served
+	Deprecated map[string]Deprecation
+}
+
+// apiVersion returns API version of gRPC method, false if method isn't part of versioned API,
+// e.g. server reflection
+func (p *VersionPolicy) apiVersion(fullMethod string) (string, bool) {
+	pkg := strings.TrimPrefix(fullMethod, "/")
+	if i := strings.IndexAny(pkg, "./"); i >= 0 {
+		pkg = pkg[:i]
+	}
+	for _, v := range p.Supported {
+		if v == pkg {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// headers returns response header and trailer metadata of method call
+func (p *VersionPolicy) headers(fullMethod string) (metadata.MD, metadata.MD, bool) {
+	version, ok := p.apiVersion(fullMethod)
+	if !ok {
+		return nil, nil, false
+	}
+
+	header := metadata.Pairs(
+		APIVersionHeader, version,
+		SupportedVersionsHeader, strings.Join(p.Supported, ", "),
+	)
+
+	// deprecation notice is sent in trailer too, clients reading only status of call get it
+	var trailer metadata.MD
+	if d, ok := p.Deprecated[version]; ok {
+		notice := metadata.Pairs(DeprecationHeader, "true")
+		if !d.Sunset.IsZero() {
+			notice.Set(SunsetHeader, d.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if len(d.Successor) > 0 {
+			notice.Set(LinkHeader, fmt.Sprintf(`<%s>; rel="successor-version"`, d.Successor))
+		}
+		header = metadata.Join(header, notice)
+		trailer = notice
+	}
+
+	return header, trailer, true
+}
+
+// AddVersionHeaders returns grpc.Server config option that sends API version of called method,
+// supported versions and deprecation notice of deprecated versions in response metadata
+func AddVersionHeaders(p *VersionPolicy, opts []grpc.ServerOption) []grpc.ServerOption {
+	// Add unary interceptor
+	opts = append(opts, grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if header, trailer, ok := p.headers(info.FullMethod); ok {
+			_ = grpc.SetHeader(ctx, header)
+			if trailer != nil {
+				_ = grpc.SetTrailer(ctx, trailer)
+			}
+		}
+		return handler(ctx, req)
+	}))
+
+	// Add stream interceptor
+	opts = append(opts, grpc.ChainStreamInterceptor(func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if header, trailer, ok := p.headers(info.FullMethod); ok {
+			_ = stream.SetHeader(header)
+			if trailer != nil {
+				stream.SetTrailer(trailer)
+			}
+		}
+		return handler(srv, stream)
+	}))
+
+	return opts
+}
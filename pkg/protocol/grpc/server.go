@@ -7,19 +7,34 @@ import (
 	"os"
 
 	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+	v2 "github.com/devararishivian/go-grpc/pkg/api/v2"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
 )
 
-// RunServer runs gRPC service to publish Todo service
-func RunServer(ctx context.Context, v1API v1.TodoServiceServer, port string) error {
+// RunServer runs gRPC service to publish Todo service v1 and v2 and Webhook service,
+// reflection lets tools like grpcurl discover services without proto files,
+// limits bound connections, calls and messages of clients.
+// The local HTTP gateway is served on its own listener by server without limit of concurrent calls,
+// as HTTP requests of all clients share its one connection
+func RunServer(ctx context.Context, v1API v1.TodoServiceServer, webhookAPI v1.WebhookServiceServer, v2API v2.TodoServiceServer, port string, gateway net.Listener, reflect bool, limits Limits, opts ...grpc.ServerOption) error {
 	listen, err := net.Listen("tcp", ":"+port)
 	if err != nil {
 		return err
 	}
+	listen = limits.Listener(listen)
 
 	// Register service
-	server := grpc.NewServer()
-	v1.RegisterTodoServiceServer(server, v1API)
+	server := grpc.NewServer(append(limits.ServerOptions(), opts...)...)
+	gatewayServer := grpc.NewServer(append(limits.GatewayServerOptions(), opts...)...)
+	for _, s := range []*grpc.Server{server, gatewayServer} {
+		v1.RegisterTodoServiceServer(s, v1API)
+		v1.RegisterWebhookServiceServer(s, webhookAPI)
+		v2.RegisterTodoServiceServer(s, v2API)
+	}
+	if reflect {
+		reflection.Register(server)
+	}
 
 	// Graceful shutdown
 	c := make(chan os.Signal, 1)
@@ -27,12 +42,18 @@ func RunServer(ctx context.Context, v1API v1.TodoServiceServer, port string) err
 		for range c {
 			log.Println("shutting down gRPC server...")
 			server.GracefulStop()
+			gatewayServer.GracefulStop()
 
 			<-ctx.Done()
 		}
 	}()
 
 	// Start gRPC server
+	go func() {
+		if err := gatewayServer.Serve(gateway); err != nil {
+			log.Printf("gRPC server of HTTP gateway stopped: %v", err)
+		}
+	}()
 	log.Println("starting gRPC server...")
 	return server.Serve(listen)
 }
@@ -3,31 +3,105 @@ package rest
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"time"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 
 	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+	v2 "github.com/devararishivian/go-grpc/pkg/api/v2"
+	"github.com/devararishivian/go-grpc/pkg/client"
+	"github.com/devararishivian/go-grpc/pkg/protocol/grpc/middleware"
 )
 
-// RunServer runs REST service to publish Todo service
-func RunServer(ctx context.Context, grpcPort, httpPort string) error {
+// headerMatcher passes Idempotency-Key header to gRPC metadata in addition to default headers,
+// client address metadata is set by the gateway only
+func headerMatcher(key string) (string, bool) {
+	switch http.CanonicalHeaderKey(key) {
+	case "Idempotency-Key":
+		return v1.IdempotencyKeyHeader, true
+	case http.CanonicalHeaderKey(runtime.MetadataHeaderPrefix + middleware.ClientIPHeader):
+		return "", false
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// clientIP passes address of HTTP client to gRPC metadata, the rate limiter identifies clients by it
+func clientIP(_ context.Context, r *http.Request) metadata.MD {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return metadata.Pairs(middleware.ClientIPHeader, host)
+}
+
+// outgoingHeaderMatcher sends API version and deprecation metadata as plain HTTP headers,
+// other metadata is prefixed by default
+func outgoingHeaderMatcher(key string) (string, bool) {
+	switch strings.ToLower(key) {
+	case middleware.APIVersionHeader, middleware.SupportedVersionsHeader,
+		middleware.DeprecationHeader, middleware.SunsetHeader, middleware.LinkHeader:
+		return http.CanonicalHeaderKey(key), true
+	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
+
+// gatewayEndpoint is target of gateway calls, dial of RunServer connects to it
+const gatewayEndpoint = "gateway"
+
+// Route is HTTP handler served alongside the gateway, pattern is the same as of http.ServeMux
+type Route struct {
+	Pattern string
+	Handler http.Handler
+}
+
+// RunServer runs REST service to publish Todo service v1 and v2 and routes,
+// dial connects the gateway to gRPC server. Limits of message size of gateway calls
+// are the opposite limits of gRPC server: gatewayCallRecv is what server sends,
+// gatewayCallSend is what server receives
+func RunServer(ctx context.Context, dial func(context.Context, string) (net.Conn, error), httpPort string, gatewayCallRecv, gatewayCallSend int, routes ...Route) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	mux := runtime.NewServeMux()
-	opts := []grpc.DialOption{grpc.WithInsecure()}
-	if err := v1.RegisterTodoServiceHandlerFromEndpoint(ctx, mux, "localhost:"+grpcPort, opts); err != nil {
+	mux := runtime.NewServeMux(
+		runtime.WithErrorHandler(errorHandler),
+		runtime.WithIncomingHeaderMatcher(headerMatcher),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeaderMatcher),
+		runtime.WithMetadata(clientIP),
+	)
+	// gateway retries reads and applies timeouts like Go clients
+	opts := []grpc.DialOption{
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(dial),
+		grpc.WithDefaultServiceConfig(client.DefaultServiceConfig),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(gatewayCallRecv), grpc.MaxCallSendMsgSize(gatewayCallSend)),
+	}
+	if err := v1.RegisterTodoServiceHandlerFromEndpoint(ctx, mux, gatewayEndpoint, opts); err != nil {
+		log.Fatalf("failed to start HTTP gateway: %v", err)
+	}
+	if err := v1.RegisterWebhookServiceHandlerFromEndpoint(ctx, mux, gatewayEndpoint, opts); err != nil {
 		log.Fatalf("failed to start HTTP gateway: %v", err)
 	}
+	if err := v2.RegisterTodoServiceHandlerFromEndpoint(ctx, mux, gatewayEndpoint, opts); err != nil {
+		log.Fatalf("failed to start HTTP gateway: %v", err)
+	}
+
+	// gateway serves all paths routes don't match
+	handler := http.NewServeMux()
+	for _, r := range routes {
+		handler.Handle(r.Pattern, r.Handler)
+	}
+	handler.Handle("/", mux)
 
 	srv := &http.Server{
 		Addr:    ":" + httpPort,
-		Handler: mux,
+		Handler: handler,
 	}
 
 	// graceful shutdown
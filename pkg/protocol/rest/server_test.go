@@ -0,0 +1,42 @@
+package rest
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/devararishivian/go-grpc/pkg/protocol/grpc/middleware"
+)
+
+func TestHeaderMatcher(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+		ok     bool
+	}{
+		{header: "Idempotency-Key", want: "idempotency-key", ok: true},
+		{header: "Grpc-Metadata-X-Gateway-Client-Ip", ok: false},
+		{header: "grpc-metadata-x-gateway-client-ip", ok: false},
+		{header: "Grpc-Metadata-Foo", want: "Foo", ok: true},
+		{header: "X-Gateway-Client-Ip", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got, ok := headerMatcher(tt.header)
+			if ok != tt.ok || (ok && got != tt.want) {
+				t.Errorf("headerMatcher(%s) = %s, %v, want %s, %v", tt.header, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	r := httptest.NewRequest("GET", "/v1/todo/1", nil)
+	r.RemoteAddr = "10.0.0.2:54321"
+	r.Header.Set("X-Forwarded-For", "10.9.9.9")
+
+	md := clientIP(context.Background(), r)
+	if got := md.Get(middleware.ClientIPHeader); len(got) != 1 || got[0] != "10.0.0.2" {
+		t.Errorf("clientIP() = %v, want [10.0.0.2]", got)
+	}
+}
@@ -0,0 +1,109 @@
+package rest
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// SwaggerPathPrefix is path of Swagger UI, OpenAPI document of each API version is served at
+	// SwaggerPathPrefix+"{version}/todo-service.swagger.json"
+	SwaggerPathPrefix = "/swagger/"
+
+	// DefaultSwaggerUIAssets is URL of Swagger UI scripts and styles, pinned to tested version
+	DefaultSwaggerUIAssets = "https://unpkg.com/swagger-ui-dist@5.17.14"
+
+	// swaggerSpecName is file name of OpenAPI document under SwaggerPathPrefix
+	swaggerSpecName = "todo-service.swagger.json"
+)
+
+//go:embed swagger-ui/index.html
+var swaggerUI embed.FS
+
+// SwaggerSpec is OpenAPI document of API version
+type SwaggerSpec struct {
+	// Version is API version, e.g. "v1"
+	Version string
+	// Spec is OpenAPI document
+	Spec []byte
+}
+
+// SwaggerSpecPath returns path OpenAPI document of API version is served at
+func SwaggerSpecPath(version string) string {
+	return SwaggerPathPrefix + version + "/" + swaggerSpecName
+}
+
+// swaggerHandler serves OpenAPI documents and Swagger UI page
+type swaggerHandler struct {
+	specs   map[string][]byte
+	page    []byte
+	started time.Time
+}
+
+// NewSwaggerHandler returns handler of OpenAPI documents and Swagger UI page loading its
+// scripts and styles from assets URL, it's served at SwaggerPathPrefix.
+// The page lets to choose API version, the first spec is shown by default and
+// is also served at SwaggerPathPrefix+"todo-service.swagger.json" for links made before versions
+func NewSwaggerHandler(assets string, specs ...SwaggerSpec) (http.Handler, error) {
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no OpenAPI documents to serve")
+	}
+
+	tmpl, err := template.ParseFS(swaggerUI, "swagger-ui/index.html")
+	if err != nil {
+		return nil, err
+	}
+
+	type spec struct{ Name, URL string }
+	h := &swaggerHandler{specs: map[string][]byte{swaggerSpecName: specs[0].Spec}, started: time.Now()}
+	var urls []spec
+	for _, s := range specs {
+		path := SwaggerSpecPath(s.Version)
+		h.specs[strings.TrimPrefix(path, SwaggerPathPrefix)] = s.Spec
+		urls = append(urls, spec{Name: s.Version, URL: path})
+	}
+
+	var page bytes.Buffer
+	if err := tmpl.Execute(&page, struct {
+		Assets string
+		Specs  []spec
+	}{
+		Assets: strings.TrimSuffix(assets, "/"),
+		Specs:  urls,
+	}); err != nil {
+		return nil, err
+	}
+	h.page = page.Bytes()
+
+	return h, nil
+}
+
+// ServeHTTP serves the page at SwaggerPathPrefix and the documents by their names
+func (h *swaggerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, SwaggerPathPrefix)
+	if name == "" || name == "index.html" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		http.ServeContent(w, r, "index.html", h.started, bytes.NewReader(h.page))
+		return
+	}
+
+	spec, ok := h.specs[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	http.ServeContent(w, r, swaggerSpecName, h.started, bytes.NewReader(spec))
+}
@@ -0,0 +1,67 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSwaggerHandler(t *testing.T) {
+	h, err := NewSwaggerHandler("https://assets.example.com/ui/",
+		SwaggerSpec{Version: "v1", Spec: []byte(`{"info":{"version":"1.0"}}`)},
+		SwaggerSpec{Version: "v2", Spec: []byte(`{"info":{"version":"2.0"}}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		wantCode int
+		wantType string
+		want     []string
+	}{
+		{
+			name:     "page",
+			path:     SwaggerPathPrefix,
+			wantCode: http.StatusOK,
+			wantType: "text/html; charset=utf-8",
+			want: []string{`href="https://assets.example.com/ui/swagger-ui.css"`,
+				// html/template escapes slashes of JS strings
+				`{url: "\/swagger\/v1\/todo-service.swagger.json", name: "v1"}`,
+				`{url: "\/swagger\/v2\/todo-service.swagger.json", name: "v2"}`},
+		},
+		{name: "document of version", path: SwaggerSpecPath("v2"), wantCode: http.StatusOK, wantType: "application/json", want: []string{`"2.0"`}},
+		{name: "link made before versions", path: SwaggerPathPrefix + "todo-service.swagger.json", wantCode: http.StatusOK, want: []string{`"1.0"`}},
+		{name: "unknown document", path: SwaggerSpecPath("v3"), wantCode: http.StatusNotFound},
+		{name: "read-only", method: http.MethodPost, path: SwaggerSpecPath("v1"), wantCode: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if len(method) == 0 {
+				method = http.MethodGet
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(method, tt.path, nil))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("%s %s = %d, want %d", method, tt.path, w.Code, tt.wantCode)
+			}
+			if got := w.Header().Get("Content-Type"); len(tt.wantType) > 0 && got != tt.wantType {
+				t.Errorf("Content-Type = %s, want %s", got, tt.wantType)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(w.Body.String(), s) {
+					t.Errorf("%s %s = %s, want %q in it", method, tt.path, w.Body, s)
+				}
+			}
+		})
+	}
+
+	if _, err := NewSwaggerHandler(DefaultSwaggerUIAssets); err == nil {
+		t.Error("NewSwaggerHandler() error = nil without documents")
+	}
+}
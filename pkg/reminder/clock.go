@@ -0,0 +1,26 @@
+package reminder
+
+import (
+	"time"
+)
+
+// Clock is source of the current time for Scheduler
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// After returns channel which receives the current time after duration d
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is Clock of the system time
+type SystemClock struct{}
+
+// Now returns the current system time in UTC
+func (SystemClock) Now() time.Time {
+	return time.Now().In(time.UTC)
+}
+
+// After waits for duration d in system time
+func (SystemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
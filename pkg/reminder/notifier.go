@@ -0,0 +1,185 @@
+package reminder
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Reminder is due reminder of todo task
+type Reminder struct {
+	// TodoID is unique integer identifier of the todo task
+	TodoID int64 `json:"todo_id"`
+	// ListID is unique integer identifier of the list of the task
+	ListID int64 `json:"list_id"`
+	// Title of the task
+	Title string `json:"title"`
+	// Description of the task
+	Description string `json:"description"`
+	// Time the reminder is due, snoozed time if the reminder is snoozed
+	Time time.Time `json:"time"`
+}
+
+// Notifier delivers reminders to user
+type Notifier interface {
+	// Notify delivers reminder, error means the delivery should be retried
+	Notify(ctx context.Context, r Reminder) error
+}
+
+// LogNotifier writes reminders to the server log
+type LogNotifier struct{}
+
+// Notify writes reminder to log
+func (LogNotifier) Notify(ctx context.Context, r Reminder) error {
+	log.Printf("reminder: Todo with ID='%d' '%s' is due at %s", r.TodoID, r.Title, r.Time.Format(time.RFC3339))
+	return nil
+}
+
+// WebhookNotifier posts reminders as JSON to URL
+type WebhookNotifier struct {
+	// URL reminders are posted to
+	URL string
+	// Client is HTTP client, http.DefaultClient if nil
+	Client *http.Client
+}
+
+// Notify posts reminder, any response status except 2xx is error
+func (n *WebhookNotifier) Notify(ctx context.Context, r Reminder) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// SMTPNotifier sends reminders by e-mail
+type SMTPNotifier struct {
+	// Addr is address of SMTP server in format "host:port"
+	Addr string
+	// Auth is SMTP authentication, nil if the server doesn't require it
+	Auth smtp.Auth
+	// From is sender address
+	From string
+	// To is list of recipient addresses
+	To []string
+}
+
+// Notify sends reminder e-mail
+func (n *SMTPNotifier) Notify(ctx context.Context, r Reminder) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", n.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.To, ", "))
+	fmt.Fprintf(&msg, "Subject: Reminder: %s\r\n", strings.NewReplacer("\r", " ", "\n", " ").Replace(r.Title))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	fmt.Fprintf(&msg, "%s\r\n\r\nDue at %s\r\n", r.Description, r.Time.Format(time.RFC3339))
+
+	return n.send(ctx, []byte(msg.String()))
+}
+
+// send sends message like smtp.SendMail, but the connection is closed when context is done,
+// so unresponsive server doesn't block the caller
+func (n *SMTPNotifier) send(ctx context.Context, msg []byte) error {
+	host, _, err := net.SplitHostPort(n.Addr)
+	if err != nil {
+		return err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", n.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	// deadline covers blocked reads and writes, cancel of context closes the connection
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return ctxError(ctx, err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return ctxError(ctx, err)
+		}
+	}
+	if n.Auth != nil {
+		if err := c.Auth(n.Auth); err != nil {
+			return ctxError(ctx, err)
+		}
+	}
+	if err := c.Mail(n.From); err != nil {
+		return ctxError(ctx, err)
+	}
+	for _, to := range n.To {
+		if err := c.Rcpt(to); err != nil {
+			return ctxError(ctx, err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return ctxError(ctx, err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return ctxError(ctx, err)
+	}
+	if err := w.Close(); err != nil {
+		return ctxError(ctx, err)
+	}
+
+	return ctxError(ctx, c.Quit())
+}
+
+// ctxError returns context error if the connection failed because context is done
+func ctxError(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("%v: %v", ctx.Err(), err)
+	}
+	return err
+}
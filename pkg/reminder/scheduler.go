@@ -0,0 +1,184 @@
+// Package reminder fires due reminders of todo tasks through notifiers.
+//
+// Delivery state is kept per reminder time and notifier in reminder_delivery table,
+// so reminder is delivered once by every notifier even if the server restarts.
+// Snoozed or rescheduled reminder has another time and is delivered again.
+// Delivery is at least once: reminder delivered just before crash can be delivered again.
+package reminder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+// state is state of reminder delivery
+type state int32
+
+const (
+	// stateRetry is failed delivery which is retried later
+	stateRetry state = 0
+	// stateDelivered is successful delivery
+	stateDelivered state = 1
+	// stateFailed is delivery given up after all attempts
+	stateFailed state = 2
+)
+
+// notifyTimeout is maximum time of single delivery
+const notifyTimeout = 30 * time.Second
+
+// fireTime is SQL expression of time the reminder of todo task is due
+const fireTime = "COALESCE(t.snoozed_until, t.reminder)"
+
+// Scheduler scans due reminders and delivers them through notifiers
+type Scheduler struct {
+	db        *sql.DB
+	clock     Clock
+	notifiers map[string]Notifier
+
+	// Interval is how often due reminders are scanned
+	Interval time.Duration
+	// Lookback is how old reminders are still delivered, older ones are skipped,
+	// it keeps the server from firing stale reminders after long downtime
+	Lookback time.Duration
+	// MaxAttempts is number of delivery attempts before reminder is given up
+	MaxAttempts int
+	// RetryDelay is delay before the first retry, it doubles with every attempt
+	RetryDelay time.Duration
+	// BatchSize is maximum number of reminders delivered by notifier in one scan
+	BatchSize int
+}
+
+// NewScheduler returns Scheduler delivering reminders through notifiers by name,
+// the name is stored in delivery state and must not change between restarts
+func NewScheduler(db *sql.DB, clock Clock, notifiers map[string]Notifier) *Scheduler {
+	return &Scheduler{
+		db:          db,
+		clock:       clock,
+		notifiers:   notifiers,
+		Interval:    30 * time.Second,
+		Lookback:    24 * time.Hour,
+		MaxAttempts: 5,
+		RetryDelay:  time.Minute,
+		BatchSize:   100,
+	}
+}
+
+// Run delivers due reminders every Interval until context is done
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		if n, err := s.Dispatch(ctx); err != nil {
+			log.Printf("failed to dispatch reminders: %v", err)
+		} else if n > 0 {
+			log.Printf("dispatched %d reminders", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.clock.After(s.Interval):
+		}
+	}
+}
+
+// Dispatch delivers reminders due at the current time of the clock once
+// and returns number of delivery attempts
+func (s *Scheduler) Dispatch(ctx context.Context) (int, error) {
+	names := make([]string, 0, len(s.notifiers))
+	for name := range s.notifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	total := 0
+	for _, name := range names {
+		n, err := s.dispatch(ctx, name, s.notifiers[name])
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("notifier '%s': %v", name, err)
+		}
+	}
+
+	return total, nil
+}
+
+// due is due reminder with number of its delivery attempts so far
+type due struct {
+	Reminder
+	attempts int
+}
+
+// dispatch delivers due reminders through notifier
+func (s *Scheduler) dispatch(ctx context.Context, name string, n Notifier) (int, error) {
+	now := s.clock.Now().In(time.UTC)
+
+	list, err := s.dueReminders(ctx, name, now)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, d := range list {
+		st := stateDelivered
+		var lastError string
+		var next sql.NullTime
+
+		d.attempts++
+		nctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+		err := n.Notify(nctx, d.Reminder)
+		cancel()
+		if err != nil {
+			lastError = err.Error()
+			if d.attempts >= s.MaxAttempts {
+				st = stateFailed
+				log.Printf("gave up reminder of Todo with ID='%d' via '%s' after %d attempts: %v", d.TodoID, name, d.attempts, err)
+			} else {
+				st = stateRetry
+				next = sql.NullTime{Time: now.Add(s.RetryDelay << (d.attempts - 1)), Valid: true}
+			}
+		}
+
+		if _, err := s.db.ExecContext(ctx, "INSERT INTO reminder_delivery(todo_id, fire_time, notifier, state, attempts, last_error, next_attempt_time, update_time) "+
+			"VALUES(?,?,?,?,?,?,?,?) ON DUPLICATE KEY UPDATE state=VALUES(state), attempts=VALUES(attempts), last_error=VALUES(last_error), "+
+			"next_attempt_time=VALUES(next_attempt_time), update_time=VALUES(update_time)",
+			d.TodoID, d.Time, name, int32(st), d.attempts, lastError, next, now); err != nil {
+			return len(list), fmt.Errorf("failed to save reminder delivery: %v", err)
+		}
+	}
+
+	return len(list), nil
+}
+
+// dueReminders returns reminders due at now which were not delivered by notifier yet
+// or whose retry time has come
+func (s *Scheduler) dueReminders(ctx context.Context, name string, now time.Time) ([]due, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT t.id, t.list_id, t.title, COALESCE(t.description, ''), "+fireTime+", COALESCE(d.attempts, 0) "+
+		"FROM todo t LEFT JOIN reminder_delivery d ON d.todo_id = t.id AND d.fire_time = "+fireTime+" AND d.notifier = ? "+
+		"WHERE t.deleted_at IS NULL AND t.status IN (?,?) AND "+fireTime+" <= ? AND "+fireTime+" > ? "+
+		"AND (d.todo_id IS NULL OR (d.state = ? AND d.next_attempt_time <= ?)) "+
+		"ORDER BY "+fireTime+", t.id LIMIT ?",
+		name, int32(v1.Todo_OPEN), int32(v1.Todo_IN_PROGRESS), now, now.Add(-s.Lookback), int32(stateRetry), now, s.BatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select due reminders: %v", err)
+	}
+	defer rows.Close()
+
+	var list []due
+	for rows.Next() {
+		var d due
+		if err := rows.Scan(&d.TodoID, &d.ListID, &d.Title, &d.Description, &d.Time, &d.attempts); err != nil {
+			return nil, fmt.Errorf("failed to retrieve due reminder: %v", err)
+		}
+		list = append(list, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to retrieve due reminders: %v", err)
+	}
+
+	return list, nil
+}
@@ -0,0 +1,159 @@
+package reminder
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// recordingNotifier records delivered reminders and fails deliveries of tasks in fail
+type recordingNotifier struct {
+	mu        sync.Mutex
+	fail      map[int64]bool
+	delivered []Reminder
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, r Reminder) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.fail[r.TodoID] {
+		return errors.New("unavailable")
+	}
+	n.delivered = append(n.delivered, r)
+	return nil
+}
+
+var (
+	dueQuery      = regexp.QuoteMeta("SELECT t.id, t.list_id, t.title, COALESCE(t.description, ''), " + fireTime)
+	deliveryQuery = regexp.QuoteMeta("INSERT INTO reminder_delivery")
+	dueColumns    = []string{"id", "list_id", "title", "description", "fire_time", "attempts"}
+)
+
+func TestDispatch(t *testing.T) {
+	now := time.Date(2021, 10, 1, 9, 0, 0, 0, time.UTC)
+	fired := now.Add(-time.Minute)
+
+	tests := []struct {
+		name     string
+		attempts int
+		fail     bool
+		want     state
+		wantNext interface{}
+	}{
+		{name: "delivered", want: stateDelivered, wantNext: nil},
+		{name: "first failure is retried", fail: true, want: stateRetry, wantNext: now.Add(time.Minute)},
+		{name: "retry delay doubles", attempts: 2, fail: true, want: stateRetry, wantNext: now.Add(4 * time.Minute)},
+		{name: "given up after max attempts", attempts: 4, fail: true, want: stateFailed, wantNext: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			n := &recordingNotifier{fail: map[int64]bool{1: tt.fail}}
+			s := NewScheduler(db, newFakeClock(now), map[string]Notifier{"test": n})
+
+			mock.ExpectQuery(dueQuery).
+				WithArgs("test", sqlmock.AnyArg(), sqlmock.AnyArg(), now, now.Add(-s.Lookback), int32(stateRetry), now, s.BatchSize).
+				WillReturnRows(sqlmock.NewRows(dueColumns).AddRow(1, 2, "Call Bob", "", fired, tt.attempts))
+			var lastError interface{} = ""
+			if tt.fail {
+				lastError = "unavailable"
+			}
+			mock.ExpectExec(deliveryQuery).
+				WithArgs(int64(1), fired, "test", int32(tt.want), tt.attempts+1, lastError, nullTimeArg{tt.wantNext}, now).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			got, err := s.Dispatch(context.Background())
+			if err != nil {
+				t.Fatalf("Dispatch() error = %v", err)
+			}
+			if got != 1 {
+				t.Errorf("Dispatch() = %d, want 1", got)
+			}
+			if delivered := len(n.delivered) == 1; delivered == tt.fail {
+				t.Errorf("delivered = %v, want %v", n.delivered, !tt.fail)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// nullTimeArg matches sql.NullTime argument with time or nil for NULL
+type nullTimeArg struct {
+	want interface{}
+}
+
+func (a nullTimeArg) Match(v driver.Value) bool {
+	t, ok := v.(time.Time)
+	if !ok {
+		return v == nil && a.want == nil
+	}
+	return a.want != nil && t.Equal(a.want.(time.Time))
+}
+
+func TestRunUsesClock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	start := time.Date(2021, 10, 1, 9, 0, 0, 0, time.UTC)
+	clock := newFakeClock(start)
+	n := &recordingNotifier{}
+	s := NewScheduler(db, clock, map[string]Notifier{"test": n})
+
+	// the first scan has nothing due, the second one after interval delivers reminder
+	mock.ExpectQuery(dueQuery).WithArgs("test", sqlmock.AnyArg(), sqlmock.AnyArg(), start,
+		sqlmock.AnyArg(), sqlmock.AnyArg(), start, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows(dueColumns))
+	next := start.Add(s.Interval)
+	mock.ExpectQuery(dueQuery).WithArgs("test", sqlmock.AnyArg(), sqlmock.AnyArg(), next,
+		sqlmock.AnyArg(), sqlmock.AnyArg(), next, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(dueColumns).AddRow(1, 2, "Call Bob", "", next, 0))
+	mock.ExpectExec(deliveryQuery).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	waitFor(t, func() bool { return clock.waiting() == 1 })
+	clock.Advance(s.Interval - time.Second)
+	if err := mock.ExpectationsWereMet(); err == nil {
+		t.Fatal("reminders are scanned before interval")
+	}
+	clock.Advance(time.Second)
+	waitFor(t, func() bool { return mock.ExpectationsWereMet() == nil && clock.waiting() == 1 })
+
+	cancel()
+	<-done
+	if len(n.delivered) != 1 || n.delivered[0].TodoID != 1 {
+		t.Errorf("delivered = %v, want reminder of task 1", n.delivered)
+	}
+}
+
+// waitFor waits until condition is true
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition is not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,507 @@
+// Package rrule parses iCalendar (RFC 5545) recurrence rules and expands them to occurrences.
+//
+// Supported rule parts are FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, COUNT, UNTIL,
+// BYDAY (with ordinals for MONTHLY and YEARLY), BYMONTHDAY, BYMONTH and WKST, COUNT is at most MaxCount.
+// Occurrences keep the wall clock time of the series start in its location,
+// so a rule started at 09:00 Europe/Berlin stays at 09:00 across daylight saving changes.
+package rrule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is FREQ rule part
+type Frequency int
+
+const (
+	Daily Frequency = iota
+	Weekly
+	Monthly
+	Yearly
+)
+
+const (
+	// maxEmptyYears is how many years (times INTERVAL) in a row without occurrences are expanded
+	// before the rule is considered exhausted, it protects from rules which never match.
+	// It is longer than the 8 years between leap years around a century, so rules matching
+	// only February 29 don't end early
+	maxEmptyYears = 10
+
+	// MaxCount is maximum COUNT, occurrences of rule with COUNT are counted from the series start,
+	// so it bounds the time to find an occurrence
+	MaxCount = 100000
+)
+
+// periodsPerYear is maximum number of periods of frequency in a year
+var periodsPerYear = map[Frequency]int{
+	Daily:   366,
+	Weekly:  53,
+	Monthly: 12,
+	Yearly:  1,
+}
+
+var frequencies = map[string]Frequency{
+	"DAILY":   Daily,
+	"WEEKLY":  Weekly,
+	"MONTHLY": Monthly,
+	"YEARLY":  Yearly,
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// WeekdayNum is BYDAY item: weekday with optional ordinal, e.g. -1FR is the last Friday
+type WeekdayNum struct {
+	Weekday time.Weekday
+	// N is ordinal of the weekday in month or year, 0 means every such weekday
+	N int
+}
+
+// Rule is parsed recurrence rule
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	WeekStart  time.Weekday
+}
+
+// Parse parses RRULE value, e.g. "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10".
+// Optional "RRULE:" prefix is skipped
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	r := &Rule{Interval: 1, WeekStart: time.Monday}
+	hasFreq := false
+
+	for _, part := range strings.Split(s, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid rule part '%s'", part)
+		}
+		name, value := strings.ToUpper(kv[0]), strings.ToUpper(kv[1])
+
+		switch name {
+		case "FREQ":
+			f, ok := frequencies[value]
+			if !ok {
+				return nil, fmt.Errorf("unsupported FREQ '%s'", value)
+			}
+			r.Freq = f
+			hasFreq = true
+
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL '%s'", value)
+			}
+			r.Interval = n
+
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > MaxCount {
+				return nil, fmt.Errorf("invalid COUNT '%s', it must be from 1 to %d", value, MaxCount)
+			}
+			r.Count = n
+
+		case "UNTIL":
+			t, err := parseUntil(value)
+			if err != nil {
+				return nil, err
+			}
+			r.Until = t
+
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				wd, err := parseWeekdayNum(d)
+				if err != nil {
+					return nil, err
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(d)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY '%s'", d)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+
+		case "BYMONTH":
+			for _, m := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(m)
+				if err != nil || n < 1 || n > 12 {
+					return nil, fmt.Errorf("invalid BYMONTH '%s'", m)
+				}
+				r.ByMonth = append(r.ByMonth, time.Month(n))
+			}
+
+		case "WKST":
+			wd, ok := weekdays[value]
+			if !ok {
+				return nil, fmt.Errorf("invalid WKST '%s'", value)
+			}
+			r.WeekStart = wd
+
+		default:
+			return nil, fmt.Errorf("unsupported rule part '%s'", name)
+		}
+	}
+
+	if !hasFreq {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, fmt.Errorf("COUNT and UNTIL can't be used together")
+	}
+	for _, wd := range r.ByDay {
+		if wd.N != 0 && r.Freq != Monthly && r.Freq != Yearly {
+			return nil, fmt.Errorf("BYDAY ordinals can be used with MONTHLY and YEARLY only")
+		}
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq == Weekly {
+		return nil, fmt.Errorf("BYMONTHDAY can't be used with WEEKLY")
+	}
+
+	return r, nil
+}
+
+// parseUntil parses UNTIL value as date or UTC date-time
+func parseUntil(s string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			if layout == "20060102" {
+				// the whole last day is included
+				t = t.Add(24*time.Hour - time.Nanosecond)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL '%s'", s)
+}
+
+// parseWeekdayNum parses BYDAY item, e.g. "MO", "2TU", "-1FR"
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY '%s'", s)
+	}
+	wd, ok := weekdays[s[len(s)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY '%s'", s)
+	}
+	n := 0
+	if num := s[:len(s)-2]; len(num) > 0 {
+		var err error
+		n, err = strconv.Atoi(num)
+		if err != nil || n == 0 || n < -53 || n > 53 {
+			return WeekdayNum{}, fmt.Errorf("invalid BYDAY '%s'", s)
+		}
+	}
+	return WeekdayNum{Weekday: wd, N: n}, nil
+}
+
+// Iterator iterates over occurrences of series in order,
+// candidates before the series start are skipped
+type Iterator struct {
+	rule    *Rule
+	dtstart time.Time
+	period  int
+	pending []time.Time
+	count   int
+	done    bool
+}
+
+// Iterator returns iterator over occurrences of series started at dtstart,
+// dtstart location is used for wall clock calculations
+func (r *Rule) Iterator(dtstart time.Time) *Iterator {
+	return &Iterator{rule: r, dtstart: dtstart}
+}
+
+// Next returns the next occurrence, false if there are no more occurrences
+func (it *Iterator) Next() (time.Time, bool) {
+	for !it.done {
+		if len(it.pending) == 0 {
+			empty := 0
+			for len(it.pending) == 0 {
+				if empty >= maxEmptyYears*periodsPerYear[it.rule.Freq] {
+					it.done = true
+					return time.Time{}, false
+				}
+				it.pending = it.expand(it.period)
+				it.period++
+				empty++
+			}
+		}
+
+		t := it.pending[0]
+		it.pending = it.pending[1:]
+
+		if t.Before(it.dtstart) {
+			continue
+		}
+		if !it.rule.Until.IsZero() && t.After(it.rule.Until) {
+			it.done = true
+			break
+		}
+
+		it.count++
+		if it.rule.Count > 0 && it.count >= it.rule.Count {
+			it.done = true
+		}
+		return t, true
+	}
+
+	return time.Time{}, false
+}
+
+// skipTo moves iterator without COUNT forward to the period before the one containing t,
+// so occurrences around t are found without expanding all periods since the series start.
+// Occurrences of rule with COUNT are counted from the start, so its iterator isn't moved
+func (it *Iterator) skipTo(t time.Time) {
+	if it.rule.Count > 0 || it.count > 0 || len(it.pending) > 0 || !t.After(it.dtstart) {
+		return
+	}
+
+	// civil dates are compared, so daylight saving changes don't matter,
+	// Unix time is used as duration is limited to about 290 years
+	start := it.dtstart
+	t = t.In(start.Location())
+	date := func(t time.Time) int64 {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix()
+	}
+	days := int((date(t) - date(start)) / (24 * 60 * 60))
+
+	var units int
+	switch it.rule.Freq {
+	case Daily:
+		units = days
+	case Weekly:
+		offset := (int(start.Weekday()) - int(it.rule.WeekStart) + 7) % 7
+		units = (days + offset) / 7
+	case Monthly:
+		units = (t.Year()-start.Year())*12 + int(t.Month()) - int(start.Month())
+	case Yearly:
+		units = t.Year() - start.Year()
+	}
+
+	if period := units/it.rule.Interval - 1; period > it.period {
+		it.period = period
+	}
+}
+
+// After returns the first occurrence of series started at dtstart strictly after t
+func (r *Rule) After(dtstart, t time.Time) (time.Time, bool) {
+	it := r.Iterator(dtstart)
+	it.skipTo(t)
+	for {
+		o, ok := it.Next()
+		if !ok {
+			return time.Time{}, false
+		}
+		if o.After(t) {
+			return o, true
+		}
+	}
+}
+
+// Between returns up to limit occurrences of series started at dtstart in [from, to),
+// zero to means no upper bound
+func (r *Rule) Between(dtstart, from, to time.Time, limit int) []time.Time {
+	var list []time.Time
+	it := r.Iterator(dtstart)
+	it.skipTo(from)
+	for len(list) < limit {
+		o, ok := it.Next()
+		if !ok || (!to.IsZero() && !o.Before(to)) {
+			break
+		}
+		if !o.Before(from) {
+			list = append(list, o)
+		}
+	}
+	return list
+}
+
+// expand returns sorted candidate occurrences in the n-th period of the rule
+func (it *Iterator) expand(n int) []time.Time {
+	r := it.rule
+	start := it.dtstart
+	loc := start.Location()
+	h, m, s := start.Clock()
+	at := func(y int, mo time.Month, d int) time.Time {
+		return time.Date(y, mo, d, h, m, s, start.Nanosecond(), loc)
+	}
+
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		d := at(start.Year(), start.Month(), start.Day()+n*r.Interval)
+		if r.matchMonth(d) && r.matchMonthDay(d) && r.matchWeekday(d) {
+			days = append(days, d)
+		}
+
+	case Weekly:
+		// first day of the week containing dtstart
+		offset := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+		weekStart := at(start.Year(), start.Month(), start.Day()-offset+7*n*r.Interval)
+		for i := 0; i < 7; i++ {
+			d := at(weekStart.Year(), weekStart.Month(), weekStart.Day()+i)
+			if len(r.ByDay) == 0 {
+				if d.Weekday() != start.Weekday() {
+					continue
+				}
+			} else if !r.matchWeekday(d) {
+				continue
+			}
+			if r.matchMonth(d) {
+				days = append(days, d)
+			}
+		}
+
+	case Monthly:
+		first := at(start.Year(), start.Month()+time.Month(n*r.Interval), 1)
+		if r.matchMonth(first) {
+			days = r.expandPeriod(monthDays(first, at), start.Day())
+		}
+
+	case Yearly:
+		year := start.Year() + n*r.Interval
+		if len(r.ByMonth) == 0 && len(r.ByDay) > 0 && len(r.ByMonthDay) == 0 {
+			// ordinals of weekdays are relative to the year
+			var all []time.Time
+			for mo := time.January; mo <= time.December; mo++ {
+				all = append(all, monthDays(at(year, mo, 1), at)...)
+			}
+			days = r.expandPeriod(all, 0)
+			break
+		}
+
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{start.Month()}
+		}
+		for _, mo := range months {
+			days = append(days, r.expandPeriod(monthDays(at(year, mo, 1), at), start.Day())...)
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days
+}
+
+// monthDays returns all days of the month of first
+func monthDays(first time.Time, at func(int, time.Month, int) time.Time) []time.Time {
+	var days []time.Time
+	for d := 1; ; d++ {
+		t := at(first.Year(), first.Month(), d)
+		if t.Month() != first.Month() {
+			break
+		}
+		days = append(days, t)
+	}
+	return days
+}
+
+// expandPeriod selects days of month or year by BYMONTHDAY and BYDAY,
+// defaultDay of month is used if neither is set (0 selects none)
+func (r *Rule) expandPeriod(days []time.Time, defaultDay int) []time.Time {
+	var list []time.Time
+	for i, d := range days {
+		if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+			if d.Day() == defaultDay {
+				list = append(list, d)
+			}
+			continue
+		}
+		if len(r.ByMonthDay) > 0 && !r.matchMonthDay(d) {
+			continue
+		}
+		if len(r.ByDay) > 0 && !r.matchWeekdayIn(days, i) {
+			continue
+		}
+		list = append(list, d)
+	}
+	return list
+}
+
+func (r *Rule) matchMonth(d time.Time) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		if d.Month() == m {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchMonthDay(d time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, d.Location()).Day()
+	for _, md := range r.ByMonthDay {
+		if md == d.Day() || (md < 0 && last+md+1 == d.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matchWeekday(d time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Weekday == d.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWeekdayIn checks BYDAY with ordinals for i-th day of the period
+func (r *Rule) matchWeekdayIn(days []time.Time, i int) bool {
+	d := days[i]
+	for _, wd := range r.ByDay {
+		if wd.Weekday != d.Weekday() {
+			continue
+		}
+		if wd.N == 0 {
+			return true
+		}
+
+		// position of the weekday from the start and from the end of the period
+		pos, neg := 0, 0
+		for j := 0; j <= i; j++ {
+			if days[j].Weekday() == wd.Weekday {
+				pos++
+			}
+		}
+		for j := i; j < len(days); j++ {
+			if days[j].Weekday() == wd.Weekday {
+				neg--
+			}
+		}
+		if wd.N == pos || wd.N == neg {
+			return true
+		}
+	}
+	return false
+}
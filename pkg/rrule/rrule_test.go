@@ -0,0 +1,248 @@
+package rrule
+
+import (
+	"testing"
+	"time"
+)
+
+// occurrences returns up to 10 occurrences of rule started at dtstart formatted in dtstart location
+func occurrences(t *testing.T, rule string, dtstart time.Time) []string {
+	t.Helper()
+	r, err := Parse(rule)
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", rule, err)
+	}
+	var list []string
+	it := r.Iterator(dtstart)
+	for len(list) < 10 {
+		o, ok := it.Next()
+		if !ok {
+			break
+		}
+		list = append(list, o.Format(time.RFC3339))
+	}
+	return list
+}
+
+func TestIterator(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(y int, mo time.Month, d int) time.Time {
+		return time.Date(y, mo, d, 9, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		want    []string
+	}{
+		{
+			name:    "count",
+			rule:    "FREQ=DAILY;COUNT=3",
+			dtstart: at(2021, 10, 1),
+			want:    []string{"2021-10-01T09:00:00Z", "2021-10-02T09:00:00Z", "2021-10-03T09:00:00Z"},
+		},
+		{
+			name:    "until",
+			rule:    "RRULE:FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20211013T000000Z",
+			dtstart: at(2021, 10, 4),
+			want:    []string{"2021-10-04T09:00:00Z", "2021-10-06T09:00:00Z", "2021-10-11T09:00:00Z"},
+		},
+		{
+			name:    "until date includes the whole day",
+			rule:    "FREQ=DAILY;UNTIL=20211002",
+			dtstart: at(2021, 10, 1),
+			want:    []string{"2021-10-01T09:00:00Z", "2021-10-02T09:00:00Z"},
+		},
+		{
+			name:    "interval",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;COUNT=3",
+			dtstart: at(2021, 10, 1),
+			want:    []string{"2021-10-01T09:00:00Z", "2021-10-15T09:00:00Z", "2021-10-29T09:00:00Z"},
+		},
+		{
+			name:    "last friday of month",
+			rule:    "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			dtstart: at(2021, 10, 1),
+			want:    []string{"2021-10-29T09:00:00Z", "2021-11-26T09:00:00Z", "2021-12-31T09:00:00Z"},
+		},
+		{
+			name:    "second tuesday of month",
+			rule:    "FREQ=MONTHLY;BYDAY=2TU;COUNT=2",
+			dtstart: at(2021, 10, 1),
+			want:    []string{"2021-10-12T09:00:00Z", "2021-11-09T09:00:00Z"},
+		},
+		{
+			name:    "first monday of year",
+			rule:    "FREQ=YEARLY;BYDAY=1MO;COUNT=2",
+			dtstart: at(2021, 1, 1),
+			want:    []string{"2021-01-04T09:00:00Z", "2022-01-03T09:00:00Z"},
+		},
+		{
+			name:    "missing month days are skipped",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=31;COUNT=3",
+			dtstart: at(2021, 1, 31),
+			want:    []string{"2021-01-31T09:00:00Z", "2021-03-31T09:00:00Z", "2021-05-31T09:00:00Z"},
+		},
+		{
+			name:    "leap day yearly",
+			rule:    "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29;COUNT=3",
+			dtstart: at(2020, 2, 29),
+			want:    []string{"2020-02-29T09:00:00Z", "2024-02-29T09:00:00Z", "2028-02-29T09:00:00Z"},
+		},
+		{
+			name:    "leap day daily",
+			rule:    "FREQ=DAILY;BYMONTH=2;BYMONTHDAY=29;COUNT=2",
+			dtstart: at(2021, 3, 1),
+			want:    []string{"2024-02-29T09:00:00Z", "2028-02-29T09:00:00Z"},
+		},
+		{
+			name:    "leap day across century",
+			rule:    "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29;COUNT=2",
+			dtstart: at(2096, 2, 29),
+			want:    []string{"2096-02-29T09:00:00Z", "2104-02-29T09:00:00Z"},
+		},
+		{
+			name:    "never matching rule ends",
+			rule:    "FREQ=MONTHLY;BYMONTH=2;BYMONTHDAY=30",
+			dtstart: at(2021, 1, 1),
+		},
+		{
+			name:    "wall clock kept across daylight saving change",
+			rule:    "FREQ=DAILY;COUNT=3",
+			dtstart: time.Date(2021, 3, 27, 9, 0, 0, 0, berlin),
+			want:    []string{"2021-03-27T09:00:00+01:00", "2021-03-28T09:00:00+02:00", "2021-03-29T09:00:00+02:00"},
+		},
+		{
+			name:    "weekly across end of daylight saving",
+			rule:    "FREQ=WEEKLY;BYDAY=SU;COUNT=2",
+			dtstart: time.Date(2021, 10, 24, 9, 0, 0, 0, berlin),
+			want:    []string{"2021-10-24T09:00:00+02:00", "2021-10-31T09:00:00+01:00"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := occurrences(t, tt.rule, tt.dtstart)
+			if len(got) != len(tt.want) {
+				t.Fatalf("occurrences = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("occurrences = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, rule := range []string{
+		"",
+		"COUNT=3",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;COUNT=100001",
+		"FREQ=DAILY;COUNT=3;UNTIL=20211002",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYDAY=XX",
+		"FREQ=DAILY;UNTIL=tomorrow",
+	} {
+		if _, err := Parse(rule); err == nil {
+			t.Errorf("Parse(%q) error = nil, want error", rule)
+		}
+	}
+}
+
+func TestAfter(t *testing.T) {
+	dtstart := time.Date(2021, 10, 5, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		rule   string
+		t      time.Time
+		want   time.Time
+		wantOK bool
+	}{
+		{
+			name:   "far future of dense rule",
+			rule:   "FREQ=DAILY",
+			t:      time.Date(9000, 1, 1, 0, 0, 0, 0, time.UTC),
+			want:   time.Date(9000, 1, 1, 9, 0, 0, 0, time.UTC),
+			wantOK: true,
+		},
+		{
+			name:   "strictly after",
+			rule:   "FREQ=MONTHLY",
+			t:      time.Date(2022, 1, 5, 9, 0, 0, 0, time.UTC),
+			want:   time.Date(2022, 2, 5, 9, 0, 0, 0, time.UTC),
+			wantOK: true,
+		},
+		{
+			name: "series over",
+			rule: "FREQ=DAILY;UNTIL=20211010",
+			t:    time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "count over",
+			rule: "FREQ=YEARLY;COUNT=2",
+			t:    time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := r.After(dtstart, tt.t)
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("After() = %s, %v, want %s, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// TestSkipTo checks occurrences found by skipping periods are the ones found by iterating all of them
+func TestSkipTo(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dtstart := time.Date(2021, 10, 5, 9, 0, 0, 0, berlin)
+
+	for _, rule := range []string{
+		"FREQ=DAILY;INTERVAL=3",
+		"FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,SU;WKST=SU",
+		"FREQ=MONTHLY;BYDAY=-1FR,1MO",
+		"FREQ=MONTHLY;INTERVAL=5;BYMONTHDAY=-1",
+		"FREQ=YEARLY;INTERVAL=2;BYMONTH=3,10;BYDAY=-1SU",
+	} {
+		r, err := Parse(rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		it := r.Iterator(dtstart)
+		prev := dtstart.Add(-time.Hour)
+		for i := 0; i < 200; i++ {
+			o, ok := it.Next()
+			if !ok {
+				t.Fatalf("%s: series ended after %d occurrences", rule, i)
+			}
+			// any time between the previous and this occurrence is followed by this one
+			for _, after := range []time.Time{prev, prev.Add(o.Sub(prev) / 2), o.Add(-time.Second)} {
+				if got, ok := r.After(dtstart, after); !ok || !got.Equal(o) {
+					t.Fatalf("%s: After(%s) = %s, want %s", rule, after, got, o)
+				}
+			}
+			if got := r.Between(dtstart, o, time.Time{}, 1); len(got) != 1 || !got[0].Equal(o) {
+				t.Fatalf("%s: Between(%s) = %v, want %s", rule, o, got, o)
+			}
+			prev = o
+		}
+	}
+}
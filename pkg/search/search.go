@@ -0,0 +1,131 @@
+// Package search builds full-text search queries and highlights matches in found text.
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Terms splits user query to lower case words, characters other than letters and digits
+// (including full-text and LIKE operators) separate words and are dropped
+func Terms(query string) []string {
+	seen := map[string]bool{}
+	var terms []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(query), isSeparator) {
+		if !seen[w] {
+			seen[w] = true
+			terms = append(terms, w)
+		}
+	}
+	return terms
+}
+
+// BooleanQuery returns MySQL boolean mode full-text query requiring every term,
+// terms are matched as word prefixes
+func BooleanQuery(terms []string) string {
+	var b strings.Builder
+	for i, t := range terms {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString("+" + t + "*")
+	}
+	return b.String()
+}
+
+// Snippet returns fragment of text around the first match of terms, up to width runes long,
+// with words starting with any of the terms wrapped by pre and post tags.
+// It returns empty string if text doesn't match
+func Snippet(text string, terms []string, width int, pre, post string) string {
+	r := []rune(text)
+	words := wordSpans(r)
+
+	first := -1
+	for _, w := range words {
+		if matches(r[w[0]:w[1]], terms) {
+			first = w[0]
+			break
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+
+	// window with the first match a quarter into it
+	start := first - width/4
+	if start < 0 {
+		start = 0
+	}
+	end := start + width
+	if end > len(r) {
+		end = len(r)
+		if start = end - width; start < 0 {
+			start = 0
+		}
+	}
+
+	// don't cut words at window bounds
+	for start > 0 && !isSeparator(r[start-1]) {
+		start--
+	}
+	for end < len(r) && !isSeparator(r[end]) {
+		end++
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, w := range words {
+		if w[1] <= start || w[0] >= end {
+			continue
+		}
+		if matches(r[w[0]:w[1]], terms) {
+			b.WriteString(string(r[pos:w[0]]))
+			b.WriteString(pre + string(r[w[0]:w[1]]) + post)
+			pos = w[1]
+		}
+	}
+	b.WriteString(string(r[pos:end]))
+	if end < len(r) {
+		b.WriteString("…")
+	}
+
+	return b.String()
+}
+
+// wordSpans returns [start, end) rune offsets of words in text
+func wordSpans(r []rune) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, c := range r {
+		if isSeparator(c) {
+			if start >= 0 {
+				spans = append(spans, [2]int{start, i})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(r)})
+	}
+	return spans
+}
+
+// matches returns true if the word starts with any of terms
+func matches(word []rune, terms []string) bool {
+	w := strings.ToLower(string(word))
+	for _, t := range terms {
+		if strings.HasPrefix(w, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSeparator(c rune) bool {
+	return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+}
@@ -0,0 +1,67 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "Call Bob", want: []string{"call", "bob"}},
+		{query: "call CALL call", want: []string{"call"}},
+		// full-text operators and LIKE wildcards are separators, not syntax
+		{query: `+call -bob* "lunch" (x) ~y <z> @2`, want: []string{"call", "bob", "lunch", "x", "y", "z", "2"}},
+		{query: "50%_off", want: []string{"50", "off"}},
+		{query: "Grüße 東京", want: []string{"grüße", "東京"}},
+		{query: `%_*"+-`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := Terms(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Terms(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBooleanQuery(t *testing.T) {
+	if got, want := BooleanQuery([]string{"call", "bob"}), "+call* +bob*"; got != want {
+		t.Errorf("BooleanQuery() = %q, want %q", got, want)
+	}
+	if got := BooleanQuery(Terms(`bob") OR ("1`)); got != "+bob* +or* +1*" {
+		t.Errorf("BooleanQuery() = %q, want words only", got)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		width int
+		want  string
+	}{
+		{name: "no match", text: "Buy milk", terms: []string{"bob"}, width: 20},
+		{name: "whole text", text: "Call Bob about lunch", terms: []string{"bob"}, width: 40, want: "Call [Bob] about lunch"},
+		{name: "prefix and case", text: "Calling BOBBY", terms: []string{"call", "bob"}, width: 40, want: "[Calling] [BOBBY]"},
+		{name: "inside a word isn't a match", text: "Kabob", terms: []string{"bob"}, width: 40},
+		{
+			name:  "window around the first match",
+			text:  "one two three four five six seven eight nine ten bob eleven twelve thirteen fourteen",
+			terms: []string{"bob"},
+			width: 20,
+			want:  "…nine ten [bob] eleven twelve…",
+		},
+		{name: "runes", text: "Grüße an Bob", terms: []string{"grüße"}, width: 40, want: "[Grüße] an Bob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Snippet(tt.text, tt.terms, tt.width, "[", "]"); got != tt.want {
+				t.Errorf("Snippet() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
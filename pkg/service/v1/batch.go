@@ -0,0 +1,145 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// maxBatchSize is maximum number of todo tasks in batch request
+	maxBatchSize = 500
+)
+
+// runBatch runs operation for n items in one transaction and returns results in order of items.
+// In partial mode every item runs in its own savepoint, so failed item is rolled back alone
+// and its status is returned in results. Otherwise the first failed item fails the whole batch.
+// field is name of request field with items used in error messages
+func (s *todoServiceServer) runBatch(ctx context.Context, field string, n int, partial bool,
+	op func(tx *sql.Tx, i int) (int64, error)) ([]*v1.BatchResult, error) {
+	if n == 0 {
+		return nil, status.Error(codes.InvalidArgument, field+" must not be empty")
+	}
+	if n > maxBatchSize {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("%s can't have more than %d items", field, maxBatchSize))
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	tx, err := c.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to start transaction-> "+err.Error())
+	}
+	defer tx.Rollback()
+
+	results := make([]*v1.BatchResult, n)
+	for i := 0; i < n; i++ {
+		if partial {
+			if _, err := tx.ExecContext(ctx, "SAVEPOINT batch_item"); err != nil {
+				return nil, status.Error(codes.Unknown, "failed to create savepoint-> "+err.Error())
+			}
+		}
+
+		id, err := op(tx, i)
+		if err != nil {
+			st := status.Convert(err)
+			if !partial {
+				return nil, status.Error(st.Code(), fmt.Sprintf("%s[%d]: %s", field, i, st.Message()))
+			}
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT batch_item"); err != nil {
+				return nil, status.Error(codes.Unknown, "failed to rollback to savepoint-> "+err.Error())
+			}
+			results[i] = &v1.BatchResult{Status: st.Proto(), Id: id}
+			continue
+		}
+
+		results[i] = &v1.BatchResult{Status: status.New(codes.OK, "").Proto(), Id: id}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to commit transaction-> "+err.Error())
+	}
+	s.events.notify()
+
+	return results, nil
+}
+
+// Create todo tasks in one transaction
+func (s *todoServiceServer) BatchCreate(ctx context.Context, req *v1.BatchCreateRequest) (*v1.BatchCreateResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	results, err := s.runBatch(ctx, "todos", len(req.Todos), req.Partial, func(tx *sql.Tx, i int) (int64, error) {
+		return createTodo(ctx, tx, req.Todos[i])
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.BatchCreateResponse{
+		Api:     API_VERSION,
+		Results: results,
+	}, nil
+}
+
+// Update todo tasks in one transaction
+func (s *todoServiceServer) BatchUpdate(ctx context.Context, req *v1.BatchUpdateRequest) (*v1.BatchUpdateResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	results, err := s.runBatch(ctx, "todos", len(req.Todos), req.Partial, func(tx *sql.Tx, i int) (int64, error) {
+		_, err := updateTodo(ctx, tx, req.Todos[i])
+		return req.Todos[i].GetId(), err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.BatchUpdateResponse{
+		Api:     API_VERSION,
+		Results: results,
+	}, nil
+}
+
+// Delete todo tasks in one transaction
+func (s *todoServiceServer) BatchDelete(ctx context.Context, req *v1.BatchDeleteRequest) (*v1.BatchDeleteResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	// Subtasks are deleted with their parent, so subtask deleted earlier in the batch
+	// is deleted successfully rather than not found
+	deleted := map[int64]bool{}
+	results, err := s.runBatch(ctx, "ids", len(req.Ids), req.Partial, func(tx *sql.Tx, i int) (int64, error) {
+		if deleted[req.Ids[i]] {
+			return req.Ids[i], nil
+		}
+		ids, err := deleteTodo(ctx, tx, req.Ids[i])
+		for _, id := range ids {
+			deleted[id] = true
+		}
+		return req.Ids[i], err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.BatchDeleteResponse{
+		Api:     API_VERSION,
+		Results: results,
+	}, nil
+}
@@ -0,0 +1,123 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+func TestBatchDelete(t *testing.T) {
+	child := &v1.Todo{Id: 2, Title: "changelog", ListId: 2, ParentId: 1}
+
+	// expectDelete expects deletion of task with subtasks, missing task isn't found
+	expectDelete := func(mock sqlmock.Sqlmock, id int64, missing bool, subtasks ...*v1.Todo) {
+		if missing {
+			mock.ExpectExec("UPDATE todo SET deleted_at=? WHERE id=? AND deleted_at IS NULL").WithArgs(sqlmock.AnyArg(), id).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			return
+		}
+		mock.ExpectExec("UPDATE todo SET deleted_at=? WHERE id=? AND deleted_at IS NULL").WithArgs(sqlmock.AnyArg(), id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		ids := sqlmock.NewRows([]string{"id"})
+		for _, td := range subtasks {
+			ids.AddRow(td.Id)
+		}
+		mock.ExpectQuery("SELECT id FROM todo WHERE parent_id IN (?)").WithArgs(id).WillReturnRows(ids)
+		deleted := subtasks
+		if len(subtasks) > 0 {
+			mock.ExpectQuery("SELECT id FROM todo WHERE parent_id IN (?)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			mock.ExpectExec("UPDATE todo SET deleted_at=? WHERE id IN").WillReturnResult(sqlmock.NewResult(0, int64(len(subtasks))))
+		}
+		deleted = append(deleted, &v1.Todo{Id: id, ListId: 2})
+		expectEmit(mock, v1.TodoEvent_DELETED, deleted...)
+	}
+
+	tests := []struct {
+		name      string
+		ids       []int64
+		partial   bool
+		expect    func(mock sqlmock.Sqlmock)
+		wantCodes []codes.Code
+		wantCode  codes.Code
+	}{
+		{name: "empty", wantCode: codes.InvalidArgument},
+		{name: "too many", ids: make([]int64, maxBatchSize+1), wantCode: codes.InvalidArgument},
+		{
+			name: "parent with child",
+			ids:  []int64{1, 2},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				expectDelete(mock, 1, false, child)
+				mock.ExpectCommit()
+			},
+			wantCodes: []codes.Code{codes.OK, codes.OK},
+		},
+		{
+			name: "child with parent",
+			ids:  []int64{2, 1},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				expectDelete(mock, 2, false)
+				expectDelete(mock, 1, false)
+				mock.ExpectCommit()
+			},
+			wantCodes: []codes.Code{codes.OK, codes.OK},
+		},
+		{
+			name: "missing task fails batch",
+			ids:  []int64{1, 3},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				expectDelete(mock, 1, false, child)
+				expectDelete(mock, 3, true)
+				mock.ExpectRollback()
+			},
+			wantCode: codes.NotFound,
+		},
+		{
+			name:    "missing task in partial batch",
+			ids:     []int64{3, 1, 2},
+			partial: true,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("SAVEPOINT batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+				expectDelete(mock, 3, true)
+				mock.ExpectExec("ROLLBACK TO SAVEPOINT batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("SAVEPOINT batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+				expectDelete(mock, 1, false, child)
+				mock.ExpectExec("SAVEPOINT batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+			wantCodes: []codes.Code{codes.NotFound, codes.OK, codes.OK},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newMock(t)
+			if tt.expect != nil {
+				tt.expect(mock)
+			}
+
+			res, err := s.BatchDelete(context.Background(), &v1.BatchDeleteRequest{Api: API_VERSION, Ids: tt.ids, Partial: tt.partial})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("BatchDelete() error = %v, want code %s", err, tt.wantCode)
+			}
+			if err != nil {
+				return
+			}
+			if len(res.Results) != len(tt.wantCodes) {
+				t.Fatalf("BatchDelete() results = %v, want %d", res.Results, len(tt.wantCodes))
+			}
+			for i, r := range res.Results {
+				if code := codes.Code(r.Status.Code); code != tt.wantCodes[i] || r.Id != tt.ids[i] {
+					t.Errorf("results[%d] = %s of ID='%d', want %s of ID='%d'", i, code, r.Id, tt.wantCodes[i], tt.ids[i])
+				}
+			}
+		})
+	}
+}
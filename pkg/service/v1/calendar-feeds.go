@@ -0,0 +1,200 @@
+package v1
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// CalendarPathPrefix is path calendar feeds are served under by HTTP server
+	CalendarPathPrefix = "/calendar/"
+
+	// maxFeedOwnerLength is maximum length of calendar feed owner in characters
+	maxFeedOwnerLength = 255
+)
+
+// feedName returns resource name of calendar feed
+func feedName(id int64) string {
+	return fmt.Sprintf("calendarFeeds/%d", id)
+}
+
+// feedTokenHash returns hash of feed token stored in calendar_feed table
+func feedTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newFeedToken returns random secret token of feed path
+func newFeedToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// feedColumns is list of calendar_feed table columns read into CalendarFeed entity by queryFeeds
+const feedColumns = "id, owner, list_id, create_time, last_access_time"
+
+// queryFeeds returns calendar feeds selected by query, paths are not known as only hashes of tokens are stored
+func queryFeeds(ctx context.Context, c queryer, query string, args ...interface{}) ([]*v1.CalendarFeed, error) {
+	rows, err := c.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to select from calendar_feed-> "+err.Error())
+	}
+	defer rows.Close()
+
+	list := []*v1.CalendarFeed{}
+	for rows.Next() {
+		f := new(v1.CalendarFeed)
+		var createTime time.Time
+		var lastAccessTime sql.NullTime
+		if err := rows.Scan(&f.Id, &f.Owner, &f.ListId, &createTime, &lastAccessTime); err != nil {
+			return nil, status.Error(codes.Unknown, "failed to retrieve field values from calendar_feed row-> "+err.Error())
+		}
+		f.Name = feedName(f.Id)
+		if f.CreateTime, err = ptypes.TimestampProto(createTime); err != nil {
+			return nil, status.Error(codes.Unknown, "create_time field has invalid format-> "+err.Error())
+		}
+		if f.LastAccessTime, err = timestampProto(lastAccessTime); err != nil {
+			return nil, status.Error(codes.Unknown, "last_access_time field has invalid format-> "+err.Error())
+		}
+		list = append(list, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to retrieve data from calendar_feed-> "+err.Error())
+	}
+
+	return list, nil
+}
+
+// Create secret calendar feed of todo tasks
+func (s *todoServiceServer) CreateCalendarFeed(ctx context.Context, req *v1.CreateCalendarFeedRequest) (*v1.CreateCalendarFeedResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	if req.Feed == nil {
+		return nil, status.Error(codes.InvalidArgument, "feed must be specified")
+	}
+	owner := strings.TrimSpace(req.Feed.Owner)
+	if len(owner) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "owner of feed must be specified")
+	}
+	if utf8.RuneCountInString(owner) > maxFeedOwnerLength {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("owner is longer than %d characters", maxFeedOwnerLength))
+	}
+
+	token, err := newFeedToken()
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to generate token-> "+err.Error())
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	if req.Feed.ListId != 0 {
+		if err := checkList(ctx, c, req.Feed.ListId); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := c.ExecContext(ctx, "INSERT INTO calendar_feed(owner, token_hash, list_id, create_time) VALUES(?,?,?,?)",
+		owner, feedTokenHash(token), req.Feed.ListId, time.Now().In(time.UTC))
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to insert into calendar_feed-> "+err.Error())
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to retrieve id for created CalendarFeed-> "+err.Error())
+	}
+
+	list, err := queryFeeds(ctx, c, "SELECT "+feedColumns+" FROM calendar_feed WHERE id=?", id)
+	if err != nil {
+		return nil, err
+	}
+	list[0].Path = CalendarPathPrefix + token + ".ics"
+
+	return &v1.CreateCalendarFeedResponse{
+		Api:  API_VERSION,
+		Feed: list[0],
+	}, nil
+}
+
+// Read all calendar feeds
+func (s *todoServiceServer) ListCalendarFeeds(ctx context.Context, req *v1.ListCalendarFeedsRequest) (*v1.ListCalendarFeedsResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	list, err := queryFeeds(ctx, c, "SELECT "+feedColumns+" FROM calendar_feed ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.ListCalendarFeedsResponse{
+		Api:   API_VERSION,
+		Feeds: list,
+	}, nil
+}
+
+// Revoke calendar feed
+func (s *todoServiceServer) DeleteCalendarFeed(ctx context.Context, req *v1.DeleteCalendarFeedRequest) (*v1.DeleteCalendarFeedResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	res, err := c.ExecContext(ctx, "DELETE FROM calendar_feed WHERE id=?", req.Id)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to delete CalendarFeed-> "+err.Error())
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to retrieve rows affected value-> "+err.Error())
+	}
+
+	if rows == 0 {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("CalendarFeed with ID='%d' is not found", req.Id))
+	}
+
+	return &v1.DeleteCalendarFeedResponse{
+		Api:     API_VERSION,
+		Deleted: rows,
+	}, nil
+}
@@ -0,0 +1,467 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/devararishivian/go-grpc/pkg/ical"
+)
+
+const (
+	// calendarName is display name of calendar feeds
+	calendarName = "Todo tasks"
+	// maxReportSize is maximum size of CalDAV REPORT request body in bytes
+	maxReportSize = 1 << 20
+
+	davNamespace    = "DAV:"
+	calDAVNamespace = "urn:ietf:params:xml:ns:caldav"
+)
+
+// CalendarHandler serves secret calendar feeds under CalendarPathPrefix.
+// "{token}.ics" is iCalendar feed for subscription in calendar apps, "{token}/" is read-only
+// CalDAV collection with task per "{token}/{id}.ics" resource. Only PROPFIND, REPORT and GET are
+// supported, which is enough for clients to sync tasks, changes are rejected
+type CalendarHandler struct {
+	db *sql.DB
+}
+
+// NewCalendarHandler returns handler of calendar feeds
+func NewCalendarHandler(db *sql.DB) *CalendarHandler {
+	return &CalendarHandler{db: db}
+}
+
+// calendarItem is task rendered for calendar feed
+type calendarItem struct {
+	href string
+	etag string
+	// data is VCALENDAR with VTODO of the task
+	data []byte
+	// vtodo is VTODO of the task
+	vtodo *ical.Component
+}
+
+func (h *CalendarHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// "{token}.ics" is feed, "{token}/" is CalDAV collection and "{token}/{id}.ics" is task in it
+	rest := strings.TrimPrefix(r.URL.Path, CalendarPathPrefix)
+	var token, resource string
+	collection := strings.Contains(rest, "/") || !strings.HasSuffix(rest, ".ics")
+	if collection {
+		parts := strings.SplitN(rest, "/", 2)
+		token = parts[0]
+		if len(parts) == 2 {
+			resource = parts[1]
+		}
+	} else {
+		token = strings.TrimSuffix(rest, ".ics")
+	}
+
+	if len(token) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	var feedID, listID int64
+	err := h.db.QueryRowContext(ctx, "SELECT id, list_id FROM calendar_feed WHERE token_hash=?", feedTokenHash(token)).Scan(&feedID, &listID)
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("failed to select from calendar_feed: %v", err)
+		http.Error(w, "failed to read calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := h.db.ExecContext(ctx, "UPDATE calendar_feed SET last_access_time=? WHERE id=?", time.Now().In(time.UTC), feedID); err != nil {
+		log.Printf("failed to update calendar_feed: %v", err)
+	}
+
+	base := CalendarPathPrefix + token + "/"
+
+	switch r.Method {
+	case http.MethodOptions:
+		w.Header().Set("DAV", "1, calendar-access")
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PROPFIND, REPORT")
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet, http.MethodHead:
+		if len(resource) > 0 {
+			h.serveItem(w, r, listID, base, resource)
+		} else {
+			h.serveFeed(w, r, listID, base)
+		}
+	case "PROPFIND":
+		if !collection {
+			w.Header().Set("Allow", "OPTIONS, GET, HEAD")
+			http.Error(w, "method is not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.propfind(w, r, listID, base, resource)
+	case "REPORT":
+		if !collection || len(resource) > 0 {
+			w.Header().Set("Allow", "OPTIONS, GET, HEAD, PROPFIND")
+			http.Error(w, "method is not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.report(w, r, listID, base)
+	default:
+		http.Error(w, "calendar feed is read-only", http.StatusForbidden)
+	}
+}
+
+// items returns rendered tasks of list, all lists if listID is 0, or the task with ID if it's not 0
+func (h *CalendarHandler) items(ctx context.Context, listID, id int64, base string) ([]*calendarItem, error) {
+	list, err := queryTodos(ctx, h.db, "SELECT "+todoColumns+" FROM todo WHERE deleted_at IS NULL AND (? = 0 OR list_id = ?) AND (? = 0 OR id = ?) ORDER BY id",
+		listID, listID, id, id)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*calendarItem, 0, len(list))
+	for _, td := range list {
+		c, err := todoComponent(td)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render Todo with ID='%d': %v", td.Id, err)
+		}
+
+		cal := feedCalendar()
+		cal.Components = append(cal.Components, c)
+		var buf bytes.Buffer
+		if err := ical.Encode(&buf, cal); err != nil {
+			return nil, err
+		}
+
+		sum := sha256.Sum256(buf.Bytes())
+		items = append(items, &calendarItem{
+			href:  base + strconv.FormatInt(td.Id, 10) + ".ics",
+			etag:  `"` + hex.EncodeToString(sum[:8]) + `"`,
+			data:  buf.Bytes(),
+			vtodo: c,
+		})
+	}
+
+	return items, nil
+}
+
+// feedCalendar returns VCALENDAR of feed without tasks
+func feedCalendar() *ical.Component {
+	c := ical.NewComponent("VCALENDAR")
+	c.Add("VERSION", "2.0")
+	c.Add("PRODID", icalProdID)
+	c.Add("X-WR-CALNAME", calendarName)
+	return c
+}
+
+// collectionTag returns tag of collection which changes when any of its tasks changes
+func collectionTag(items []*calendarItem) string {
+	h := sha256.New()
+	for _, it := range items {
+		io.WriteString(h, it.etag)
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:8]) + `"`
+}
+
+// serveFeed writes all tasks of feed as one VCALENDAR
+func (h *CalendarHandler) serveFeed(w http.ResponseWriter, r *http.Request, listID int64, base string) {
+	items, err := h.items(r.Context(), listID, 0, base)
+	if err != nil {
+		log.Printf("failed to read calendar feed: %v", err)
+		http.Error(w, "failed to read calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	cal := feedCalendar()
+	for _, it := range items {
+		cal.Components = append(cal.Components, it.vtodo)
+	}
+	var buf bytes.Buffer
+	if err := ical.Encode(&buf, cal); err != nil {
+		log.Printf("failed to render calendar feed: %v", err)
+		http.Error(w, "failed to read calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	writeCalendar(w, r, collectionTag(items), buf.Bytes())
+}
+
+// serveItem writes task of CalDAV collection
+func (h *CalendarHandler) serveItem(w http.ResponseWriter, r *http.Request, listID int64, base, resource string) {
+	id, err := strconv.ParseInt(strings.TrimSuffix(resource, ".ics"), 10, 64)
+	if err != nil || !strings.HasSuffix(resource, ".ics") || id <= 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	items, err := h.items(r.Context(), listID, id, base)
+	if err != nil {
+		log.Printf("failed to read calendar feed: %v", err)
+		http.Error(w, "failed to read calendar feed", http.StatusInternalServerError)
+		return
+	}
+	if len(items) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	writeCalendar(w, r, items[0].etag, items[0].data)
+}
+
+// writeCalendar writes iCalendar data with ETag, not modified if client has the same version
+func writeCalendar(w http.ResponseWriter, r *http.Request, etag string, data []byte) {
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(data)
+	}
+}
+
+// davMultistatus is WebDAV multi-status response
+type davMultistatus struct {
+	XMLName   xml.Name      `xml:"d:multistatus"`
+	DAV       string        `xml:"xmlns:d,attr"`
+	CalDAV    string        `xml:"xmlns:c,attr"`
+	CS        string        `xml:"xmlns:cs,attr"`
+	Responses []davResponse `xml:"d:response"`
+}
+
+type davResponse struct {
+	Href     string       `xml:"d:href"`
+	Propstat *davPropstat `xml:"d:propstat,omitempty"`
+	Status   string       `xml:"d:status,omitempty"`
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"d:prop"`
+	Status string  `xml:"d:status"`
+}
+
+type davProp struct {
+	ResourceType *davResourceType `xml:"d:resourcetype,omitempty"`
+	DisplayName  string           `xml:"d:displayname,omitempty"`
+	ContentType  string           `xml:"d:getcontenttype,omitempty"`
+	ETag         string           `xml:"d:getetag,omitempty"`
+	CTag         string           `xml:"cs:getctag,omitempty"`
+	ComponentSet *davCompSet      `xml:"c:supported-calendar-component-set,omitempty"`
+	Privileges   *davPrivileges   `xml:"d:current-user-privilege-set,omitempty"`
+	CalendarData string           `xml:"c:calendar-data,omitempty"`
+}
+
+type davResourceType struct {
+	Collection *struct{} `xml:"d:collection,omitempty"`
+	Calendar   *struct{} `xml:"c:calendar,omitempty"`
+}
+
+type davCompSet struct {
+	Comp struct {
+		Name string `xml:"name,attr"`
+	} `xml:"c:comp"`
+}
+
+type davPrivileges struct {
+	Privilege struct {
+		Read struct{} `xml:"d:read"`
+	} `xml:"d:privilege"`
+}
+
+// itemResponse returns properties of task, with calendar data if withData is set
+func itemResponse(it *calendarItem, withData bool) davResponse {
+	prop := davProp{
+		ResourceType: &davResourceType{},
+		ContentType:  "text/calendar; charset=utf-8; component=vtodo",
+		ETag:         it.etag,
+	}
+	if withData {
+		prop.CalendarData = string(it.data)
+	}
+	return davResponse{
+		Href:     it.href,
+		Propstat: &davPropstat{Prop: prop, Status: "HTTP/1.1 200 OK"},
+	}
+}
+
+// propfind writes properties of collection and its tasks or of task of collection.
+// All supported properties are returned regardless of requested ones
+func (h *CalendarHandler) propfind(w http.ResponseWriter, r *http.Request, listID int64, base, resource string) {
+	var id int64
+	if len(resource) > 0 {
+		var err error
+		id, err = strconv.ParseInt(strings.TrimSuffix(resource, ".ics"), 10, 64)
+		if err != nil || !strings.HasSuffix(resource, ".ics") || id <= 0 {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	items, err := h.items(r.Context(), listID, id, base)
+	if err != nil {
+		log.Printf("failed to read calendar feed: %v", err)
+		http.Error(w, "failed to read calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	var responses []davResponse
+	if id != 0 {
+		if len(items) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		responses = append(responses, itemResponse(items[0], false))
+	} else {
+		prop := davProp{
+			ResourceType: &davResourceType{Collection: &struct{}{}, Calendar: &struct{}{}},
+			DisplayName:  calendarName,
+			CTag:         collectionTag(items),
+			ETag:         collectionTag(items),
+			ComponentSet: &davCompSet{},
+			Privileges:   &davPrivileges{},
+		}
+		prop.ComponentSet.Comp.Name = "VTODO"
+		responses = append(responses, davResponse{
+			Href:     base,
+			Propstat: &davPropstat{Prop: prop, Status: "HTTP/1.1 200 OK"},
+		})
+
+		if r.Header.Get("Depth") != "0" {
+			for _, it := range items {
+				responses = append(responses, itemResponse(it, false))
+			}
+		}
+	}
+
+	writeMultistatus(w, responses)
+}
+
+// report answers calendar-multiget with requested tasks and calendar-query with all tasks,
+// filters of calendar-query are not applied
+func (h *CalendarHandler) report(w http.ResponseWriter, r *http.Request, listID int64, base string) {
+	root, hrefs, err := parseReport(io.LimitReader(r.Body, maxReportSize))
+	if err != nil {
+		http.Error(w, "invalid REPORT request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	items, err := h.items(r.Context(), listID, 0, base)
+	if err != nil {
+		log.Printf("failed to read calendar feed: %v", err)
+		http.Error(w, "failed to read calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	var responses []davResponse
+	switch root {
+	case "calendar-multiget":
+		byHref := map[string]*calendarItem{}
+		for _, it := range items {
+			byHref[it.href] = it
+		}
+		for _, href := range hrefs {
+			path := href
+			if u, err := url.Parse(href); err == nil {
+				path = u.Path
+			}
+			if it, ok := byHref[path]; ok {
+				responses = append(responses, itemResponse(it, true))
+			} else {
+				responses = append(responses, davResponse{Href: href, Status: "HTTP/1.1 404 Not Found"})
+			}
+		}
+	case "calendar-query":
+		for _, it := range items {
+			responses = append(responses, itemResponse(it, true))
+		}
+	default:
+		http.Error(w, fmt.Sprintf("REPORT '%s' is not supported", root), http.StatusForbidden)
+		return
+	}
+
+	writeMultistatus(w, responses)
+}
+
+// parseReport returns name of the root element of REPORT request and hrefs in it
+func parseReport(r io.Reader) (string, []string, error) {
+	var (
+		root   string
+		hrefs  []string
+		inHref bool
+		text   strings.Builder
+	)
+
+	d := xml.NewDecoder(r)
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, err
+		}
+
+		switch el := t.(type) {
+		case xml.StartElement:
+			if len(root) == 0 {
+				if el.Name.Space != calDAVNamespace {
+					return "", nil, fmt.Errorf("unknown report '%s'", el.Name.Local)
+				}
+				root = el.Name.Local
+			}
+			if el.Name.Space == davNamespace && el.Name.Local == "href" {
+				inHref = true
+				text.Reset()
+			}
+		case xml.CharData:
+			if inHref {
+				text.Write(el)
+			}
+		case xml.EndElement:
+			if inHref && el.Name.Local == "href" {
+				inHref = false
+				hrefs = append(hrefs, strings.TrimSpace(text.String()))
+			}
+		}
+	}
+
+	if len(root) == 0 {
+		return "", nil, fmt.Errorf("request body is empty")
+	}
+	return root, hrefs, nil
+}
+
+// writeMultistatus writes WebDAV multi-status response
+func writeMultistatus(w http.ResponseWriter, responses []davResponse) {
+	b, err := xml.Marshal(&davMultistatus{
+		DAV:       davNamespace,
+		CalDAV:    calDAVNamespace,
+		CS:        "http://calendarserver.org/ns/",
+		Responses: responses,
+	})
+	if err != nil {
+		log.Printf("failed to marshal multistatus: %v", err)
+		http.Error(w, "failed to read calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	_, _ = io.WriteString(w, xml.Header)
+	_, _ = w.Write(b)
+}
@@ -0,0 +1,191 @@
+package v1
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+const (
+	// feedQuery is query of tasks of calendar feed
+	feedQuery = "SELECT " + todoColumns + " FROM todo WHERE deleted_at IS NULL AND (? = 0 OR list_id = ?) AND (? = 0 OR id = ?)"
+	// testFeedToken is secret token of calendar feed of tests
+	testFeedToken = "secret"
+)
+
+// expectFeed expects lookup of feed of list by hash of its token
+func expectFeed(mock sqlmock.Sqlmock, token string, listID int64, found bool) {
+	rows := sqlmock.NewRows([]string{"id", "list_id"})
+	if found {
+		rows.AddRow(int64(7), listID)
+	}
+	mock.ExpectQuery("SELECT id, list_id FROM calendar_feed WHERE token_hash=?").WithArgs(feedTokenHash(token)).WillReturnRows(rows)
+	if found {
+		mock.ExpectExec("UPDATE calendar_feed SET last_access_time=? WHERE id=?").WithArgs(sqlmock.AnyArg(), int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+}
+
+// serveCalendar serves request of calendar handler
+func serveCalendar(s *todoServiceServer, method, path, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, CalendarPathPrefix+path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	NewCalendarHandler(s.db).ServeHTTP(w, r)
+	return w
+}
+
+// capturedArg is query argument matching any value and recording it
+type capturedArg struct {
+	value driver.Value
+}
+
+func (a *capturedArg) Match(v driver.Value) bool {
+	a.value = v
+	return true
+}
+
+func TestCreateCalendarFeed(t *testing.T) {
+	s, mock := newMock(t)
+
+	// only hash of token is stored, token is returned in path of the feed once
+	hash := &capturedArg{}
+	mock.ExpectExec("INSERT INTO calendar_feed(owner, token_hash, list_id, create_time)").
+		WithArgs("bob", hash, int64(0), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectQuery("SELECT " + feedColumns + " FROM calendar_feed WHERE id=?").WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner", "list_id", "create_time", "last_access_time"}).
+			AddRow(int64(7), "bob", int64(0), testTime, nil))
+
+	res, err := s.CreateCalendarFeed(context.Background(), &v1.CreateCalendarFeedRequest{Api: API_VERSION, Feed: &v1.CalendarFeed{Owner: " bob "}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := strings.TrimSuffix(strings.TrimPrefix(res.Feed.Path, CalendarPathPrefix), ".ics")
+	if len(token) < 32 || hash.value != feedTokenHash(token) {
+		t.Errorf("feed path = %s, stored hash %v, want hash of token of the path", res.Feed.Path, hash.value)
+	}
+}
+
+func TestCalendarFeedToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		expect   func(mock sqlmock.Sqlmock)
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:   "feed",
+			method: http.MethodGet,
+			path:   testFeedToken + ".ics",
+			expect: func(mock sqlmock.Sqlmock) {
+				expectFeed(mock, testFeedToken, 2, true)
+				expectTodos(mock, feedQuery, &v1.Todo{Id: 1, Title: "Call Bob", ListId: 2})
+			},
+			wantCode: http.StatusOK,
+			wantBody: "SUMMARY:Call Bob",
+		},
+		{
+			name:     "unknown token",
+			method:   http.MethodGet,
+			path:     "guess.ics",
+			expect:   func(mock sqlmock.Sqlmock) { expectFeed(mock, "guess", 0, false) },
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "empty token",
+			method:   http.MethodGet,
+			path:     ".ics",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "collection is read-only",
+			method:   http.MethodPut,
+			path:     testFeedToken + "/1.ics",
+			expect:   func(mock sqlmock.Sqlmock) { expectFeed(mock, testFeedToken, 0, true) },
+			wantCode: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newMock(t)
+			if tt.expect != nil {
+				tt.expect(mock)
+			}
+
+			w := serveCalendar(s, tt.method, tt.path, "")
+			if w.Code != tt.wantCode || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("%s %s = %d %s, want %d with %q", tt.method, tt.path, w.Code, w.Body, tt.wantCode, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCalendarReport(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantCode  int
+		want      []string
+		wantNotIn []string
+	}{
+		{
+			name: "multiget",
+			body: `<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">` +
+				`<d:prop><d:getetag/><c:calendar-data/></d:prop>` +
+				`<d:href>/calendar/secret/1.ics</d:href><d:href>https://example.com/calendar/secret/9.ics</d:href>` +
+				`</c:calendar-multiget>`,
+			wantCode:  http.StatusMultiStatus,
+			want:      []string{"<d:href>/calendar/secret/1.ics</d:href>", "SUMMARY:Call Bob", "HTTP/1.1 404 Not Found"},
+			wantNotIn: []string{"SUMMARY:Buy milk"},
+		},
+		{
+			name: "query returns all tasks",
+			body: `<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">` +
+				`<c:filter><c:comp-filter name="VCALENDAR"/></c:filter></c:calendar-query>`,
+			wantCode: http.StatusMultiStatus,
+			want:     []string{"SUMMARY:Call Bob", "SUMMARY:Buy milk"},
+		},
+		{
+			name:     "unsupported report",
+			body:     `<c:free-busy-query xmlns:c="urn:ietf:params:xml:ns:caldav"/>`,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "not CalDAV report",
+			body:     `<d:sync-collection xmlns:d="DAV:"/>`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newMock(t)
+			expectFeed(mock, testFeedToken, 2, true)
+			if tt.wantCode != http.StatusBadRequest {
+				expectTodos(mock, feedQuery, &v1.Todo{Id: 1, Title: "Call Bob", ListId: 2}, &v1.Todo{Id: 2, Title: "Buy milk", ListId: 2})
+			}
+
+			w := serveCalendar(s, "REPORT", testFeedToken+"/", tt.body)
+			if w.Code != tt.wantCode {
+				t.Fatalf("REPORT = %d %s, want %d", w.Code, w.Body, tt.wantCode)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(w.Body.String(), s) {
+					t.Errorf("REPORT = %s, want %q in it", w.Body, s)
+				}
+			}
+			for _, s := range tt.wantNotIn {
+				if strings.Contains(w.Body.String(), s) {
+					t.Errorf("REPORT = %s, want no %q in it", w.Body, s)
+				}
+			}
+		})
+	}
+}
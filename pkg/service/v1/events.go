@@ -0,0 +1,251 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// watchBatchSize is maximum number of events read by watcher at once
+	watchBatchSize = 100
+	// watchPollInterval is how often watchers check for events written by other server instances
+	watchPollInterval = 5 * time.Second
+)
+
+// broker wakes up watchers when new events are committed
+type broker struct {
+	mu   sync.Mutex
+	subs map[chan struct{}]struct{}
+}
+
+func newBroker() *broker {
+	return &broker{subs: map[chan struct{}]struct{}{}}
+}
+
+// subscribe returns channel which receives a value after new events are committed
+func (b *broker) subscribe() chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan struct{}, 1)
+	b.subs[ch] = struct{}{}
+	return ch
+}
+
+func (b *broker) unsubscribe(ch chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.subs, ch)
+}
+
+// notify wakes up all watchers, watcher which is not waiting is woken up once later
+func (b *broker) notify() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// emit records events of type for todo tasks in the transaction of the change.
+// Event sequence row stays locked until commit, so events are committed in the order of their IDs
+// and watcher reading events after the last seen ID never misses one
+func emit(ctx context.Context, tx *sql.Tx, typ v1.TodoEvent_Type, ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var next int64
+	if err := tx.QueryRowContext(ctx, "SELECT next_id FROM todo_event_seq WHERE id=1 FOR UPDATE").Scan(&next); err != nil {
+		return status.Error(codes.Unknown, "failed to select from todo_event_seq-> "+err.Error())
+	}
+
+	list, err := queryTodos(ctx, tx, "SELECT "+todoColumns+" FROM todo WHERE id IN "+inClause(len(ids))+" ORDER BY id", int64Args(ids)...)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().In(time.UTC)
+	for _, td := range list {
+		data, err := proto.Marshal(td)
+		if err != nil {
+			return status.Error(codes.Unknown, "failed to encode Todo-> "+err.Error())
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO todo_event(id, type, todo_id, list_id, todo, create_time) VALUES(?,?,?,?,?,?)",
+			next, int32(typ), td.Id, td.ListId, data, now); err != nil {
+			return status.Error(codes.Unknown, "failed to insert into todo_event-> "+err.Error())
+		}
+		next++
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE todo_event_seq SET next_id=? WHERE id=1", next); err != nil {
+		return status.Error(codes.Unknown, "failed to update todo_event_seq-> "+err.Error())
+	}
+
+	return nil
+}
+
+// PurgeEvents permanently removes change events recorded before the given time
+// which were relayed by all outbox sinks and fanned out to webhooks if any is registered,
+// resume tokens of removed events are expired. Outbox sink which hasn't moved since idleBefore
+// is disabled or stuck, it doesn't keep events any more
+func PurgeEvents(ctx context.Context, db *sql.DB, before, idleBefore time.Time) (int64, error) {
+	// Events after the slowest cursor are kept until they are relayed
+	var relayed sql.NullInt64
+	if err := db.QueryRowContext(ctx, "SELECT MIN(last_event_id) FROM (SELECT last_event_id FROM outbox_cursor WHERE update_time >= ? "+
+		"UNION ALL SELECT last_event_id FROM webhook_cursor WHERE EXISTS (SELECT 1 FROM webhook)) c", idleBefore).Scan(&relayed); err != nil {
+		return 0, err
+	}
+
+	query := "DELETE FROM todo_event WHERE create_time < ?"
+	args := []interface{}{before}
+	if relayed.Valid {
+		query += " AND id <= ?"
+		args = append(args, relayed.Int64)
+	}
+
+	res, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// resumeToken returns resume token of event, the token is opaque for clients
+func resumeToken(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+// parseResumeToken returns ID of event resume token was issued for
+func parseResumeToken(token string) (int64, error) {
+	id, err := strconv.ParseInt(token, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, status.Error(codes.InvalidArgument, fmt.Sprintf("resume_token '%s' is invalid", token))
+	}
+	return id, nil
+}
+
+// queryEvents returns events after event ID and ID of the last returned event,
+// only events of list if it is not 0
+func queryEvents(ctx context.Context, c queryer, after, listID int64) ([]*v1.TodoEvent, int64, error) {
+	rows, err := c.QueryContext(ctx, "SELECT id, type, todo, create_time FROM todo_event WHERE id > ? AND (? = 0 OR list_id = ?) ORDER BY id LIMIT ?",
+		after, listID, listID, watchBatchSize)
+	if err != nil {
+		return nil, 0, status.Error(codes.Unknown, "failed to select from todo_event-> "+err.Error())
+	}
+	defer rows.Close()
+
+	list := []*v1.TodoEvent{}
+	for rows.Next() {
+		var id int64
+		var typ int32
+		var data []byte
+		var createTime time.Time
+		if err := rows.Scan(&id, &typ, &data, &createTime); err != nil {
+			return nil, 0, status.Error(codes.Unknown, "failed to retrieve field values from todo_event row-> "+err.Error())
+		}
+
+		ev := &v1.TodoEvent{
+			Type:        v1.TodoEvent_Type(typ),
+			Todo:        new(v1.Todo),
+			ResumeToken: resumeToken(id),
+		}
+		if err := proto.Unmarshal(data, ev.Todo); err != nil {
+			return nil, 0, status.Error(codes.Unknown, "todo field of todo_event has invalid format-> "+err.Error())
+		}
+		if ev.EventTime, err = ptypes.TimestampProto(createTime); err != nil {
+			return nil, 0, status.Error(codes.Unknown, "create_time field has invalid format-> "+err.Error())
+		}
+		list = append(list, ev)
+		after = id
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, status.Error(codes.Unknown, "failed to retrieve data from todo_event-> "+err.Error())
+	}
+
+	return list, after, nil
+}
+
+// Stream changes of todo tasks
+func (s *todoServiceServer) WatchTodos(req *v1.WatchTodosRequest, stream v1.TodoService_WatchTodosServer) error {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+
+	// Subscribe before the start point is read, so no commit is missed
+	wake := s.events.subscribe()
+	defer s.events.unsubscribe(wake)
+
+	// Start after the resumed event or after the last event if the watch is new
+	var last int64
+	if len(req.ResumeToken) > 0 {
+		id, err := parseResumeToken(req.ResumeToken)
+		if err != nil {
+			return err
+		}
+
+		var found int
+		err = s.db.QueryRowContext(ctx, "SELECT 1 FROM todo_event WHERE id=?", id).Scan(&found)
+		if err == sql.ErrNoRows {
+			return status.Error(codes.OutOfRange, "resume_token is expired, read todo tasks again and start new watch")
+		}
+		if err != nil {
+			return status.Error(codes.Unknown, "failed to select from todo_event-> "+err.Error())
+		}
+		last = id
+	} else if err := s.db.QueryRowContext(ctx, "SELECT next_id - 1 FROM todo_event_seq WHERE id=1").Scan(&last); err != nil {
+		return status.Error(codes.Unknown, "failed to select from todo_event_seq-> "+err.Error())
+	}
+
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+
+	for {
+		list, next, err := queryEvents(ctx, s.db, last, req.ListId)
+		if err != nil {
+			return err
+		}
+
+		for _, ev := range list {
+			if err := stream.Send(&v1.WatchTodosResponse{
+				Api:   API_VERSION,
+				Event: ev,
+			}); err != nil {
+				return err
+			}
+		}
+		last = next
+
+		// Read the rest without waiting if the batch is full
+		if len(list) == watchBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package v1
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPurgeEvents(t *testing.T) {
+	before := testTime.Add(-24 * time.Hour)
+	idleBefore := testTime.Add(-7 * 24 * time.Hour)
+
+	tests := []struct {
+		name    string
+		relayed interface{}
+		query   string
+		args    []driver.Value
+	}{
+		{
+			name:    "no sinks",
+			relayed: nil,
+			query:   "DELETE FROM todo_event WHERE create_time < ?",
+			args:    []driver.Value{before},
+		},
+		{
+			name:    "kept until relayed",
+			relayed: int64(42),
+			query:   "DELETE FROM todo_event WHERE create_time < ? AND id <= ?",
+			args:    []driver.Value{before, int64(42)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newMock(t)
+
+			// cursors of sinks which haven't moved since idleBefore don't keep events
+			mock.ExpectQuery("SELECT MIN(last_event_id) FROM (SELECT last_event_id FROM outbox_cursor WHERE update_time >= ? UNION ALL " +
+				"SELECT last_event_id FROM webhook_cursor WHERE EXISTS (SELECT 1 FROM webhook)) c").
+				WithArgs(idleBefore).WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(tt.relayed))
+			mock.ExpectExec(tt.query).WithArgs(tt.args...).WillReturnResult(sqlmock.NewResult(0, 3))
+
+			purged, err := PurgeEvents(context.Background(), s.db, before, idleBefore)
+			if err != nil {
+				t.Fatalf("PurgeEvents() error = %v", err)
+			}
+			if purged != 3 {
+				t.Errorf("PurgeEvents() = %d, want 3", purged)
+			}
+		})
+	}
+}
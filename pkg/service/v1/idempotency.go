@@ -0,0 +1,114 @@
+package v1
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// DefaultIdempotencyWindow is how long idempotency keys are kept if server is not configured
+	DefaultIdempotencyWindow = 24 * time.Hour
+
+	// maxRequestIDLength is maximum length of idempotency key
+	maxRequestIDLength = 128
+)
+
+// requestID returns idempotency key of Create request, request_id field has priority over metadata
+func requestID(ctx context.Context, req *v1.CreateRequest) (string, error) {
+	id := req.RequestId
+	if len(id) == 0 {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if v := md.Get(v1.IdempotencyKeyHeader); len(v) > 0 {
+				id = v[0]
+			}
+		}
+	}
+
+	if len(id) > maxRequestIDLength {
+		return "", status.Error(codes.InvalidArgument, fmt.Sprintf("request_id can't be longer than %d characters", maxRequestIDLength))
+	}
+
+	return id, nil
+}
+
+// requestHash returns hash of task in request to detect reuse of idempotency key with different task
+func requestHash(td *v1.Todo) (string, error) {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(td)
+	if err != nil {
+		return "", status.Error(codes.Internal, "failed to marshal todo-> "+err.Error())
+	}
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// claimRequestID reserves idempotency key in transaction. It returns ID of the task created
+// for the key within the window or 0 if the task has to be created. Concurrent request with
+// the same key waits until transaction of the first one ends
+func claimRequestID(ctx context.Context, tx *sql.Tx, key, hash string, window time.Duration) (int64, error) {
+	now := time.Now().In(time.UTC)
+
+	res, err := tx.ExecContext(ctx, "INSERT IGNORE INTO idempotency_key(request_id, request_hash, todo_id, create_time) VALUES(?, ?, 0, ?)",
+		key, hash, now)
+	if err != nil {
+		return 0, status.Error(codes.Unknown, "failed to insert into idempotency_key-> "+err.Error())
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return 0, status.Error(codes.Unknown, "failed to retrieve rows affected value-> "+err.Error())
+	} else if n > 0 {
+		return 0, nil
+	}
+
+	var (
+		storedHash string
+		todoID     int64
+		created    time.Time
+	)
+	if err := tx.QueryRowContext(ctx, "SELECT request_hash, todo_id, create_time FROM idempotency_key WHERE request_id=? FOR UPDATE",
+		key).Scan(&storedHash, &todoID, &created); err != nil {
+		return 0, status.Error(codes.Unknown, "failed to select from idempotency_key-> "+err.Error())
+	}
+
+	// key is expired, it's reused for the new task
+	if created.Before(now.Add(-window)) {
+		if _, err := tx.ExecContext(ctx, "UPDATE idempotency_key SET request_hash=?, todo_id=0, create_time=? WHERE request_id=?",
+			hash, now, key); err != nil {
+			return 0, status.Error(codes.Unknown, "failed to update idempotency_key-> "+err.Error())
+		}
+		return 0, nil
+	}
+
+	if storedHash != hash {
+		return 0, status.Error(codes.InvalidArgument, fmt.Sprintf("request_id='%s' was already used with different todo", key))
+	}
+
+	return todoID, nil
+}
+
+// completeRequestID stores ID of the task created for idempotency key
+func completeRequestID(ctx context.Context, tx *sql.Tx, key string, todoID int64) error {
+	if _, err := tx.ExecContext(ctx, "UPDATE idempotency_key SET todo_id=? WHERE request_id=?", todoID, key); err != nil {
+		return status.Error(codes.Unknown, "failed to update idempotency_key-> "+err.Error())
+	}
+	return nil
+}
+
+// PurgeIdempotencyKeys permanently removes idempotency keys stored before the given time
+func PurgeIdempotencyKeys(ctx context.Context, db *sql.DB, before time.Time) (int64, error) {
+	res, err := db.ExecContext(ctx, "DELETE FROM idempotency_key WHERE create_time < ?", before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,107 @@
+package v1
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		field    string
+		header   string
+		want     string
+		wantCode codes.Code
+	}{
+		{name: "none"},
+		{name: "field", field: "a", want: "a"},
+		{name: "header", header: "b", want: "b"},
+		{name: "field has priority", field: "a", header: "b", want: "a"},
+		{name: "too long", field: strings.Repeat("a", maxRequestIDLength+1), wantCode: codes.InvalidArgument},
+		{name: "too long header", header: strings.Repeat("b", maxRequestIDLength+1), wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if len(tt.header) > 0 {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(v1.IdempotencyKeyHeader, tt.header))
+			}
+
+			got, err := requestID(ctx, &v1.CreateRequest{RequestId: tt.field})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("requestID() error = %v, want code %s", err, tt.wantCode)
+			}
+			if got != tt.want {
+				t.Errorf("requestID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClaimRequestID(t *testing.T) {
+	const window = time.Hour
+	now := time.Now().In(time.UTC)
+	storedColumns := []string{"request_hash", "todo_id", "create_time"}
+
+	tests := []struct {
+		name     string
+		stored   []driver.Value
+		expect   func(mock sqlmock.Sqlmock)
+		want     int64
+		wantCode codes.Code
+	}{
+		{name: "new key"},
+		{name: "repeated request", stored: []driver.Value{"hash", 7, now.Add(-time.Minute)}, want: 7},
+		{name: "reused with different task", stored: []driver.Value{"other", 7, now.Add(-time.Minute)}, wantCode: codes.InvalidArgument},
+		{
+			name:   "expired key",
+			stored: []driver.Value{"other", 7, now.Add(-2 * window)},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("UPDATE idempotency_key SET request_hash=?, todo_id=0").WithArgs("hash", sqlmock.AnyArg(), "key").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newMock(t)
+
+			mock.ExpectBegin()
+			claimed := int64(1)
+			if tt.stored != nil {
+				claimed = 0
+			}
+			mock.ExpectExec("INSERT IGNORE INTO idempotency_key").WithArgs("key", "hash", sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, claimed))
+			if tt.stored != nil {
+				mock.ExpectQuery("SELECT request_hash, todo_id, create_time FROM idempotency_key WHERE request_id=? FOR UPDATE").
+					WithArgs("key").WillReturnRows(sqlmock.NewRows(storedColumns).AddRow(tt.stored...))
+			}
+			if tt.expect != nil {
+				tt.expect(mock)
+			}
+
+			tx, err := s.db.Begin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := claimRequestID(context.Background(), tx, "key", "hash", window)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("claimRequestID() error = %v, want code %s", err, tt.wantCode)
+			}
+			if got != tt.want {
+				t.Errorf("claimRequestID() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
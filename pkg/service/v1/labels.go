@@ -0,0 +1,258 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// maxLabelLength is maximum length of label in characters
+	maxLabelLength = 64
+)
+
+// normalizeLabel trims label and folds its case, labels differing in case only are one label
+// as MySQL compares them case-insensitively
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.TrimSpace(label))
+}
+
+// normalizeLabels normalizes labels, drops duplicates and checks they are valid
+func normalizeLabels(labels []string) ([]string, error) {
+	seen := map[string]bool{}
+	list := []string{}
+	for _, l := range labels {
+		l = normalizeLabel(l)
+		if len(l) == 0 {
+			return nil, status.Error(codes.InvalidArgument, "label can't be empty")
+		}
+		if utf8.RuneCountInString(l) > maxLabelLength {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("label '%s' is longer than %d characters", l, maxLabelLength))
+		}
+		if !seen[l] {
+			seen[l] = true
+			list = append(list, l)
+		}
+	}
+
+	return list, nil
+}
+
+// loadLabels reads labels of todo tasks
+func loadLabels(ctx context.Context, c queryer, todos []*v1.Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*v1.Todo, len(todos))
+	args := make([]interface{}, 0, len(todos))
+	for _, td := range todos {
+		td.Labels = []string{}
+		byID[td.Id] = td
+		args = append(args, td.Id)
+	}
+
+	rows, err := c.QueryContext(ctx, "SELECT todo_id, label FROM todo_label WHERE todo_id IN (?"+
+		strings.Repeat(",?", len(args)-1)+") ORDER BY label", args...)
+	if err != nil {
+		return status.Error(codes.Unknown, "failed to select from todo_label-> "+err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var label string
+		if err := rows.Scan(&id, &label); err != nil {
+			return status.Error(codes.Unknown, "failed to retrieve field values from todo_label row-> "+err.Error())
+		}
+		if td, ok := byID[id]; ok {
+			td.Labels = append(td.Labels, label)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return status.Error(codes.Unknown, "failed to retrieve data from todo_label-> "+err.Error())
+	}
+
+	return nil
+}
+
+// addLabels adds labels to todo task skipping labels it already has
+func addLabels(ctx context.Context, c queryer, id int64, labels []string) error {
+	for _, l := range labels {
+		if _, err := c.ExecContext(ctx, "INSERT IGNORE INTO todo_label(todo_id, label) VALUES(?,?)", id, l); err != nil {
+			return status.Error(codes.Unknown, "failed to insert into todo_label-> "+err.Error())
+		}
+	}
+
+	return nil
+}
+
+// replaceLabels replaces labels of todo task in transaction if labels differ from old ones
+func replaceLabels(ctx context.Context, c queryer, id int64, old, labels []string) error {
+	labels, err := normalizeLabels(labels)
+	if err != nil {
+		return err
+	}
+
+	has := map[string]bool{}
+	for _, l := range old {
+		has[normalizeLabel(l)] = true
+	}
+	same := len(labels) == len(has)
+	for _, l := range labels {
+		same = same && has[l]
+	}
+	if same {
+		return nil
+	}
+
+	if _, err := c.ExecContext(ctx, "DELETE FROM todo_label WHERE todo_id=?", id); err != nil {
+		return status.Error(codes.Unknown, "failed to delete from todo_label-> "+err.Error())
+	}
+	return addLabels(ctx, c, id, labels)
+}
+
+// changeLabels adds or removes labels of todo task and returns the task after change
+func (s *todoServiceServer) changeLabels(ctx context.Context, id int64, labels []string, remove bool) (*v1.Todo, error) {
+	labels, err := normalizeLabels(labels)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	tx, err := c.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to start transaction-> "+err.Error())
+	}
+	defer tx.Rollback()
+
+	// Touch the task first, it locks the row and checks the task exists
+	res, err := tx.ExecContext(ctx, "UPDATE todo SET update_time=? WHERE id=? AND deleted_at IS NULL", time.Now().In(time.UTC), id)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to update Todo-> "+err.Error())
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to retrieve rows affected value-> "+err.Error())
+	}
+
+	if rows == 0 {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("Todo with ID='%d' is not found", id))
+	}
+
+	if remove {
+		for _, l := range labels {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM todo_label WHERE todo_id=? AND label=?", id, l); err != nil {
+				return nil, status.Error(codes.Unknown, "failed to delete from todo_label-> "+err.Error())
+			}
+		}
+	} else if err := addLabels(ctx, tx, id, labels); err != nil {
+		return nil, err
+	}
+
+	if err := emit(ctx, tx, v1.TodoEvent_UPDATED, id); err != nil {
+		return nil, err
+	}
+
+	list, err := queryTodos(ctx, tx, "SELECT "+todoColumns+" FROM todo WHERE id=?", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to commit transaction-> "+err.Error())
+	}
+	s.events.notify()
+
+	return list[0], nil
+}
+
+// Add labels to todo task
+func (s *todoServiceServer) AddLabels(ctx context.Context, req *v1.AddLabelsRequest) (*v1.AddLabelsResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	td, err := s.changeLabels(ctx, req.Id, req.Labels, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.AddLabelsResponse{
+		Api:  API_VERSION,
+		Todo: td,
+	}, nil
+}
+
+// Remove labels from todo task
+func (s *todoServiceServer) RemoveLabels(ctx context.Context, req *v1.RemoveLabelsRequest) (*v1.RemoveLabelsResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	td, err := s.changeLabels(ctx, req.Id, req.Labels, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.RemoveLabelsResponse{
+		Api:  API_VERSION,
+		Todo: td,
+	}, nil
+}
+
+// Read all labels with number of todo tasks having them
+func (s *todoServiceServer) ListLabels(ctx context.Context, req *v1.ListLabelsRequest) (*v1.ListLabelsResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	rows, err := c.QueryContext(ctx, "SELECT l.label, COUNT(*) FROM todo_label l JOIN todo t ON t.id = l.todo_id "+
+		"WHERE t.deleted_at IS NULL GROUP BY l.label ORDER BY l.label")
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to select from todo_label-> "+err.Error())
+	}
+	defer rows.Close()
+
+	list := []*v1.LabelCount{}
+	for rows.Next() {
+		lc := new(v1.LabelCount)
+		if err := rows.Scan(&lc.Label, &lc.Count); err != nil {
+			return nil, status.Error(codes.Unknown, "failed to retrieve field values from todo_label row-> "+err.Error())
+		}
+		list = append(list, lc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to retrieve data from todo_label-> "+err.Error())
+	}
+
+	return &v1.ListLabelsResponse{
+		Api:    API_VERSION,
+		Labels: list,
+	}, nil
+}
@@ -0,0 +1,43 @@
+package v1
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+	"github.com/devararishivian/go-grpc/pkg/filter"
+)
+
+func TestNormalizeLabels(t *testing.T) {
+	// MySQL compares labels case-insensitively, so labels differing in case only are one label
+	got, err := normalizeLabels([]string{"Work", "work", " WORK ", "home"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"work", "home"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("normalizeLabels() = %v, want %v", got, want)
+	}
+
+	if _, err := normalizeLabels([]string{" "}); err == nil {
+		t.Error("normalizeLabels() error = nil for empty label")
+	}
+}
+
+func TestLabelFilters(t *testing.T) {
+	// all labels condition counts distinct labels, so it matches the task having "work" label
+	_, args, err := filterClause(&v1.TodoFilter{LabelsAll: []string{"Work", "work"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{"work", 1}; !reflect.DeepEqual(args, want) {
+		t.Errorf("filterClause() args = %v, want %v", args, want)
+	}
+
+	_, args, err = filter.Parse(`labels: " Work" OR labels != "HOME"`, todoFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{"work", "home"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("filter.Parse() args = %v, want %v", args, want)
+	}
+}
@@ -0,0 +1,423 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultListID is ID of the list todo tasks are created in if no list is specified.
+	// It is created by the schema migration and can't be deleted
+	DefaultListID = 1
+)
+
+// todoName returns resource name of todo task
+func todoName(listID, id int64) string {
+	return fmt.Sprintf("lists/%d/todos/%d", listID, id)
+}
+
+// listName returns resource name of list
+func listName(id int64) string {
+	return fmt.Sprintf("lists/%d", id)
+}
+
+// checkList checks if list exists
+func checkList(ctx context.Context, c queryer, id int64) error {
+	rows, err := c.QueryContext(ctx, "SELECT id FROM todo_list WHERE id=?", id)
+	if err != nil {
+		return status.Error(codes.Unknown, "failed to select from todo_list-> "+err.Error())
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return status.Error(codes.Unknown, "failed to retrieve data from todo_list-> "+err.Error())
+		}
+		return status.Error(codes.NotFound, fmt.Sprintf("TodoList with ID='%d' is not found", id))
+	}
+
+	return nil
+}
+
+// queryLists returns lists selected by condition with number of not deleted todo tasks
+func queryLists(ctx context.Context, c queryer, where string, args ...interface{}) ([]*v1.TodoList, error) {
+	rows, err := c.QueryContext(ctx, "SELECT l.id, l.title, l.create_time, l.update_time, "+
+		"(SELECT COUNT(*) FROM todo t WHERE t.list_id = l.id AND t.deleted_at IS NULL) "+
+		"FROM todo_list l"+where+" ORDER BY l.id", args...)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to select from todo_list-> "+err.Error())
+	}
+	defer rows.Close()
+
+	list := []*v1.TodoList{}
+	for rows.Next() {
+		tl := new(v1.TodoList)
+		var createTime, updateTime time.Time
+		if err := rows.Scan(&tl.Id, &tl.Title, &createTime, &updateTime, &tl.TodoCount); err != nil {
+			return nil, status.Error(codes.Unknown, "failed to retrieve field values from todo_list row-> "+err.Error())
+		}
+		tl.Name = listName(tl.Id)
+		if tl.CreateTime, err = ptypes.TimestampProto(createTime); err != nil {
+			return nil, status.Error(codes.Unknown, "create_time field has invalid format-> "+err.Error())
+		}
+		if tl.UpdateTime, err = ptypes.TimestampProto(updateTime); err != nil {
+			return nil, status.Error(codes.Unknown, "update_time field has invalid format-> "+err.Error())
+		}
+		list = append(list, tl)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to retrieve data from todo_list-> "+err.Error())
+	}
+
+	return list, nil
+}
+
+// checkListTitle checks title of list is valid
+func checkListTitle(title string) error {
+	if len(strings.TrimSpace(title)) == 0 {
+		return status.Error(codes.InvalidArgument, "title of list can't be empty")
+	}
+	return nil
+}
+
+// Create new list of todo tasks
+func (s *todoServiceServer) CreateList(ctx context.Context, req *v1.CreateListRequest) (*v1.CreateListResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	if req.List == nil {
+		return nil, status.Error(codes.InvalidArgument, "list field is required")
+	}
+	if err := checkListTitle(req.List.Title); err != nil {
+		return nil, err
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	// Insert TodoList entity data
+	now := time.Now().In(time.UTC)
+	res, err := c.ExecContext(ctx, "INSERT INTO todo_list(title, create_time, update_time) VALUES(?,?,?)",
+		req.List.Title, now, now)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to insert into todo_list-> "+err.Error())
+	}
+
+	// Get ID of created TodoList
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to retrieve id for created TodoList-> "+err.Error())
+	}
+
+	return &v1.CreateListResponse{
+		Api: API_VERSION,
+		Id:  id,
+	}, nil
+}
+
+// Read list of todo tasks
+func (s *todoServiceServer) GetList(ctx context.Context, req *v1.GetListRequest) (*v1.GetListResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	list, err := queryLists(ctx, c, " WHERE l.id = ?", req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("TodoList with ID='%d' is not found", req.Id))
+	}
+
+	return &v1.GetListResponse{
+		Api:  API_VERSION,
+		List: list[0],
+	}, nil
+}
+
+// Read all lists of todo tasks
+func (s *todoServiceServer) ListLists(ctx context.Context, req *v1.ListListsRequest) (*v1.ListListsResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	list, err := queryLists(ctx, c, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.ListListsResponse{
+		Api:   API_VERSION,
+		Lists: list,
+	}, nil
+}
+
+// Rename list of todo tasks
+func (s *todoServiceServer) RenameList(ctx context.Context, req *v1.RenameListRequest) (*v1.RenameListResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	if err := checkListTitle(req.Title); err != nil {
+		return nil, err
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	// Update TodoList
+	res, err := c.ExecContext(ctx, "UPDATE todo_list SET title=?, update_time=? WHERE id=?",
+		req.Title, time.Now().In(time.UTC), req.Id)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to update TodoList-> "+err.Error())
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to retrieve rows affected value-> "+err.Error())
+	}
+
+	if rows == 0 {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("TodoList with ID='%d' is not found", req.Id))
+	}
+
+	return &v1.RenameListResponse{
+		Api:     API_VERSION,
+		Updated: rows,
+	}, nil
+}
+
+// Delete list of todo tasks.
+// Not deleted tasks of the list are moved to another list or deleted with cascade.
+// Deleted tasks of the list are moved to the target or default list, so they can still be restored
+func (s *todoServiceServer) DeleteList(ctx context.Context, req *v1.DeleteListRequest) (*v1.DeleteListResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	if req.Id == DefaultListID {
+		return nil, status.Error(codes.FailedPrecondition, "default list can't be deleted")
+	}
+	if req.Cascade && req.MoveToListId != 0 {
+		return nil, status.Error(codes.InvalidArgument, "cascade and move_to_list_id can't be specified together")
+	}
+	if req.MoveToListId == req.Id {
+		return nil, status.Error(codes.InvalidArgument, "todo tasks can't be moved to the list being deleted")
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	tx, err := c.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to start transaction-> "+err.Error())
+	}
+	defer tx.Rollback()
+
+	// Lock the list, so no tasks are added to it while it is being deleted
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM todo_list WHERE id=? FOR UPDATE", req.Id)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to select from todo_list-> "+err.Error())
+	}
+	found := rows.Next()
+	rows.Close()
+	if !found {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("TodoList with ID='%d' is not found", req.Id))
+	}
+
+	target := int64(DefaultListID)
+	if req.MoveToListId != 0 {
+		if err := checkList(ctx, tx, req.MoveToListId); err != nil {
+			return nil, err
+		}
+		target = req.MoveToListId
+	}
+
+	// Without cascade or target list only deleted tasks can be left in the list
+	if !req.Cascade && req.MoveToListId == 0 {
+		var count int64
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM todo WHERE list_id=? AND deleted_at IS NULL", req.Id).Scan(&count); err != nil {
+			return nil, status.Error(codes.Unknown, "failed to select from todo-> "+err.Error())
+		}
+		if count > 0 {
+			return nil, status.Error(codes.FailedPrecondition, fmt.Sprintf("TodoList with ID='%d' has %d todo tasks, use cascade or move_to_list_id", req.Id, count))
+		}
+	}
+
+	// Tasks of the list are read before change for change events
+	ids, err := queryIDs(ctx, tx, "SELECT id FROM todo WHERE list_id=? AND deleted_at IS NULL", req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(time.UTC)
+	var deletedTodos, movedTodos int64
+	if req.Cascade {
+		res, err := tx.ExecContext(ctx, "UPDATE todo SET deleted_at=? WHERE list_id=? AND deleted_at IS NULL", now, req.Id)
+		if err != nil {
+			return nil, status.Error(codes.Unknown, "failed to delete Todo-> "+err.Error())
+		}
+		if deletedTodos, err = res.RowsAffected(); err != nil {
+			return nil, status.Error(codes.Unknown, "failed to retrieve rows affected value-> "+err.Error())
+		}
+		if err := emit(ctx, tx, v1.TodoEvent_DELETED, ids...); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.MoveToListId != 0 {
+		res, err := tx.ExecContext(ctx, "UPDATE todo SET list_id=?, update_time=? WHERE list_id=? AND deleted_at IS NULL", target, now, req.Id)
+		if err != nil {
+			return nil, status.Error(codes.Unknown, "failed to move Todo-> "+err.Error())
+		}
+		if movedTodos, err = res.RowsAffected(); err != nil {
+			return nil, status.Error(codes.Unknown, "failed to retrieve rows affected value-> "+err.Error())
+		}
+		if err := emit(ctx, tx, v1.TodoEvent_UPDATED, ids...); err != nil {
+			return nil, err
+		}
+	}
+
+	// Keep deleted tasks restorable in the target list
+	if _, err := tx.ExecContext(ctx, "UPDATE todo SET list_id=? WHERE list_id=?", target, req.Id); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to move Todo-> "+err.Error())
+	}
+
+	// Delete TodoList
+	res, err := tx.ExecContext(ctx, "DELETE FROM todo_list WHERE id=?", req.Id)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to delete TodoList-> "+err.Error())
+	}
+
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to retrieve rows affected value-> "+err.Error())
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to commit transaction-> "+err.Error())
+	}
+	s.events.notify()
+
+	return &v1.DeleteListResponse{
+		Api:          API_VERSION,
+		Deleted:      deleted,
+		DeletedTodos: deletedTodos,
+		MovedTodos:   movedTodos,
+	}, nil
+}
+
+// Move todo task to another list
+func (s *todoServiceServer) MoveTodo(ctx context.Context, req *v1.MoveTodoRequest) (*v1.MoveTodoResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	tx, err := c.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to start transaction-> "+err.Error())
+	}
+	defer tx.Rollback()
+
+	if err := checkList(ctx, tx, req.ToListId); err != nil {
+		return nil, err
+	}
+
+	// Move Todo, current list ID is checked only if specified.
+	// Subtask becomes top-level task in the new list
+	now := time.Now().In(time.UTC)
+	res, err := tx.ExecContext(ctx, "UPDATE todo SET list_id=?, parent_id=0, update_time=? WHERE id=? AND (? = 0 OR list_id = ?) AND deleted_at IS NULL",
+		req.ToListId, now, req.Id, req.ListId, req.ListId)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to move Todo-> "+err.Error())
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to retrieve rows affected value-> "+err.Error())
+	}
+
+	if rows == 0 {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("Todo with ID='%d' is not found", req.Id))
+	}
+
+	// Subtasks are moved with the task
+	ids, _, err := descendants(ctx, tx, req.Id, "1=1")
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 {
+		if _, err := tx.ExecContext(ctx, "UPDATE todo SET list_id=?, update_time=? WHERE id IN "+inClause(len(ids)),
+			append([]interface{}{req.ToListId, now}, int64Args(ids)...)...); err != nil {
+			return nil, status.Error(codes.Unknown, "failed to move Todo-> "+err.Error())
+		}
+	}
+
+	if err := emit(ctx, tx, v1.TodoEvent_UPDATED, append(ids, req.Id)...); err != nil {
+		return nil, err
+	}
+
+	list, err := queryTodos(ctx, tx, "SELECT "+todoColumns+" FROM todo WHERE id=?", req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to commit transaction-> "+err.Error())
+	}
+	s.events.notify()
+
+	return &v1.MoveTodoResponse{
+		Api:  API_VERSION,
+		Todo: list[0],
+	}, nil
+}
@@ -0,0 +1,153 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+// expectLockList expects the list to be locked
+func expectLockList(mock sqlmock.Sqlmock, listID int64) {
+	mock.ExpectQuery("SELECT id FROM todo_list WHERE id=? FOR UPDATE").WithArgs(listID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(listID))
+}
+
+// idRows returns rows of IDs
+func idRows(ids ...int64) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id"})
+	for _, id := range ids {
+		rows.AddRow(id)
+	}
+	return rows
+}
+
+func TestDeleteList(t *testing.T) {
+	tasks := []*v1.Todo{{Id: 5, ListId: 3}, {Id: 6, ListId: 3}}
+
+	tests := []struct {
+		name     string
+		req      *v1.DeleteListRequest
+		expect   func(mock sqlmock.Sqlmock)
+		want     *v1.DeleteListResponse
+		wantCode codes.Code
+	}{
+		{name: "default list", req: &v1.DeleteListRequest{Id: DefaultListID}, wantCode: codes.FailedPrecondition},
+		{name: "cascade and move", req: &v1.DeleteListRequest{Id: 3, Cascade: true, MoveToListId: 4}, wantCode: codes.InvalidArgument},
+		{name: "move to itself", req: &v1.DeleteListRequest{Id: 3, MoveToListId: 3}, wantCode: codes.InvalidArgument},
+		{
+			name: "not found",
+			req:  &v1.DeleteListRequest{Id: 3},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT id FROM todo_list WHERE id=? FOR UPDATE").WithArgs(int64(3)).WillReturnRows(idRows())
+				mock.ExpectRollback()
+			},
+			wantCode: codes.NotFound,
+		},
+		{
+			name: "list with tasks",
+			req:  &v1.DeleteListRequest{Id: 3},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				expectLockList(mock, 3)
+				mock.ExpectQuery("SELECT COUNT(*) FROM todo WHERE list_id=? AND deleted_at IS NULL").WithArgs(int64(3)).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+				mock.ExpectRollback()
+			},
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name: "cascade",
+			req:  &v1.DeleteListRequest{Id: 3, Cascade: true},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				expectLockList(mock, 3)
+				mock.ExpectQuery("SELECT id FROM todo WHERE list_id=? AND deleted_at IS NULL").WithArgs(int64(3)).WillReturnRows(idRows(5, 6))
+				mock.ExpectExec("UPDATE todo SET deleted_at=? WHERE list_id=? AND deleted_at IS NULL").WithArgs(sqlmock.AnyArg(), int64(3)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				expectEmit(mock, v1.TodoEvent_DELETED, tasks...)
+				// deleted tasks stay restorable in the default list
+				mock.ExpectExec("UPDATE todo SET list_id=? WHERE list_id=?").WithArgs(int64(DefaultListID), int64(3)).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("DELETE FROM todo_list WHERE id=?").WithArgs(int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			want: &v1.DeleteListResponse{Deleted: 1, DeletedTodos: 2},
+		},
+		{
+			name: "move tasks",
+			req:  &v1.DeleteListRequest{Id: 3, MoveToListId: 4},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				expectLockList(mock, 3)
+				mock.ExpectQuery("SELECT id FROM todo_list WHERE id=?").WithArgs(int64(4)).WillReturnRows(idRows(4))
+				mock.ExpectQuery("SELECT id FROM todo WHERE list_id=? AND deleted_at IS NULL").WithArgs(int64(3)).WillReturnRows(idRows(5, 6))
+				mock.ExpectExec("UPDATE todo SET list_id=?, update_time=? WHERE list_id=? AND deleted_at IS NULL").
+					WithArgs(int64(4), sqlmock.AnyArg(), int64(3)).WillReturnResult(sqlmock.NewResult(0, 2))
+				expectEmit(mock, v1.TodoEvent_UPDATED, &v1.Todo{Id: 5, ListId: 4}, &v1.Todo{Id: 6, ListId: 4})
+				mock.ExpectExec("UPDATE todo SET list_id=? WHERE list_id=?").WithArgs(int64(4), int64(3)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("DELETE FROM todo_list WHERE id=?").WithArgs(int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			want: &v1.DeleteListResponse{Deleted: 1, MovedTodos: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newMock(t)
+			if tt.expect != nil {
+				tt.expect(mock)
+			}
+
+			tt.req.Api = API_VERSION
+			got, err := s.DeleteList(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("DeleteList() error = %v, want code %s", err, tt.wantCode)
+			}
+			if tt.want != nil && (got.Deleted != tt.want.Deleted || got.DeletedTodos != tt.want.DeletedTodos || got.MovedTodos != tt.want.MovedTodos) {
+				t.Errorf("DeleteList() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoveTodo(t *testing.T) {
+	s, mock := newMock(t)
+
+	// subtask becomes top-level task of the new list, its subtasks move with it
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM todo_list WHERE id=?").WithArgs(int64(4)).WillReturnRows(idRows(4))
+	mock.ExpectExec("UPDATE todo SET list_id=?, parent_id=0, update_time=? WHERE id=? AND (? = 0 OR list_id = ?) AND deleted_at IS NULL").
+		WithArgs(int64(4), sqlmock.AnyArg(), int64(1), int64(3), int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT id FROM todo WHERE parent_id IN (?)").WithArgs(int64(1)).WillReturnRows(idRows(2))
+	mock.ExpectQuery("SELECT id FROM todo WHERE parent_id IN (?)").WithArgs(int64(2)).WillReturnRows(idRows())
+	mock.ExpectExec("UPDATE todo SET list_id=?, update_time=? WHERE id IN (?)").WithArgs(int64(4), sqlmock.AnyArg(), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectEmit(mock, v1.TodoEvent_UPDATED, &v1.Todo{Id: 2, ListId: 4, ParentId: 1}, &v1.Todo{Id: 1, ListId: 4})
+	expectTodos(mock, "SELECT "+todoColumns+" FROM todo WHERE id=?", &v1.Todo{Id: 1, ListId: 4})
+	mock.ExpectCommit()
+
+	res, err := s.MoveTodo(context.Background(), &v1.MoveTodoRequest{Api: API_VERSION, Id: 1, ListId: 3, ToListId: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Todo.ListId != 4 || res.Todo.ParentId != 0 {
+		t.Errorf("MoveTodo() = %v, want top-level task of list 4", res.Todo)
+	}
+
+	// task of another list isn't moved
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM todo_list WHERE id=?").WithArgs(int64(4)).WillReturnRows(idRows(4))
+	mock.ExpectExec("UPDATE todo SET list_id=?, parent_id=0").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	if _, err := s.MoveTodo(context.Background(), &v1.MoveTodoRequest{Api: API_VERSION, Id: 1, ListId: 5, ToListId: 4}); status.Code(err) != codes.NotFound {
+		t.Errorf("MoveTodo() error = %v, want code %s", err, codes.NotFound)
+	}
+}
@@ -0,0 +1,93 @@
+package v1
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+// testTime is time tasks of tests are created at
+var testTime = time.Date(2021, 10, 1, 9, 0, 0, 0, time.UTC)
+
+// newMock returns database mock matching queries by their literal prefix
+func newMock(t *testing.T) (*todoServiceServer, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherFunc(func(expected, actual string) error {
+		if !strings.HasPrefix(actual, expected) {
+			return errMismatch{expected: expected, actual: actual}
+		}
+		return nil
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	return &todoServiceServer{db: db, events: newBroker(), idempotencyWindow: DefaultIdempotencyWindow}, mock
+}
+
+// errMismatch is error of query which isn't expected
+type errMismatch struct {
+	expected, actual string
+}
+
+func (e errMismatch) Error() string {
+	return "query '" + e.actual + "' doesn't start with '" + e.expected + "'"
+}
+
+// nullableTime returns database value of optional timestamp
+func nullableTime(ts *timestamp.Timestamp) driver.Value {
+	if ts == nil {
+		return nil
+	}
+	t, _ := ptypes.Timestamp(ts)
+	return t
+}
+
+// todoRows returns rows of todoColumns with tasks
+func todoRows(todos ...*v1.Todo) *sqlmock.Rows {
+	rows := sqlmock.NewRows(strings.Split(strings.ReplaceAll(todoColumns, " ", ""), ","))
+	for _, td := range todos {
+		reminder := nullableTime(td.Reminder)
+		if reminder == nil {
+			reminder = testTime
+		}
+		rows.AddRow(td.Id, td.Title, td.Description, reminder, nullableTime(td.DeletedAt),
+			int32(td.Status), nullableTime(td.CompletedAt), nullableTime(td.DueDate), int32(td.Priority), testTime, testTime,
+			td.ListId, td.ParentId, td.Recurrence, td.TimeZone, nullableTime(td.RecurrenceStart), nullableTime(td.SnoozedUntil))
+	}
+	return rows
+}
+
+// expectTodos expects query of tasks followed by queries of their labels and progress
+func expectTodos(mock sqlmock.Sqlmock, query string, todos ...*v1.Todo) {
+	mock.ExpectQuery(query).WillReturnRows(todoRows(todos...))
+	if len(todos) == 0 {
+		return
+	}
+	mock.ExpectQuery("SELECT todo_id, label FROM todo_label").WillReturnRows(sqlmock.NewRows([]string{"todo_id", "label"}))
+	mock.ExpectQuery("SELECT parent_id, SUM(status = ?), COUNT(*) FROM todo").
+		WillReturnRows(sqlmock.NewRows([]string{"parent_id", "done", "total"}))
+}
+
+// expectEmit expects change events of tasks to be recorded
+func expectEmit(mock sqlmock.Sqlmock, typ v1.TodoEvent_Type, todos ...*v1.Todo) {
+	mock.ExpectQuery("SELECT next_id FROM todo_event_seq").WillReturnRows(sqlmock.NewRows([]string{"next_id"}).AddRow(1))
+	expectTodos(mock, "SELECT "+todoColumns+" FROM todo WHERE id IN", todos...)
+	for _, td := range todos {
+		mock.ExpectExec("INSERT INTO todo_event").WithArgs(sqlmock.AnyArg(), int32(typ), td.Id, td.ListId, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectExec("UPDATE todo_event_seq").WillReturnResult(sqlmock.NewResult(0, 1))
+}
@@ -0,0 +1,123 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/devararishivian/go-grpc/pkg/outbox"
+)
+
+// OutboxRelay publishes change events from outbox to sink.
+// Events are written to todo_event table by emit in the transaction of the change,
+// so committed change always has its event. Relay keeps position of sink by name in outbox_cursor table
+// and moves it after event is published, so every event is published at least once.
+// Position which doesn't move for cursor timeout of RunPurge stops keeping events from purge,
+// so disabled or stuck sink skips events purged meanwhile
+type OutboxRelay struct {
+	db   *sql.DB
+	name string
+	sink outbox.Sink
+
+	// Interval is how often new events are checked
+	Interval time.Duration
+}
+
+// NewOutboxRelay returns relay of events to sink, the name keeps position of the sink
+// and must not change between restarts. New sink starts with events recorded after it is first run
+func NewOutboxRelay(db *sql.DB, name string, sink outbox.Sink) *OutboxRelay {
+	return &OutboxRelay{
+		db:       db,
+		name:     name,
+		sink:     sink,
+		Interval: time.Second,
+	}
+}
+
+// Run relays events every Interval until context is done
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := r.Relay(ctx); err != nil {
+			log.Printf("failed to relay todo events to '%s': %v", r.name, err)
+		} else if n > 0 {
+			log.Printf("relayed %d todo events to '%s'", n, r.name)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Relay publishes all events recorded after the position of sink and returns number of published events.
+// It stops at the first event sink fails to publish, the event is published again by the next call
+func (r *OutboxRelay) Relay(ctx context.Context) (int, error) {
+	if _, err := r.db.ExecContext(ctx, "INSERT IGNORE INTO outbox_cursor(name, last_event_id, update_time) "+
+		"SELECT ?, next_id - 1, ? FROM todo_event_seq WHERE id=1", r.name, time.Now().In(time.UTC)); err != nil {
+		return 0, fmt.Errorf("failed to insert into outbox_cursor: %v", err)
+	}
+
+	total := 0
+	for {
+		n, err := r.relayBatch(ctx)
+		total += n
+		if err != nil || n < watchBatchSize {
+			return total, err
+		}
+	}
+}
+
+// relayBatch publishes the next batch of events and returns number of published events
+func (r *OutboxRelay) relayBatch(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the position, so events are relayed to sink by one of server instances
+	var last int64
+	if err := tx.QueryRowContext(ctx, "SELECT last_event_id FROM outbox_cursor WHERE name=? FOR UPDATE", r.name).Scan(&last); err != nil {
+		return 0, fmt.Errorf("failed to select from outbox_cursor: %v", err)
+	}
+
+	events, _, err := queryEvents(ctx, tx, last, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	var publishErr error
+	for _, ev := range events {
+		if publishErr = r.sink.Publish(ctx, ev); publishErr != nil {
+			break
+		}
+		if last, err = parseResumeToken(ev.ResumeToken); err != nil {
+			return 0, err
+		}
+		published++
+	}
+
+	if published > 0 {
+		if _, err := tx.ExecContext(ctx, "UPDATE outbox_cursor SET last_event_id=?, update_time=? WHERE name=?",
+			last, time.Now().In(time.UTC), r.name); err != nil {
+			return 0, fmt.Errorf("failed to update outbox_cursor: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return 0, fmt.Errorf("failed to commit transaction: %v", err)
+		}
+	}
+
+	if publishErr != nil {
+		return published, fmt.Errorf("failed to publish event: %v", publishErr)
+	}
+
+	return published, nil
+}
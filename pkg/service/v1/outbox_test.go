@@ -0,0 +1,153 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"google.golang.org/protobuf/proto"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+// recordingSink records IDs of published tasks and fails to publish task in fail
+type recordingSink struct {
+	fail      int64
+	published []int64
+}
+
+func (s *recordingSink) Publish(ctx context.Context, ev *v1.TodoEvent) error {
+	if ev.Todo.Id == s.fail {
+		return errors.New("unavailable")
+	}
+	s.published = append(s.published, ev.Todo.Id)
+	return nil
+}
+
+// expectRelayBatch expects batch of events after the position last, event ID is ID of its task
+func expectRelayBatch(t *testing.T, mock sqlmock.Sqlmock, last int64, ids ...int64) {
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT last_event_id FROM outbox_cursor WHERE name=? FOR UPDATE").WithArgs("test").
+		WillReturnRows(sqlmock.NewRows([]string{"last_event_id"}).AddRow(last))
+	rows := sqlmock.NewRows([]string{"id", "type", "todo", "create_time"})
+	for _, id := range ids {
+		data, err := proto.Marshal(&v1.Todo{Id: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.AddRow(id, int32(v1.TodoEvent_UPDATED), data, testTime)
+	}
+	mock.ExpectQuery("SELECT id, type, todo, create_time FROM todo_event WHERE id > ?").
+		WithArgs(last, int64(0), int64(0), watchBatchSize).WillReturnRows(rows)
+}
+
+// expectCursor expects position of sink to be moved to last
+func expectCursor(mock sqlmock.Sqlmock, last int64) {
+	mock.ExpectExec("UPDATE outbox_cursor SET last_event_id=?, update_time=? WHERE name=?").
+		WithArgs(last, sqlmock.AnyArg(), "test").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func TestOutboxRelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		fail     int64
+		expect   func(mock sqlmock.Sqlmock)
+		want     []int64
+		wantErr  bool
+		wantLast int64
+	}{
+		{
+			name: "published in order",
+			expect: func(mock sqlmock.Sqlmock) {
+				expectRelayBatch(t, mock, 4, 5, 6, 7)
+				expectCursor(mock, 7)
+			},
+			want: []int64{5, 6, 7},
+		},
+		{
+			name: "cursor moved to event before failed one",
+			fail: 6,
+			expect: func(mock sqlmock.Sqlmock) {
+				expectRelayBatch(t, mock, 4, 5, 6, 7)
+				expectCursor(mock, 5)
+			},
+			want:    []int64{5},
+			wantErr: true,
+		},
+		{
+			name: "cursor kept if the first event fails",
+			fail: 5,
+			expect: func(mock sqlmock.Sqlmock) {
+				expectRelayBatch(t, mock, 4, 5, 6)
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name: "nothing to relay",
+			expect: func(mock sqlmock.Sqlmock) {
+				expectRelayBatch(t, mock, 7)
+				mock.ExpectRollback()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newMock(t)
+			sink := &recordingSink{fail: tt.fail}
+			r := NewOutboxRelay(s.db, "test", sink)
+
+			mock.ExpectExec("INSERT IGNORE INTO outbox_cursor").WithArgs("test", sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			tt.expect(mock)
+
+			n, err := r.Relay(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Relay() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if n != len(tt.want) || !equalIDs(sink.published, tt.want) {
+				t.Errorf("Relay() = %d, published %v, want %v", n, sink.published, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutboxRelayRetry(t *testing.T) {
+	s, mock := newMock(t)
+	sink := &recordingSink{fail: 6}
+	r := NewOutboxRelay(s.db, "test", sink)
+
+	// the first run stops at failed event, the next one publishes it again from the saved position
+	mock.ExpectExec("INSERT IGNORE INTO outbox_cursor").WillReturnResult(sqlmock.NewResult(0, 1))
+	expectRelayBatch(t, mock, 4, 5, 6, 7)
+	expectCursor(mock, 5)
+	mock.ExpectExec("INSERT IGNORE INTO outbox_cursor").WillReturnResult(sqlmock.NewResult(0, 0))
+	expectRelayBatch(t, mock, 5, 6, 7)
+	expectCursor(mock, 7)
+
+	if _, err := r.Relay(context.Background()); err == nil {
+		t.Fatal("Relay() error = nil, want failure of sink")
+	}
+	sink.fail = 0
+	if _, err := r.Relay(context.Background()); err != nil {
+		t.Fatalf("Relay() error = %v", err)
+	}
+	if want := []int64{5, 6, 7}; !equalIDs(sink.published, want) {
+		t.Errorf("published %v, want %v", sink.published, want)
+	}
+}
+
+// equalIDs reports whether lists of IDs are equal
+func equalIDs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package v1
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// maxPageSize is maximum number of tasks in page of ListTodos
+	maxPageSize = 1000
+
+	// pageTokenLength is length of decoded page token: offset and hash of the query
+	pageTokenLength = 8 + 8
+)
+
+// queryHash returns short hash of filter and sort order to check page token is used with the same query
+func queryHash(filter, orderBy string) []byte {
+	sum := sha256.Sum256([]byte(filter + "\x00" + orderBy))
+	return sum[:8]
+}
+
+// pageToken returns opaque token of page starting at offset
+func pageToken(offset int64, filter, orderBy string) string {
+	b := make([]byte, pageTokenLength)
+	binary.BigEndian.PutUint64(b, uint64(offset))
+	copy(b[8:], queryHash(filter, orderBy))
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// parsePageToken returns offset of page, 0 for empty token
+func parsePageToken(token, filter, orderBy string) (int64, error) {
+	if len(token) == 0 {
+		return 0, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(b) != pageTokenLength {
+		return 0, status.Error(codes.InvalidArgument, "page_token field is invalid")
+	}
+	if string(b[8:]) != string(queryHash(filter, orderBy)) {
+		return 0, status.Error(codes.InvalidArgument, "page_token field doesn't match filter and order_by")
+	}
+	offset := binary.BigEndian.Uint64(b)
+	if offset > 1<<62 {
+		return 0, status.Error(codes.InvalidArgument, "page_token field is invalid")
+	}
+	return int64(offset), nil
+}
+
+// pageClause returns LIMIT clause of page, one more row is read to find out if there is the next page
+func pageClause(pageSize int32, offset int64) (string, error) {
+	if pageSize < 0 {
+		return "", status.Error(codes.InvalidArgument, "page_size field can't be negative")
+	}
+	if pageSize == 0 {
+		if offset > 0 {
+			return "", status.Error(codes.InvalidArgument, "page_size field is required with page_token")
+		}
+		return "", nil
+	}
+	return fmt.Sprintf(" LIMIT %d OFFSET %d", pageSize+1, offset), nil
+}
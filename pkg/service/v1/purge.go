@@ -0,0 +1,57 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// Purge permanently removes todo tasks deleted before the given time
+func Purge(ctx context.Context, db *sql.DB, before time.Time) (int64, error) {
+	res, err := db.ExecContext(ctx, "DELETE FROM todo WHERE deleted_at IS NOT NULL AND deleted_at < ?", before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// RunPurge permanently removes todo tasks deleted more than retention ago,
+// relayed change events recorded more than eventRetention ago and idempotency keys stored more than idempotencyWindow ago,
+// outbox sinks which haven't relayed events for cursorTimeout don't keep events.
+// It checks every interval until context is done
+func RunPurge(ctx context.Context, db *sql.DB, retention, eventRetention, cursorTimeout, idempotencyWindow, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now().In(time.UTC)
+		purged, err := Purge(ctx, db, now.Add(-retention))
+		if err != nil {
+			log.Printf("failed to purge deleted todo tasks: %v", err)
+		} else if purged > 0 {
+			log.Printf("purged %d deleted todo tasks", purged)
+		}
+
+		purged, err = PurgeEvents(ctx, db, now.Add(-eventRetention), now.Add(-cursorTimeout))
+		if err != nil {
+			log.Printf("failed to purge todo change events: %v", err)
+		} else if purged > 0 {
+			log.Printf("purged %d todo change events", purged)
+		}
+
+		purged, err = PurgeIdempotencyKeys(ctx, db, now.Add(-idempotencyWindow))
+		if err != nil {
+			log.Printf("failed to purge idempotency keys: %v", err)
+		} else if purged > 0 {
+			log.Printf("purged %d idempotency keys", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+	"github.com/devararishivian/go-grpc/pkg/rrule"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// defaultOccurrencesLimit is number of ListOccurrences results if client doesn't ask for
+	defaultOccurrencesLimit = 20
+	// maxOccurrencesLimit is maximum number of ListOccurrences results
+	maxOccurrencesLimit = 1000
+)
+
+// checkRecurrence parses recurrence rule and time zone of todo task,
+// empty rule returns nil rule and empty time zone is UTC
+func checkRecurrence(recurrence, timeZone string) (*rrule.Rule, *time.Location, error) {
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, "time_zone field is invalid-> "+err.Error())
+	}
+
+	if len(recurrence) == 0 {
+		return nil, loc, nil
+	}
+
+	rule, err := rrule.Parse(recurrence)
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, "recurrence field is invalid-> "+err.Error())
+	}
+
+	return rule, loc, nil
+}
+
+// checkRecurringTodo checks recurrence rule and time zone of todo task,
+// recurring task must have reminder which starts its series
+func checkRecurringTodo(td *v1.Todo, reminder sql.NullTime) error {
+	if _, _, err := checkRecurrence(td.Recurrence, td.TimeZone); err != nil {
+		return err
+	}
+	if len(td.Recurrence) > 0 && !reminder.Valid {
+		return status.Error(codes.InvalidArgument, "reminder field is required for recurring Todo")
+	}
+	return nil
+}
+
+// series returns recurrence rule of todo task and start of its series in the task time zone,
+// nil rule if the task is not recurring
+func series(td *v1.Todo) (*rrule.Rule, time.Time, error) {
+	if len(td.Recurrence) == 0 || td.RecurrenceStart == nil {
+		return nil, time.Time{}, nil
+	}
+
+	// stored rule was valid when saved, error here means broken data
+	rule, loc, err := checkRecurrence(td.Recurrence, td.TimeZone)
+	if err != nil {
+		return nil, time.Time{}, status.Error(codes.Unknown, fmt.Sprintf("Todo with ID='%d' has invalid recurrence-> ", td.Id)+err.Error())
+	}
+
+	start, err := ptypes.Timestamp(td.RecurrenceStart)
+	if err != nil {
+		return nil, time.Time{}, status.Error(codes.Unknown, "recurrence_start field has invalid format-> "+err.Error())
+	}
+
+	return rule, start.In(loc), nil
+}
+
+// createNextOccurrence creates copy of recurring todo task reminded at the next occurrence
+// after its reminder. Due date is moved by the same time as the reminder.
+// It returns nil if the task is not recurring or the series is over
+func createNextOccurrence(ctx context.Context, c queryer, td *v1.Todo, now time.Time) (*v1.Todo, error) {
+	rule, start, err := series(td)
+	if err != nil || rule == nil || td.Reminder == nil {
+		return nil, err
+	}
+
+	reminder, err := ptypes.Timestamp(td.Reminder)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "reminder field has invalid format-> "+err.Error())
+	}
+
+	next, ok := rule.After(start, reminder)
+	if !ok {
+		return nil, nil
+	}
+	next = next.In(time.UTC)
+
+	dueDate, err := nullTime(td.DueDate)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "due_date field has invalid format-> "+err.Error())
+	}
+	if dueDate.Valid {
+		dueDate.Time = dueDate.Time.Add(next.Sub(reminder))
+	}
+
+	res, err := c.ExecContext(ctx, "INSERT INTO todo(title, description, reminder, status, due_date, priority, create_time, update_time, list_id, parent_id, "+
+		"recurrence, time_zone, recurrence_start) SELECT title, description, ?, ?, ?, priority, ?, ?, list_id, parent_id, "+
+		"recurrence, time_zone, recurrence_start FROM todo WHERE id=?",
+		next, int32(v1.Todo_OPEN), dueDate, now, now, td.Id)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to insert into todo-> "+err.Error())
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to retrieve id for created Todo-> "+err.Error())
+	}
+
+	if _, err := c.ExecContext(ctx, "INSERT INTO todo_label(todo_id, label) SELECT ?, label FROM todo_label WHERE todo_id=?", id, td.Id); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to insert into todo_label-> "+err.Error())
+	}
+
+	list, err := queryTodos(ctx, c, "SELECT "+todoColumns+" FROM todo WHERE id=?", id)
+	if err != nil {
+		return nil, err
+	}
+
+	return list[0], nil
+}
+
+// Read upcoming occurrences of recurring todo task
+func (s *todoServiceServer) ListOccurrences(ctx context.Context, req *v1.ListOccurrencesRequest) (*v1.ListOccurrencesResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	from := time.Now().In(time.UTC)
+	if req.Start != nil {
+		t, err := ptypes.Timestamp(req.Start)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "start field has invalid format-> "+err.Error())
+		}
+		from = t
+	}
+
+	// zero end time means no bound
+	var to time.Time
+	if req.End != nil {
+		t, err := ptypes.Timestamp(req.End)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "end field has invalid format-> "+err.Error())
+		}
+		if !t.After(from) {
+			return nil, status.Error(codes.InvalidArgument, "end must be after start")
+		}
+		to = t
+	}
+
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultOccurrencesLimit
+	}
+	if limit > maxOccurrencesLimit {
+		limit = maxOccurrencesLimit
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	list, err := queryTodos(ctx, c, "SELECT "+todoColumns+" FROM todo WHERE id=? AND deleted_at IS NULL", req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("Todo with ID='%d' is not found", req.Id))
+	}
+
+	rule, start, err := series(list[0])
+	if err != nil {
+		return nil, err
+	}
+	if rule == nil {
+		return nil, status.Error(codes.FailedPrecondition, fmt.Sprintf("Todo with ID='%d' is not recurring", req.Id))
+	}
+
+	occurrences := []*timestamp.Timestamp{}
+	for _, t := range rule.Between(start, from, to, limit) {
+		ts, err := ptypes.TimestampProto(t)
+		if err != nil {
+			return nil, status.Error(codes.Unknown, "occurrence has invalid format-> "+err.Error())
+		}
+		occurrences = append(occurrences, ts)
+	}
+
+	return &v1.ListOccurrencesResponse{
+		Api:         API_VERSION,
+		Occurrences: occurrences,
+	}, nil
+}
@@ -0,0 +1,63 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/protobuf/ptypes"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+func TestUpdateDoneCreatesNextOccurrence(t *testing.T) {
+	reminder, _ := ptypes.TimestampProto(testTime)
+	next, _ := ptypes.TimestampProto(testTime.AddDate(0, 0, 1))
+	recurring := func(st v1.Todo_Status) *v1.Todo {
+		return &v1.Todo{Id: 1, Title: "stand-up", Reminder: reminder, Status: st, ListId: 2,
+			Recurrence: "FREQ=DAILY", TimeZone: "UTC", RecurrenceStart: reminder}
+	}
+
+	tests := []struct {
+		name     string
+		stored   v1.Todo_Status
+		update   v1.Todo_Status
+		wantNext bool
+	}{
+		{name: "open to done", stored: v1.Todo_OPEN, update: v1.Todo_DONE, wantNext: true},
+		{name: "done stays done", stored: v1.Todo_DONE, update: v1.Todo_DONE},
+		{name: "open to in progress", stored: v1.Todo_OPEN, update: v1.Todo_IN_PROGRESS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newMock(t)
+
+			mock.ExpectBegin()
+			if tt.update == v1.Todo_DONE {
+				expectTodos(mock, "SELECT "+todoColumns+" FROM todo WHERE id=? AND deleted_at IS NULL FOR UPDATE", recurring(tt.stored))
+			}
+			mock.ExpectExec("UPDATE todo SET title=?").WillReturnResult(sqlmock.NewResult(0, 1))
+			expectEmit(mock, v1.TodoEvent_UPDATED, recurring(tt.update))
+			if tt.wantNext {
+				expectTodos(mock, "SELECT "+todoColumns+" FROM todo WHERE id=?", recurring(tt.update))
+				mock.ExpectExec("INSERT INTO todo(title").
+					WithArgs(testTime.AddDate(0, 0, 1), int32(v1.Todo_OPEN), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1)).
+					WillReturnResult(sqlmock.NewResult(3, 1))
+				mock.ExpectExec("INSERT INTO todo_label").WithArgs(int64(3), int64(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+				occurrence := &v1.Todo{Id: 3, Reminder: next, Status: v1.Todo_OPEN, ListId: 2,
+					Recurrence: "FREQ=DAILY", TimeZone: "UTC", RecurrenceStart: reminder}
+				expectTodos(mock, "SELECT "+todoColumns+" FROM todo WHERE id=?", occurrence)
+				expectEmit(mock, v1.TodoEvent_CREATED, occurrence)
+			}
+			mock.ExpectCommit()
+
+			res, err := s.Update(context.Background(), &v1.UpdateRequest{Todo: recurring(tt.update)})
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if res.Updated != 1 {
+				t.Errorf("Update() updated = %d, want 1", res.Updated)
+			}
+		})
+	}
+}
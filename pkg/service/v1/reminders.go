@@ -0,0 +1,90 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Postpone reminder of todo task, the reminder fires again at the snoozed time
+func (s *todoServiceServer) SnoozeReminder(ctx context.Context, req *v1.SnoozeReminderRequest) (*v1.SnoozeReminderResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(time.UTC)
+	var until time.Time
+	switch {
+	case req.Until != nil:
+		t, err := ptypes.Timestamp(req.Until)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "until field has invalid format-> "+err.Error())
+		}
+		until = t
+	case req.Duration != nil:
+		d, err := ptypes.Duration(req.Duration)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "duration field has invalid format-> "+err.Error())
+		}
+		until = now.Add(d)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "duration or until must be specified")
+	}
+
+	if !until.After(now) {
+		return nil, status.Error(codes.InvalidArgument, "reminder can be snoozed to the future only")
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	tx, err := c.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to start transaction-> "+err.Error())
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "UPDATE todo SET snoozed_until=?, update_time=? WHERE id=? AND deleted_at IS NULL",
+		until, now, req.Id)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to update Todo-> "+err.Error())
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to retrieve rows affected value-> "+err.Error())
+	}
+
+	if rows == 0 {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("Todo with ID='%d' is not found", req.Id))
+	}
+
+	if err := emit(ctx, tx, v1.TodoEvent_UPDATED, req.Id); err != nil {
+		return nil, err
+	}
+
+	list, err := queryTodos(ctx, tx, "SELECT "+todoColumns+" FROM todo WHERE id=?", req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to commit transaction-> "+err.Error())
+	}
+	s.events.notify()
+
+	return &v1.SnoozeReminderResponse{
+		Api:  API_VERSION,
+		Todo: list[0],
+	}, nil
+}
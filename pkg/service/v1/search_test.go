@@ -0,0 +1,58 @@
+package v1
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+func TestSearch(t *testing.T) {
+	s, mock := newMock(t)
+
+	// rows are ranked by database, results keep its order with scores and snippets
+	rows := sqlmock.NewRows(append(strings.Split(strings.ReplaceAll(todoColumns, " ", ""), ","), "score"))
+	for _, r := range []struct {
+		id                 int64
+		title, description string
+		score              float64
+	}{
+		{2, "Call Bob", "about lunch with bob", 1.5},
+		{1, "Buy milk", "and call Bob back", 0.5},
+	} {
+		rows.AddRow(r.id, r.title, r.description, nil, nil, int32(v1.Todo_OPEN), nil, nil, int32(0), testTime, testTime,
+			int64(1), int64(0), "", "", nil, nil, r.score)
+	}
+	// operators of the query are not passed to full-text search
+	mock.ExpectQuery("SELECT "+todoColumns+", MATCH(title, description) AGAINST(? IN BOOLEAN MODE) AS score").
+		WithArgs("+bob* +call*", "+bob* +call*", int32(defaultSearchLimit)).WillReturnRows(rows)
+	mock.ExpectQuery("SELECT todo_id, label FROM todo_label").WillReturnRows(sqlmock.NewRows([]string{"todo_id", "label"}))
+	mock.ExpectQuery("SELECT parent_id, SUM(status = ?), COUNT(*) FROM todo").
+		WillReturnRows(sqlmock.NewRows([]string{"parent_id", "done", "total"}))
+
+	res, err := s.Search(context.Background(), &v1.SearchRequest{Api: API_VERSION, Query: `+bob* -"call"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Results) != 2 || res.Results[0].Todo.Id != 2 || res.Results[0].Score != 1.5 || res.Results[1].Todo.Id != 1 {
+		t.Fatalf("Search() = %v, want tasks 2 and 1 in order of score", res.Results)
+	}
+	if got, want := res.Results[0].TitleSnippet, "<em>Call</em> <em>Bob</em>"; got != want {
+		t.Errorf("title snippet = %q, want %q", got, want)
+	}
+	if got, want := res.Results[1].DescriptionSnippet, "and <em>call</em> <em>Bob</em> back"; got != want {
+		t.Errorf("description snippet = %q, want %q", got, want)
+	}
+}
+
+func TestSearchWithoutWords(t *testing.T) {
+	s, _ := newMock(t)
+	if _, err := s.Search(context.Background(), &v1.SearchRequest{Api: API_VERSION, Query: `%_ "*"`}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Search() error = %v, want code %s", err, codes.InvalidArgument)
+	}
+}
@@ -4,40 +4,316 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+	"github.com/devararishivian/go-grpc/pkg/filter"
+	"github.com/devararishivian/go-grpc/pkg/search"
 	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 const (
-	API_VERSION = "v1"
+	API_VERSION = v1.APIVersion
+
+	// defaultSearchLimit is number of Search results if client doesn't ask for
+	defaultSearchLimit = 20
+	// maxSearchLimit is maximum number of Search results
+	maxSearchLimit = 100
+	// snippetWidth is approximate length of Search snippets in characters
+	snippetWidth = 80
 )
 
 // todoServiceServer is implementation of v1.TodoServiceServer proto interface
 type todoServiceServer struct {
 	db *sql.DB
+	// events wakes up WatchTodos streams on changes
+	events *broker
+	// idempotencyWindow is how long Create returns the same task for retries with the same request_id
+	idempotencyWindow time.Duration
 	v1.UnimplementedTodoServiceServer
 }
 
-func NewTodoServiceServer(db *sql.DB) v1.TodoServiceServer {
-	return &todoServiceServer{db: db}
+// TodoServer is Todo service with storage operations shared with other API versions
+type TodoServer interface {
+	v1.TodoServiceServer
+
+	// ListTodosInList reads tasks of list matched by filter of request, the list is a separate
+	// condition so the filter can't widen it. List ID 0 is any list
+	ListTodosInList(ctx context.Context, listID int64, req *v1.ListTodosRequest) (*v1.ListTodosResponse, error)
+
+	// UpdateTodo locks todo task and passes it to change, then writes fields, labels and parent
+	// set by change in the same transaction, so concurrent updates don't interleave.
+	// The task is not found if list ID isn't 0 and the task is in another list
+	UpdateTodo(ctx context.Context, listID, id int64, change func(td *v1.Todo) error) (*v1.Todo, error)
+
+	// DeleteTodo deletes todo task of list with its subtasks and returns number of deleted tasks,
+	// the task is locked while it's checked it's in the list
+	DeleteTodo(ctx context.Context, listID, id int64) (int64, error)
+
+	// CompleteTodo marks todo task of list as done, the task is locked while it's checked it's in the list
+	CompleteTodo(ctx context.Context, listID, id int64) (*v1.CompleteResponse, error)
+}
+
+// NewTodoServiceServer returns Todo service, idempotencyWindow is how long idempotency keys of Create are kept
+func NewTodoServiceServer(db *sql.DB, idempotencyWindow time.Duration) TodoServer {
+	if idempotencyWindow <= 0 {
+		idempotencyWindow = DefaultIdempotencyWindow
+	}
+	return &todoServiceServer{db: db, events: newBroker(), idempotencyWindow: idempotencyWindow}
 }
 
 // checkAPI checks if the API version requested by client is supported by server
 func (s *todoServiceServer) checkAPI(api string) error {
+	return checkAPI(api)
+}
+
+// checkAPI checks if the API version requested by client is supported by server
+func checkAPI(api string) error {
 	// API version is "" means use the current version of the service
 	if len(api) > 0 {
 		if api != API_VERSION {
-			return status.Errorf(codes.Unimplemented, "unsupported API version: service implements API version '%s', but asked for '%s'", API_VERSION, api)
+			// other versions are served side by side by services of their own proto package
+			return status.Errorf(codes.Unimplemented, "unsupported API version: service implements API version '%s', but asked for '%s', "+
+				"other versions are served by services of their own package, e.g. 'v2.TodoService'", API_VERSION, api)
 		}
 	}
 
 	return nil
 }
 
+// todoColumns is list of todo table columns read into Todo entity by scanTodo
+const todoColumns = "id, title, description, reminder, deleted_at, status, completed_at, due_date, priority, create_time, update_time, list_id, parent_id, " +
+	"recurrence, time_zone, recurrence_start, snoozed_until"
+
+// scanTodo reads Todo entity from the current row selected with todoColumns,
+// extra are destinations of columns selected after todoColumns
+func scanTodo(rows *sql.Rows, extra ...interface{}) (*v1.Todo, error) {
+	td := new(v1.Todo)
+	var description sql.NullString
+	var reminder, deletedAt, completedAt, dueDate, recurrenceStart, snoozedUntil sql.NullTime
+	var createTime, updateTime time.Time
+	var st, priority int32
+	dest := []interface{}{&td.Id, &td.Title, &description, &reminder, &deletedAt,
+		&st, &completedAt, &dueDate, &priority, &createTime, &updateTime, &td.ListId, &td.ParentId,
+		&td.Recurrence, &td.TimeZone, &recurrenceStart, &snoozedUntil}
+	if err := rows.Scan(append(dest, extra...)...); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to retrieve field values from Todo row-> "+err.Error())
+	}
+	td.Description = description.String
+	td.Status = v1.Todo_Status(st)
+	td.Priority = v1.Todo_Priority(priority)
+	td.Name = todoName(td.ListId, td.Id)
+
+	var err error
+	if td.Reminder, err = timestampProto(reminder); err != nil {
+		return nil, status.Error(codes.Unknown, "reminder field has invalid format-> "+err.Error())
+	}
+	if td.DeletedAt, err = timestampProto(deletedAt); err != nil {
+		return nil, status.Error(codes.Unknown, "deleted_at field has invalid format-> "+err.Error())
+	}
+	if td.CompletedAt, err = timestampProto(completedAt); err != nil {
+		return nil, status.Error(codes.Unknown, "completed_at field has invalid format-> "+err.Error())
+	}
+	if td.DueDate, err = timestampProto(dueDate); err != nil {
+		return nil, status.Error(codes.Unknown, "due_date field has invalid format-> "+err.Error())
+	}
+	if td.CreateTime, err = ptypes.TimestampProto(createTime); err != nil {
+		return nil, status.Error(codes.Unknown, "create_time field has invalid format-> "+err.Error())
+	}
+	if td.UpdateTime, err = ptypes.TimestampProto(updateTime); err != nil {
+		return nil, status.Error(codes.Unknown, "update_time field has invalid format-> "+err.Error())
+	}
+	if td.RecurrenceStart, err = timestampProto(recurrenceStart); err != nil {
+		return nil, status.Error(codes.Unknown, "recurrence_start field has invalid format-> "+err.Error())
+	}
+	if td.SnoozedUntil, err = timestampProto(snoozedUntil); err != nil {
+		return nil, status.Error(codes.Unknown, "snoozed_until field has invalid format-> "+err.Error())
+	}
+
+	return td, nil
+}
+
+// timestampProto converts nullable database time to protobuf timestamp, NULL is nil
+func timestampProto(t sql.NullTime) (*timestamp.Timestamp, error) {
+	if !t.Valid {
+		return nil, nil
+	}
+	return ptypes.TimestampProto(t.Time)
+}
+
+// nullTime converts optional protobuf timestamp to nullable database time, nil is NULL
+func nullTime(ts *timestamp.Timestamp) (sql.NullTime, error) {
+	if ts == nil {
+		return sql.NullTime{}, nil
+	}
+	t, err := ptypes.Timestamp(ts)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	return sql.NullTime{Time: t, Valid: true}, nil
+}
+
+// filterClause returns SQL conditions joined with AND and their arguments for the filter
+func filterClause(f *v1.TodoFilter) (string, []interface{}, error) {
+	var where string
+	var args []interface{}
+	if f == nil {
+		return where, args, nil
+	}
+
+	if f.ListId != 0 {
+		where += " AND list_id = ?"
+		args = append(args, f.ListId)
+	}
+
+	if len(f.Status) > 0 {
+		where += " AND status IN (?" + strings.Repeat(",?", len(f.Status)-1) + ")"
+		for _, st := range f.Status {
+			args = append(args, int32(st))
+		}
+	}
+
+	if f.MinPriority != v1.Todo_PRIORITY_UNSPECIFIED {
+		where += " AND priority >= ?"
+		args = append(args, int32(f.MinPriority))
+	}
+
+	times := []struct {
+		name string
+		cond string
+		ts   *timestamp.Timestamp
+	}{
+		{"due_before", "due_date < ?", f.DueBefore},
+		{"due_after", "due_date > ?", f.DueAfter},
+		{"create_before", "create_time < ?", f.CreateBefore},
+		{"create_after", "create_time > ?", f.CreateAfter},
+		{"update_before", "update_time < ?", f.UpdateBefore},
+		{"update_after", "update_time > ?", f.UpdateAfter},
+	}
+	for _, tc := range times {
+		if tc.ts == nil {
+			continue
+		}
+		t, err := ptypes.Timestamp(tc.ts)
+		if err != nil {
+			return "", nil, status.Error(codes.InvalidArgument, tc.name+" field has invalid format-> "+err.Error())
+		}
+		where += " AND " + tc.cond
+		args = append(args, t)
+	}
+
+	if len(f.LabelsAny) > 0 {
+		labels, err := normalizeLabels(f.LabelsAny)
+		if err != nil {
+			return "", nil, err
+		}
+		where += " AND id IN (SELECT todo_id FROM todo_label WHERE label IN (?" + strings.Repeat(",?", len(labels)-1) + "))"
+		for _, l := range labels {
+			args = append(args, l)
+		}
+	}
+
+	if len(f.LabelsAll) > 0 {
+		labels, err := normalizeLabels(f.LabelsAll)
+		if err != nil {
+			return "", nil, err
+		}
+		where += " AND id IN (SELECT todo_id FROM todo_label WHERE label IN (?" + strings.Repeat(",?", len(labels)-1) + ") " +
+			"GROUP BY todo_id HAVING COUNT(*) = ?)"
+		for _, l := range labels {
+			args = append(args, l)
+		}
+		args = append(args, len(labels))
+	}
+
+	return where, args, nil
+}
+
+// todoFields is whitelist of fields ListTodos can filter and sort by
+var todoFields = filter.Fields{
+	"id":           {Column: "id", Type: filter.Int},
+	"title":        {Column: "title", Type: filter.String},
+	"description":  {Column: "description", Type: filter.String},
+	"status":       {Column: "status", Type: filter.Enum, Values: v1.Todo_Status_value},
+	"priority":     {Column: "priority", Type: filter.Enum, Values: v1.Todo_Priority_value},
+	"reminder":     {Column: "reminder", Type: filter.Time, Nullable: true},
+	"due_date":     {Column: "due_date", Type: filter.Time, Nullable: true},
+	"completed_at": {Column: "completed_at", Type: filter.Time, Nullable: true},
+	"create_time":  {Column: "create_time", Type: filter.Time},
+	"update_time":  {Column: "update_time", Type: filter.Time},
+	"labels":       {Column: "id IN (SELECT todo_id FROM todo_label WHERE label = ?)", Type: filter.Set, Normalize: normalizeLabel},
+	"list_id":      {Column: "list_id", Type: filter.Int},
+	"parent_id":    {Column: "parent_id", Type: filter.Int},
+}
+
+// readAllOrderFields is whitelist of fields ReadAll can be sorted by
+var readAllOrderFields = filter.Fields{
+	"create_time": todoFields["create_time"],
+	"update_time": todoFields["update_time"],
+}
+
+// orderClause returns SQL ORDER BY clause for sort order over whitelisted fields,
+// id is always the last sort key to make the order stable
+func orderClause(orderBy string, fields filter.Fields) (string, error) {
+	order, err := filter.OrderBy(orderBy, fields)
+	if err != nil {
+		return "", status.Error(codes.InvalidArgument, "order_by field is invalid-> "+err.Error())
+	}
+
+	if len(order) == 0 {
+		return " ORDER BY id", nil
+	}
+	return " ORDER BY " + order + ", id", nil
+}
+
+// loadDetails reads labels and subtask progress of todo tasks
+func loadDetails(ctx context.Context, c queryer, todos []*v1.Todo) error {
+	if err := loadLabels(ctx, c, todos); err != nil {
+		return err
+	}
+	return loadProgress(ctx, c, todos)
+}
+
+// queryer is database connection or transaction to run queries
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// queryTodos returns list of Todo entities selected by query with their labels
+func queryTodos(ctx context.Context, c queryer, query string, args ...interface{}) ([]*v1.Todo, error) {
+	rows, err := c.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to select from Todo-> "+err.Error())
+	}
+	defer rows.Close()
+
+	list := []*v1.Todo{}
+	for rows.Next() {
+		td, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, td)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to retrieve data from Todo-> "+err.Error())
+	}
+	rows.Close()
+
+	if err := loadDetails(ctx, c, list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
 // connect returns SQL database connection from the pool
 func (s *todoServiceServer) connect(ctx context.Context) (*sql.Conn, error) {
 	c, err := s.db.Conn(ctx)
@@ -55,6 +331,11 @@ func (s *todoServiceServer) Create(ctx context.Context, req *v1.CreateRequest) (
 		return nil, err
 	}
 
+	key, err := requestID(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get SQL connection from pool
 	c, err := s.connect(ctx)
 	if err != nil {
@@ -62,22 +343,45 @@ func (s *todoServiceServer) Create(ctx context.Context, req *v1.CreateRequest) (
 	}
 	defer c.Close()
 
-	reminder, err := ptypes.Timestamp(req.Todo.Reminder)
+	tx, err := c.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, status.Error(codes.InvalidArgument, "reminder field has invalid format-> "+err.Error())
+		return nil, status.Error(codes.Unknown, "failed to start transaction-> "+err.Error())
 	}
+	defer tx.Rollback()
 
-	// Insert Todo entity data
-	res, err := c.ExecContext(ctx, "INSERT INTO todo(title, description, reminder) VALUES(?,?,?)", req.Todo.Title, req.Todo.Description, reminder)
-	if err != nil {
-		return nil, status.Error(codes.Unknown, "failed to insert into todo-> "+err.Error())
+	// retry of request returns the task created by the first one
+	if len(key) > 0 {
+		hash, err := requestHash(req.Todo)
+		if err != nil {
+			return nil, err
+		}
+		id, err := claimRequestID(ctx, tx, key, hash, s.idempotencyWindow)
+		if err != nil {
+			return nil, err
+		}
+		if id > 0 {
+			return &v1.CreateResponse{
+				Api: API_VERSION,
+				Id:  id,
+			}, nil
+		}
 	}
 
-	// Get ID of created Todo
-	id, err := res.LastInsertId()
+	id, err := createTodo(ctx, tx, req.Todo)
 	if err != nil {
-		return nil, status.Error(codes.Unknown, "failed to retrieve id for created Todo-> "+err.Error())
+		return nil, err
+	}
+
+	if len(key) > 0 {
+		if err := completeRequestID(ctx, tx, key, id); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to commit transaction-> "+err.Error())
 	}
+	s.events.notify()
 
 	return &v1.CreateResponse{
 		Api: API_VERSION,
@@ -85,6 +389,101 @@ func (s *todoServiceServer) Create(ctx context.Context, req *v1.CreateRequest) (
 	}, nil
 }
 
+// createTodo inserts todo task in transaction and returns its ID
+func createTodo(ctx context.Context, tx *sql.Tx, td *v1.Todo) (int64, error) {
+	return insertTodo(ctx, tx, td, sql.NullTime{})
+}
+
+// insertTodo inserts todo task in transaction and returns its ID, task inserted as DONE
+// is completed at completedAt, or now if completedAt is NULL
+func insertTodo(ctx context.Context, tx *sql.Tx, td *v1.Todo, completedAt sql.NullTime) (int64, error) {
+	if td == nil {
+		return 0, status.Error(codes.InvalidArgument, "todo must be specified")
+	}
+
+	// task without reminder is never reminded
+	reminder, err := nullTime(td.Reminder)
+	if err != nil {
+		return 0, status.Error(codes.InvalidArgument, "reminder field has invalid format-> "+err.Error())
+	}
+
+	dueDate, err := nullTime(td.DueDate)
+	if err != nil {
+		return 0, status.Error(codes.InvalidArgument, "due_date field has invalid format-> "+err.Error())
+	}
+
+	st := td.Status
+	if st == v1.Todo_STATUS_UNSPECIFIED {
+		st = v1.Todo_OPEN
+	}
+
+	now := time.Now().In(time.UTC)
+
+	if st != v1.Todo_DONE {
+		completedAt = sql.NullTime{}
+	} else if !completedAt.Valid {
+		completedAt = sql.NullTime{Time: now, Valid: true}
+	}
+
+	labels, err := normalizeLabels(td.Labels)
+	if err != nil {
+		return 0, err
+	}
+
+	// Reminder of the first task is the start of the recurrence series
+	if err := checkRecurringTodo(td, reminder); err != nil {
+		return 0, err
+	}
+	var recurrenceStart sql.NullTime
+	if len(td.Recurrence) > 0 {
+		recurrenceStart = reminder
+	}
+
+	listID := td.ListId
+	if td.ParentId != 0 {
+		// subtask is in the list of its parent
+		parentListID, err := checkParent(ctx, tx, 0, td.ParentId, 0)
+		if err != nil {
+			return 0, err
+		}
+		if listID != 0 && listID != parentListID {
+			return 0, status.Error(codes.InvalidArgument, "subtask must be in the list of its parent")
+		}
+		listID = parentListID
+	}
+	if listID == 0 {
+		listID = DefaultListID
+	}
+	if err := checkList(ctx, tx, listID); err != nil {
+		return 0, err
+	}
+
+	// Insert Todo entity data
+	res, err := tx.ExecContext(ctx, "INSERT INTO todo(title, description, reminder, status, completed_at, due_date, priority, create_time, update_time, list_id, parent_id, "+
+		"recurrence, time_zone, recurrence_start) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?)",
+		td.Title, td.Description, reminder, int32(st), completedAt, dueDate, int32(td.Priority), now, now, listID, td.ParentId,
+		td.Recurrence, td.TimeZone, recurrenceStart)
+	if err != nil {
+		return 0, status.Error(codes.Unknown, "failed to insert into todo-> "+err.Error())
+	}
+
+	// Get ID of created Todo
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, status.Error(codes.Unknown, "failed to retrieve id for created Todo-> "+err.Error())
+	}
+
+	if err := addLabels(ctx, tx, id, labels); err != nil {
+		return 0, err
+	}
+
+	if err := emit(ctx, tx, v1.TodoEvent_CREATED, id); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
 func (s *todoServiceServer) Read(ctx context.Context, req *v1.ReadRequest) (*v1.ReadResponse, error) {
 	// check if the API version requested by client is supported by server
 	if err := s.checkAPI(req.Api); err != nil {
@@ -98,8 +497,9 @@ func (s *todoServiceServer) Read(ctx context.Context, req *v1.ReadRequest) (*v1.
 	}
 	defer c.Close()
 
-	// Query Todo by ID
-	rows, err := c.QueryContext(ctx, "SELECT id, title, description, reminder FROM todo WHERE id = ?", req.Id)
+	// Query Todo by ID, list ID is checked only if specified
+	rows, err := c.QueryContext(ctx, "SELECT "+todoColumns+" FROM todo WHERE id = ? AND (? = 0 OR list_id = ?) AND deleted_at IS NULL",
+		req.Id, req.ListId, req.ListId)
 	if err != nil {
 		return nil, status.Error(codes.Unknown, "failed to select from todo-> "+err.Error())
 	}
@@ -114,25 +514,24 @@ func (s *todoServiceServer) Read(ctx context.Context, req *v1.ReadRequest) (*v1.
 	}
 
 	// Get Todo data
-	var td v1.Todo
-	var reminder time.Time
-	if err := rows.Scan(&td.Id, &td.Title, &td.Description, &reminder); err != nil {
-		return nil, status.Error(codes.Unknown, "failed to retrieve field values from Todo row-> "+err.Error())
-	}
-
-	td.Reminder, err = ptypes.TimestampProto(reminder)
+	td, err := scanTodo(rows)
 	if err != nil {
-		return nil, status.Error(codes.Unknown, "reminder field has invalid format-> "+err.Error())
+		return nil, err
 	}
 
 	if rows.Next() {
 		return nil, status.Error(codes.Unknown, fmt.Sprintf("found multiple Todo rows with ID='%d'",
 			req.Id))
 	}
+	rows.Close()
+
+	if err := loadDetails(ctx, c, []*v1.Todo{td}); err != nil {
+		return nil, err
+	}
 
 	return &v1.ReadResponse{
 		Api:  API_VERSION,
-		Todo: &td,
+		Todo: td,
 	}, nil
 }
 
@@ -150,32 +549,112 @@ func (s *todoServiceServer) Update(ctx context.Context, req *v1.UpdateRequest) (
 	}
 	defer c.Close()
 
-	reminder, err := ptypes.Timestamp(req.Todo.Reminder)
+	tx, err := c.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, status.Error(codes.InvalidArgument, "reminder field has invalid format-> "+err.Error())
+		return nil, status.Error(codes.Unknown, "failed to start transaction-> "+err.Error())
 	}
+	defer tx.Rollback()
 
-	// Update todo
-	res, err := c.ExecContext(ctx, "UPDATE todo SET title=?, description=?, reminder=? WHERE id=?",
-		req.Todo.Title, req.Todo.Description, reminder, req.Todo.Id)
+	rows, err := updateTodo(ctx, tx, req.Todo)
 	if err != nil {
-		return nil, status.Error(codes.Unknown, "failed to update Todo-> "+err.Error())
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to commit transaction-> "+err.Error())
+	}
+	s.events.notify()
+
+	return &v1.UpdateResponse{
+		Api:     API_VERSION,
+		Updated: rows,
+	}, nil
+}
+
+// updateTodo updates todo task in transaction and returns number of updated rows
+func updateTodo(ctx context.Context, tx *sql.Tx, td *v1.Todo) (int64, error) {
+	if td == nil {
+		return 0, status.Error(codes.InvalidArgument, "todo must be specified")
+	}
+
+	reminder, err := nullTime(td.Reminder)
+	if err != nil {
+		return 0, status.Error(codes.InvalidArgument, "reminder field has invalid format-> "+err.Error())
+	}
+
+	dueDate, err := nullTime(td.DueDate)
+	if err != nil {
+		return 0, status.Error(codes.InvalidArgument, "due_date field has invalid format-> "+err.Error())
+	}
+
+	if err := checkRecurringTodo(td, reminder); err != nil {
+		return 0, err
+	}
+
+	// Lock the task marked as done to create the next occurrence of recurring task only once,
+	// the same way as Complete does
+	wasDone := false
+	if td.Status == v1.Todo_DONE {
+		list, err := queryTodos(ctx, tx, "SELECT "+todoColumns+" FROM todo WHERE id=? AND deleted_at IS NULL FOR UPDATE", td.Id)
+		if err != nil {
+			return 0, err
+		}
+		if len(list) == 0 {
+			return 0, status.Error(codes.NotFound, fmt.Sprintf("Todo with ID='%d' is not found", td.Id))
+		}
+		wasDone = list[0].Status == v1.Todo_DONE
+	}
+
+	// Update todo, status is kept if not specified and completion time is
+	// kept while the task stays done. Changed recurrence starts a new series from the reminder,
+	// recurrence_start is assigned before recurrence as MySQL evaluates SET left to right,
+	// the same way snooze is cleared before reminder is changed
+	st := int32(td.Status)
+	now := time.Now().In(time.UTC)
+	rec := td.Recurrence
+	res, err := tx.ExecContext(ctx, "UPDATE todo SET title=?, description=?, snoozed_until=IF(reminder<=>?, snoozed_until, NULL), reminder=?, due_date=?, priority=?, "+
+		"status=IF(?=0, status, ?), completed_at=IF(IF(?=0, status, ?)=?, COALESCE(completed_at, ?), NULL), "+
+		"recurrence_start=IF(?='', NULL, IF(recurrence=?, recurrence_start, ?)), recurrence=?, time_zone=?, update_time=? "+
+		"WHERE id=? AND deleted_at IS NULL",
+		td.Title, td.Description, reminder, reminder, dueDate, int32(td.Priority),
+		st, st, st, st, int32(v1.Todo_DONE), now,
+		rec, rec, reminder, rec, td.TimeZone, now, td.Id)
+	if err != nil {
+		return 0, status.Error(codes.Unknown, "failed to update Todo-> "+err.Error())
 	}
 
 	rows, err := res.RowsAffected()
 	if err != nil {
-		return nil, status.Error(codes.Unknown, "failed to retrieve rows affected value-> "+err.Error())
+		return 0, status.Error(codes.Unknown, "failed to retrieve rows affected value-> "+err.Error())
 	}
 
 	if rows == 0 {
-		return nil, status.Error(codes.NotFound, fmt.Sprintf("Todo with ID='%d' is not found",
-			req.Todo.Id))
+		return 0, status.Error(codes.NotFound, fmt.Sprintf("Todo with ID='%d' is not found",
+			td.Id))
 	}
 
-	return &v1.UpdateResponse{
-		Api:     API_VERSION,
-		Updated: rows,
-	}, nil
+	if err := emit(ctx, tx, v1.TodoEvent_UPDATED, td.Id); err != nil {
+		return 0, err
+	}
+
+	// the next occurrence follows the updated recurrence and reminder
+	if td.Status == v1.Todo_DONE && !wasDone {
+		list, err := queryTodos(ctx, tx, "SELECT "+todoColumns+" FROM todo WHERE id=?", td.Id)
+		if err != nil {
+			return 0, err
+		}
+		next, err := createNextOccurrence(ctx, tx, list[0], now)
+		if err != nil {
+			return 0, err
+		}
+		if next != nil {
+			if err := emit(ctx, tx, v1.TodoEvent_CREATED, next.Id); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return rows, nil
 }
 
 // Delete todo task
@@ -192,8 +671,66 @@ func (s *todoServiceServer) Delete(ctx context.Context, req *v1.DeleteRequest) (
 	}
 	defer c.Close()
 
-	// Delete Todo
-	res, err := c.ExecContext(ctx, "DELETE FROM todo WHERE id=?", req.Id)
+	tx, err := c.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to start transaction-> "+err.Error())
+	}
+	defer tx.Rollback()
+
+	ids, err := deleteTodo(ctx, tx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to commit transaction-> "+err.Error())
+	}
+	s.events.notify()
+
+	return &v1.DeleteResponse{
+		Api:     API_VERSION,
+		Deleted: int64(len(ids)),
+	}, nil
+}
+
+// DeleteTodo deletes todo task of list with its subtasks
+func (s *todoServiceServer) DeleteTodo(ctx context.Context, listID, id int64) (int64, error) {
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+
+	tx, err := c.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, status.Error(codes.Unknown, "failed to start transaction-> "+err.Error())
+	}
+	defer tx.Rollback()
+
+	// the task can't be moved to another list until it's deleted
+	if _, err := lockTodo(ctx, tx, listID, id); err != nil {
+		return 0, err
+	}
+
+	ids, err := deleteTodo(ctx, tx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, status.Error(codes.Unknown, "failed to commit transaction-> "+err.Error())
+	}
+	s.events.notify()
+
+	return int64(len(ids)), nil
+}
+
+// deleteTodo deletes todo task with its subtasks in transaction and returns IDs of deleted tasks
+func deleteTodo(ctx context.Context, tx *sql.Tx, id int64) ([]int64, error) {
+	// Delete Todo, it is kept in the table until purged so it can be restored by Undelete
+	now := time.Now().In(time.UTC)
+	res, err := tx.ExecContext(ctx, "UPDATE todo SET deleted_at=? WHERE id=? AND deleted_at IS NULL", now, id)
 	if err != nil {
 		return nil, status.Error(codes.Unknown, "failed to delete Todo-> "+err.Error())
 	}
@@ -205,13 +742,27 @@ func (s *todoServiceServer) Delete(ctx context.Context, req *v1.DeleteRequest) (
 
 	if rows == 0 {
 		return nil, status.Error(codes.NotFound, fmt.Sprintf("Todo with ID='%d' is not found",
-			req.Id))
+			id))
 	}
 
-	return &v1.DeleteResponse{
-		Api:     API_VERSION,
-		Deleted: rows,
-	}, nil
+	// Delete subtasks with the same time, so they are restored together
+	ids, _, err := descendants(ctx, tx, id, "deleted_at IS NULL")
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 {
+		if _, err := tx.ExecContext(ctx, "UPDATE todo SET deleted_at=? WHERE id IN "+inClause(len(ids)),
+			append([]interface{}{now}, int64Args(ids)...)...); err != nil {
+			return nil, status.Error(codes.Unknown, "failed to delete Todo-> "+err.Error())
+		}
+	}
+
+	ids = append(ids, id)
+	if err := emit(ctx, tx, v1.TodoEvent_DELETED, ids...); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
 }
 
 // Read all todo tasks
@@ -229,28 +780,19 @@ func (s *todoServiceServer) ReadAll(ctx context.Context, req *v1.ReadAllRequest)
 	defer c.Close()
 
 	// Get Todo list
-	rows, err := c.QueryContext(ctx, "SELECT id, title, description, reminder FROM todo")
+	where, args, err := filterClause(req.Filter)
 	if err != nil {
-		return nil, status.Error(codes.Unknown, "failed to select from Todo-> "+err.Error())
+		return nil, err
 	}
-	defer rows.Close()
 
-	var reminder time.Time
-	list := []*v1.Todo{}
-	for rows.Next() {
-		td := new(v1.Todo)
-		if err := rows.Scan(&td.Id, &td.Title, &td.Description, &reminder); err != nil {
-			return nil, status.Error(codes.Unknown, "failed to retrieve field values from Todo row-> "+err.Error())
-		}
-		td.Reminder, err = ptypes.TimestampProto(reminder)
-		if err != nil {
-			return nil, status.Error(codes.Unknown, "reminder field has invalid format-> "+err.Error())
-		}
-		list = append(list, td)
+	order, err := orderClause(req.OrderBy, readAllOrderFields)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, status.Error(codes.Unknown, "failed to retrieve data from Todo-> "+err.Error())
+	list, err := queryTodos(ctx, c, "SELECT "+todoColumns+" FROM todo WHERE deleted_at IS NULL"+where+order, args...)
+	if err != nil {
+		return nil, err
 	}
 
 	return &v1.ReadAllResponse{
@@ -274,32 +816,393 @@ func (s *todoServiceServer) ReadByTitle(ctx context.Context, req *v1.ReadByTitle
 	defer c.Close()
 
 	// Get Todo list
-	rows, err := c.QueryContext(ctx, "SELECT id, title, description, reminder FROM todo WHERE title LIKE ?", "%"+req.Title+"%")
+	where, args, err := filterClause(req.Filter)
 	if err != nil {
-		return nil, status.Error(codes.Unknown, "failed to select from Todo-> "+err.Error())
+		return nil, err
+	}
+
+	// wildcards in title are matched literally
+	list, err := queryTodos(ctx, c, "SELECT "+todoColumns+" FROM todo WHERE title LIKE ? AND deleted_at IS NULL"+where,
+		append([]interface{}{"%" + filter.EscapeLike(req.Title) + "%"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.ReadByTitleResponse{
+		Api:   API_VERSION,
+		Todos: list,
+	}, nil
+}
+
+// Restore deleted todo task
+func (s *todoServiceServer) Undelete(ctx context.Context, req *v1.UndeleteRequest) (*v1.UndeleteResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	tx, err := c.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to start transaction-> "+err.Error())
+	}
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+	var parentID int64
+	if err := tx.QueryRowContext(ctx, "SELECT deleted_at, parent_id FROM todo WHERE id=? AND deleted_at IS NOT NULL FOR UPDATE", req.Id).
+		Scan(&deletedAt, &parentID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, fmt.Sprintf("deleted Todo with ID='%d' is not found",
+				req.Id))
+		}
+		return nil, status.Error(codes.Unknown, "failed to select from todo-> "+err.Error())
+	}
+
+	// Subtasks deleted together with the task are restored with it
+	ids, _, err := descendants(ctx, tx, req.Id, "deleted_at = ?", deletedAt)
+	if err != nil {
+		return nil, err
+	}
+	ids = append(ids, req.Id)
+
+	// Restore Todo
+	res, err := tx.ExecContext(ctx, "UPDATE todo SET deleted_at=NULL WHERE id IN "+inClause(len(ids)), int64Args(ids)...)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to undelete Todo-> "+err.Error())
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to retrieve rows affected value-> "+err.Error())
+	}
+
+	// Restored task becomes top-level if its parent is still deleted
+	if parentID != 0 {
+		if _, err := tx.ExecContext(ctx, "UPDATE todo SET parent_id=0 WHERE id=? AND NOT EXISTS "+
+			"(SELECT 1 FROM (SELECT id FROM todo WHERE id=? AND deleted_at IS NULL) p)", req.Id, parentID); err != nil {
+			return nil, status.Error(codes.Unknown, "failed to undelete Todo-> "+err.Error())
+		}
+	}
+
+	if err := emit(ctx, tx, v1.TodoEvent_CREATED, ids...); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to commit transaction-> "+err.Error())
+	}
+	s.events.notify()
+
+	return &v1.UndeleteResponse{
+		Api:       API_VERSION,
+		Undeleted: rows,
+	}, nil
+}
+
+// Read deleted todo tasks which are not purged yet
+func (s *todoServiceServer) ListDeleted(ctx context.Context, req *v1.ListDeletedRequest) (*v1.ListDeletedResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	// Get deleted Todo list
+	list, err := queryTodos(ctx, c, "SELECT "+todoColumns+" FROM todo WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC")
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.ListDeletedResponse{
+		Api:   API_VERSION,
+		Todos: list,
+	}, nil
+}
+
+// Mark todo task as done
+func (s *todoServiceServer) Complete(ctx context.Context, req *v1.CompleteRequest) (*v1.CompleteResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	return s.CompleteTodo(ctx, 0, req.Id)
+}
+
+// CompleteTodo marks todo task of list as done
+func (s *todoServiceServer) CompleteTodo(ctx context.Context, listID, id int64) (*v1.CompleteResponse, error) {
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	tx, err := c.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to start transaction-> "+err.Error())
+	}
+	defer tx.Rollback()
+
+	// Lock the task to create the next occurrence of recurring task only once
+	old, err := lockTodo(ctx, tx, listID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(time.UTC)
+	td, err := setStatus(ctx, tx, id, "status=?, completed_at=COALESCE(completed_at, ?), update_time=?",
+		int32(v1.Todo_DONE), now, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := emit(ctx, tx, v1.TodoEvent_UPDATED, id); err != nil {
+		return nil, err
+	}
+
+	var next *v1.Todo
+	if old.Status != v1.Todo_DONE {
+		if next, err = createNextOccurrence(ctx, tx, old, now); err != nil {
+			return nil, err
+		}
+	}
+	if next != nil {
+		if err := emit(ctx, tx, v1.TodoEvent_CREATED, next.Id); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to commit transaction-> "+err.Error())
+	}
+	s.events.notify()
+
+	return &v1.CompleteResponse{
+		Api:  API_VERSION,
+		Todo: td,
+		Next: next,
+	}, nil
+}
+
+// Mark todo task as open again
+func (s *todoServiceServer) Reopen(ctx context.Context, req *v1.ReopenRequest) (*v1.ReopenResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	tx, err := c.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to start transaction-> "+err.Error())
+	}
+	defer tx.Rollback()
+
+	td, err := setStatus(ctx, tx, req.Id, "status=?, completed_at=NULL, update_time=?",
+		int32(v1.Todo_OPEN), time.Now().In(time.UTC))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := emit(ctx, tx, v1.TodoEvent_UPDATED, req.Id); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to commit transaction-> "+err.Error())
+	}
+	s.events.notify()
+
+	return &v1.ReopenResponse{
+		Api:  API_VERSION,
+		Todo: td,
+	}, nil
+}
+
+// setStatus updates status columns of todo task and returns the task after update
+func setStatus(ctx context.Context, c queryer, id int64, set string, args ...interface{}) (*v1.Todo, error) {
+	if _, err := c.ExecContext(ctx, "UPDATE todo SET "+set+" WHERE id=? AND deleted_at IS NULL", append(args, id)...); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to update Todo-> "+err.Error())
+	}
+
+	// rows affected is 0 for the task already in the status, so existence is checked by reading it
+	list, err := queryTodos(ctx, c, "SELECT "+todoColumns+" FROM todo WHERE id=? AND deleted_at IS NULL", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("Todo with ID='%d' is not found", id))
+	}
+
+	return list[0], nil
+}
+
+// Read todo tasks matched by filter expression
+func (s *todoServiceServer) ListTodos(ctx context.Context, req *v1.ListTodosRequest) (*v1.ListTodosResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	return s.ListTodosInList(ctx, 0, req)
+}
+
+// ListTodosInList reads page of todo tasks of list matched by filter expression
+func (s *todoServiceServer) ListTodosInList(ctx context.Context, listID int64, req *v1.ListTodosRequest) (*v1.ListTodosResponse, error) {
+	where, args, err := filter.Parse(req.Filter, todoFields)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "filter field is invalid-> "+err.Error())
+	}
+	if len(where) > 0 {
+		where = " AND " + where
+	}
+	// page token is bound to the list as well as to the filter
+	query := req.Filter
+	if listID > 0 {
+		where = " AND list_id = ?" + where
+		args = append([]interface{}{listID}, args...)
+		query = fmt.Sprintf("lists/%d\x00%s", listID, req.Filter)
+	}
+
+	order, err := orderClause(req.OrderBy, todoFields)
+	if err != nil {
+		return nil, err
+	}
+
+	offset, err := parsePageToken(req.PageToken, query, req.OrderBy)
+	if err != nil {
+		return nil, err
+	}
+	pageSize := req.PageSize
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	limit, err := pageClause(pageSize, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	// Get Todo list
+	list, err := queryTodos(ctx, c, "SELECT "+todoColumns+" FROM todo WHERE deleted_at IS NULL"+where+order+limit, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// the extra row read by page clause means there is the next page
+	next := ""
+	if pageSize > 0 && len(list) > int(pageSize) {
+		list = list[:pageSize]
+		next = pageToken(offset+int64(pageSize), query, req.OrderBy)
+	}
+
+	return &v1.ListTodosResponse{
+		Api:           API_VERSION,
+		Todos:         list,
+		NextPageToken: next,
+	}, nil
+}
+
+// Search todo tasks by words in title and description
+func (s *todoServiceServer) Search(ctx context.Context, req *v1.SearchRequest) (*v1.SearchResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	terms := search.Terms(req.Query)
+	if len(terms) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "query field has no words to search")
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	pre, post := req.HighlightPreTag, req.HighlightPostTag
+	if len(pre) == 0 && len(post) == 0 {
+		pre, post = "<em>", "</em>"
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	// Search Todo list using full-text index, query is built from words only
+	// so no user input is interpreted as full-text operators
+	q := search.BooleanQuery(terms)
+	rows, err := c.QueryContext(ctx, "SELECT "+todoColumns+", MATCH(title, description) AGAINST(? IN BOOLEAN MODE) AS score "+
+		"FROM todo WHERE MATCH(title, description) AGAINST(? IN BOOLEAN MODE) AND deleted_at IS NULL "+
+		"ORDER BY score DESC, id LIMIT ?", q, q, limit)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to search Todo-> "+err.Error())
 	}
 	defer rows.Close()
 
-	var reminder time.Time
-	list := []*v1.Todo{}
+	results := []*v1.SearchResult{}
 	for rows.Next() {
-		td := new(v1.Todo)
-		if err := rows.Scan(&td.Id, &td.Title, &td.Description, &reminder); err != nil {
-			return nil, status.Error(codes.Unknown, "failed to retrieve field values from Todo row-> "+err.Error())
-		}
-		td.Reminder, err = ptypes.TimestampProto(reminder)
+		var score float64
+		td, err := scanTodo(rows, &score)
 		if err != nil {
-			return nil, status.Error(codes.Unknown, "reminder field has invalid format-> "+err.Error())
+			return nil, err
 		}
-		list = append(list, td)
+		results = append(results, &v1.SearchResult{
+			Todo:               td,
+			Score:              score,
+			TitleSnippet:       search.Snippet(td.Title, terms, snippetWidth, pre, post),
+			DescriptionSnippet: search.Snippet(td.Description, terms, snippetWidth, pre, post),
+		})
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, status.Error(codes.Unknown, "failed to retrieve data from Todo-> "+err.Error())
 	}
+	rows.Close()
 
-	return &v1.ReadByTitleResponse{
-		Api:   API_VERSION,
-		Todos: list,
+	todos := make([]*v1.Todo, len(results))
+	for i, r := range results {
+		todos[i] = r.Todo
+	}
+	if err := loadDetails(ctx, c, todos); err != nil {
+		return nil, err
+	}
+
+	return &v1.SearchResponse{
+		Api:     API_VERSION,
+		Results: results,
 	}, nil
 }
@@ -0,0 +1,301 @@
+package v1
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+func TestListTodosInList(t *testing.T) {
+	const listQuery = "SELECT " + todoColumns + " FROM todo WHERE deleted_at IS NULL"
+
+	tests := []struct {
+		name     string
+		listID   int64
+		filter   string
+		token    string
+		expect   func(mock sqlmock.Sqlmock)
+		wantCode codes.Code
+	}{
+		{
+			name:   "list is separate condition",
+			listID: 2,
+			filter: `title = "a" OR id > 0`,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(listQuery+" AND list_id = ? AND (title = ? OR id > ?) ORDER BY id").
+					WithArgs(int64(2), "a", int64(0)).WillReturnRows(todoRows())
+			},
+		},
+		{
+			name: "any list",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(listQuery + " ORDER BY id").WithArgs().WillReturnRows(todoRows())
+			},
+		},
+		{
+			name:     "filter breaking out of list",
+			listID:   2,
+			filter:   `title = "a") OR (id > 0`,
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "page token of another list",
+			listID:   2,
+			token:    pageToken(10, "lists/3\x00", ""),
+			wantCode: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newMock(t)
+			if tt.expect != nil {
+				tt.expect(mock)
+			}
+
+			_, err := s.ListTodosInList(context.Background(), tt.listID, &v1.ListTodosRequest{
+				Filter:    tt.filter,
+				PageSize:  10,
+				PageToken: tt.token,
+			})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("ListTodosInList() error = %v, want code %s", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestCreateTodoReminder(t *testing.T) {
+	tests := []struct {
+		name     string
+		todo     *v1.Todo
+		wantCode codes.Code
+	}{
+		{name: "without reminder", todo: &v1.Todo{Title: "Call Bob"}},
+		{name: "recurring without reminder", todo: &v1.Todo{Title: "Call Bob", Recurrence: "FREQ=DAILY"}, wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newMock(t)
+			mock.ExpectBegin()
+			if tt.wantCode == codes.OK {
+				mock.ExpectQuery("SELECT id FROM todo_list WHERE id=?").WithArgs(int64(DefaultListID)).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(DefaultListID))
+				// reminder is NULL, so the scheduler never fires it
+				mock.ExpectExec("INSERT INTO todo(").
+					WithArgs("Call Bob", "", nil, int32(v1.Todo_OPEN), nil, nil, int32(0), sqlmock.AnyArg(), sqlmock.AnyArg(),
+						int64(DefaultListID), int64(0), "", "", nil).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectEmit(mock, v1.TodoEvent_CREATED, &v1.Todo{Id: 1, ListId: DefaultListID})
+			}
+			mock.ExpectRollback()
+
+			tx, err := s.db.Begin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tx.Rollback()
+
+			_, err = createTodo(context.Background(), tx, tt.todo)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("createTodo() error = %v, want code %s", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestDeleteTodoInList(t *testing.T) {
+	tests := []struct {
+		name     string
+		listID   int64
+		found    bool
+		wantCode codes.Code
+	}{
+		{name: "in list", listID: 2, found: true},
+		{name: "in another list", listID: 3, wantCode: codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newMock(t)
+			td := &v1.Todo{Id: 1, ListId: 2}
+
+			mock.ExpectBegin()
+			if !tt.found {
+				mock.ExpectQuery(lockQuery).WithArgs(int64(1), tt.listID, tt.listID).WillReturnRows(todoRows())
+				mock.ExpectRollback()
+			} else {
+				expectTodos(mock, lockQuery, td)
+				mock.ExpectExec("UPDATE todo SET deleted_at=? WHERE id=? AND deleted_at IS NULL").WithArgs(sqlmock.AnyArg(), int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT id FROM todo WHERE parent_id IN (?)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				expectEmit(mock, v1.TodoEvent_DELETED, td)
+				mock.ExpectCommit()
+			}
+
+			got, err := s.DeleteTodo(context.Background(), tt.listID, 1)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("DeleteTodo() error = %v, want code %s", err, tt.wantCode)
+			}
+			if tt.found && got != 1 {
+				t.Errorf("DeleteTodo() = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestCompleteTodoInList(t *testing.T) {
+	s, mock := newMock(t)
+
+	// the task is locked with the list condition, nothing is written if it's in another list
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockQuery).WithArgs(int64(1), int64(3), int64(3)).WillReturnRows(todoRows())
+	mock.ExpectRollback()
+
+	if _, err := s.CompleteTodo(context.Background(), 3, 1); status.Code(err) != codes.NotFound {
+		t.Fatalf("CompleteTodo() error = %v, want code %s", err, codes.NotFound)
+	}
+}
+
+func TestCompleteAndReopen(t *testing.T) {
+	s, mock := newMock(t)
+	completedAt, _ := ptypes.TimestampProto(testTime)
+	td := &v1.Todo{Id: 1, Title: "Call Bob", Status: v1.Todo_OPEN, ListId: 2}
+	done := &v1.Todo{Id: 1, Title: "Call Bob", Status: v1.Todo_DONE, CompletedAt: completedAt, ListId: 2}
+
+	// completion time of the task completed again is kept
+	mock.ExpectBegin()
+	expectTodos(mock, lockQuery, td)
+	mock.ExpectExec("UPDATE todo SET status=?, completed_at=COALESCE(completed_at, ?), update_time=? WHERE id=? AND deleted_at IS NULL").
+		WithArgs(int32(v1.Todo_DONE), sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectTodos(mock, "SELECT "+todoColumns+" FROM todo WHERE id=? AND deleted_at IS NULL", done)
+	expectEmit(mock, v1.TodoEvent_UPDATED, done)
+	mock.ExpectCommit()
+
+	res, err := s.Complete(context.Background(), &v1.CompleteRequest{Api: API_VERSION, Id: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Todo.Status != v1.Todo_DONE || res.Todo.CompletedAt == nil || res.Next != nil {
+		t.Errorf("Complete() = %v, want done task without next occurrence", res)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE todo SET status=?, completed_at=NULL, update_time=? WHERE id=? AND deleted_at IS NULL").
+		WithArgs(int32(v1.Todo_OPEN), sqlmock.AnyArg(), int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectTodos(mock, "SELECT "+todoColumns+" FROM todo WHERE id=? AND deleted_at IS NULL", td)
+	expectEmit(mock, v1.TodoEvent_UPDATED, td)
+	mock.ExpectCommit()
+
+	reopened, err := s.Reopen(context.Background(), &v1.ReopenRequest{Api: API_VERSION, Id: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Todo.Status != v1.Todo_OPEN || reopened.Todo.CompletedAt != nil {
+		t.Errorf("Reopen() = %v, want open task without completion time", reopened.Todo)
+	}
+
+	// the task which doesn't exist isn't reopened
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE todo SET status=?, completed_at=NULL").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT " + todoColumns + " FROM todo WHERE id=? AND deleted_at IS NULL").WillReturnRows(todoRows())
+	mock.ExpectRollback()
+
+	if _, err := s.Reopen(context.Background(), &v1.ReopenRequest{Api: API_VERSION, Id: 9}); status.Code(err) != codes.NotFound {
+		t.Errorf("Reopen() error = %v, want code %s", err, codes.NotFound)
+	}
+}
+
+func TestFilterClauseStatus(t *testing.T) {
+	due := ptypes.TimestampNow()
+	dueTime, _ := ptypes.Timestamp(due)
+
+	where, args, err := filterClause(&v1.TodoFilter{
+		Status:      []v1.Todo_Status{v1.Todo_OPEN, v1.Todo_IN_PROGRESS},
+		MinPriority: v1.Todo_HIGH,
+		DueBefore:   due,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := " AND status IN (?,?) AND priority >= ? AND due_date < ?"; where != want {
+		t.Errorf("filterClause() = %q, want %q", where, want)
+	}
+	if want := []interface{}{int32(v1.Todo_OPEN), int32(v1.Todo_IN_PROGRESS), int32(v1.Todo_HIGH), dueTime}; !reflect.DeepEqual(args, want) {
+		t.Errorf("filterClause() args = %v, want %v", args, want)
+	}
+
+	if _, _, err := filterClause(&v1.TodoFilter{DueAfter: &timestamp.Timestamp{Seconds: -1 << 62}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("filterClause() error = %v, want code %s for invalid time", err, codes.InvalidArgument)
+	}
+}
+
+// recentTime is query argument matching time not before the time
+type recentTime time.Time
+
+func (a recentTime) Match(v driver.Value) bool {
+	t, ok := v.(time.Time)
+	return ok && !t.Before(time.Time(a))
+}
+
+func TestAuditTimes(t *testing.T) {
+	s, mock := newMock(t)
+	started := time.Now().In(time.UTC)
+	past, _ := ptypes.TimestampProto(testTime)
+
+	// create and update times of input are ignored, server sets them
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM todo_list WHERE id=?").WithArgs(int64(DefaultListID)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(DefaultListID))
+	mock.ExpectExec("INSERT INTO todo(").
+		WithArgs("Call Bob", "", nil, int32(v1.Todo_OPEN), nil, nil, int32(0), recentTime(started), recentTime(started),
+			int64(DefaultListID), int64(0), "", "", nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectEmit(mock, v1.TodoEvent_CREATED, &v1.Todo{Id: 1, ListId: DefaultListID})
+	mock.ExpectRollback()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createTodo(context.Background(), tx, &v1.Todo{Title: "Call Bob", CreateTime: past, UpdateTime: past}); err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+
+	tests := []struct {
+		name     string
+		req      *v1.ReadAllRequest
+		query    string
+		wantCode codes.Code
+	}{
+		{
+			name:  "filtered and ordered by audit times",
+			req:   &v1.ReadAllRequest{Filter: &v1.TodoFilter{CreateAfter: past}, OrderBy: "update_time desc"},
+			query: " AND create_time > ? ORDER BY update_time DESC, id",
+		},
+		{name: "default order", req: &v1.ReadAllRequest{}, query: " ORDER BY id"},
+		{name: "order by field without index", req: &v1.ReadAllRequest{OrderBy: "title"}, wantCode: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newMock(t)
+			if tt.wantCode == codes.OK {
+				mock.ExpectQuery("SELECT " + todoColumns + " FROM todo WHERE deleted_at IS NULL" + tt.query).WillReturnRows(todoRows())
+			}
+
+			tt.req.Api = API_VERSION
+			if _, err := s.ReadAll(context.Background(), tt.req); status.Code(err) != tt.wantCode {
+				t.Fatalf("ReadAll() error = %v, want code %s", err, tt.wantCode)
+			}
+		})
+	}
+}
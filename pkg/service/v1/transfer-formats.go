@@ -0,0 +1,601 @@
+package v1
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+	"github.com/devararishivian/go-grpc/pkg/ical"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	// icalProdID is PRODID of exported iCalendar data
+	icalProdID = "-//go-grpc//Todo Service//EN"
+	// icalUIDSuffix is suffix of UID of exported tasks, UID is "{id}@todo-service"
+	icalUIDSuffix = "@todo-service"
+	// icalListProperty keeps ID of the list of exported task
+	icalListProperty = "X-TODO-LIST-ID"
+)
+
+// csvHeader is header row of CSV data
+var csvHeader = []string{"id", "title", "description", "status", "priority", "reminder", "due_date", "completed_at",
+	"labels", "list_id", "parent_id", "recurrence", "time_zone"}
+
+// icalStatuses are VTODO STATUS values of task statuses
+var icalStatuses = map[v1.Todo_Status]string{
+	v1.Todo_OPEN:        "NEEDS-ACTION",
+	v1.Todo_IN_PROGRESS: "IN-PROCESS",
+	v1.Todo_DONE:        "COMPLETED",
+	v1.Todo_CANCELLED:   "CANCELLED",
+}
+
+// icalPriorities are VTODO PRIORITY values of task priorities, 1 is the highest
+var icalPriorities = map[v1.Todo_Priority]int{
+	v1.Todo_URGENT: 1,
+	v1.Todo_HIGH:   3,
+	v1.Todo_MEDIUM: 5,
+	v1.Todo_LOW:    9,
+}
+
+// todoEncoder writes todo tasks in export format
+type todoEncoder interface {
+	// begin writes data before the first task
+	begin(w io.Writer) error
+	encode(w io.Writer, td *v1.Todo) error
+	// end writes data after the last task
+	end(w io.Writer) error
+}
+
+// importRow is task read from imported data
+type importRow struct {
+	// row is 1-based number of the task in data
+	row int
+	// key identifies the task in data, it's empty if the task has no identifier
+	key string
+	// parentKey is key of the parent task, the parent may be in data or already exist
+	parentKey string
+	todo      *v1.Todo
+	// err is reason the task can't be imported
+	err error
+}
+
+// newTodoEncoder returns encoder of export format
+func newTodoEncoder(format v1.TodoFormat) (todoEncoder, error) {
+	switch format {
+	case v1.TodoFormat_JSON_LINES:
+		return jsonEncoder{}, nil
+	case v1.TodoFormat_CSV:
+		return csvEncoder{}, nil
+	case v1.TodoFormat_ICALENDAR:
+		return icalEncoder{}, nil
+	default:
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("unsupported format '%s'", format))
+	}
+}
+
+// decodeTodos reads tasks from imported data, error is returned if data can't be read at all,
+// invalid tasks are returned with their errors
+func decodeTodos(format v1.TodoFormat, data []byte) ([]*importRow, error) {
+	switch format {
+	case v1.TodoFormat_JSON_LINES:
+		return decodeJSON(data)
+	case v1.TodoFormat_CSV:
+		return decodeCSV(data)
+	case v1.TodoFormat_ICALENDAR:
+		return decodeICal(data)
+	default:
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("unsupported format '%s'", format))
+	}
+}
+
+// formatTimestamp returns time in RFC 3339 format, empty if it's not set
+func formatTimestamp(ts *timestamp.Timestamp) string {
+	if ts == nil {
+		return ""
+	}
+	t, err := ptypes.Timestamp(ts)
+	if err != nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// parseTimestamp parses time in RFC 3339 format, nil if it's empty
+func parseTimestamp(field, value string) (*timestamp.Timestamp, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("%s field has invalid format '%s'", field, value))
+	}
+	return ptypes.TimestampProto(t)
+}
+
+// rowKeys sets keys of row from IDs of the task and its parent
+func rowKeys(r *importRow) {
+	if r.todo.Id != 0 {
+		r.key = strconv.FormatInt(r.todo.Id, 10)
+	}
+	if r.todo.ParentId != 0 {
+		r.parentKey = strconv.FormatInt(r.todo.ParentId, 10)
+	}
+}
+
+// jsonEncoder writes task per line in JSON
+type jsonEncoder struct{}
+
+func (jsonEncoder) begin(w io.Writer) error {
+	return nil
+}
+
+func (jsonEncoder) encode(w io.Writer, td *v1.Todo) error {
+	b, err := protojson.Marshal(td)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+func (jsonEncoder) end(w io.Writer) error {
+	return nil
+}
+
+// decodeJSON reads task per line in JSON, empty lines are skipped
+func decodeJSON(data []byte) ([]*importRow, error) {
+	var rows []*importRow
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		r := &importRow{row: len(rows) + 1, todo: &v1.Todo{}}
+		rows = append(rows, r)
+		if err := protojson.Unmarshal(line, r.todo); err != nil {
+			r.err = status.Error(codes.InvalidArgument, "invalid JSON-> "+err.Error())
+			continue
+		}
+		rowKeys(r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "failed to read JSON lines-> "+err.Error())
+	}
+
+	return rows, nil
+}
+
+// csvEncoder writes task per row of CSV with header row
+type csvEncoder struct{}
+
+func (csvEncoder) begin(w io.Writer) error {
+	return writeCSV(w, csvHeader)
+}
+
+func (csvEncoder) encode(w io.Writer, td *v1.Todo) error {
+	var st, priority string
+	if td.Status != v1.Todo_STATUS_UNSPECIFIED {
+		st = td.Status.String()
+	}
+	if td.Priority != v1.Todo_PRIORITY_UNSPECIFIED {
+		priority = td.Priority.String()
+	}
+
+	return writeCSV(w, []string{
+		strconv.FormatInt(td.Id, 10),
+		td.Title,
+		td.Description,
+		st,
+		priority,
+		formatTimestamp(td.Reminder),
+		formatTimestamp(td.DueDate),
+		formatTimestamp(td.CompletedAt),
+		strings.Join(td.Labels, ";"),
+		strconv.FormatInt(td.ListId, 10),
+		strconv.FormatInt(td.ParentId, 10),
+		td.Recurrence,
+		td.TimeZone,
+	})
+}
+
+func (csvEncoder) end(w io.Writer) error {
+	return nil
+}
+
+// writeCSV writes CSV record
+func writeCSV(w io.Writer, record []string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(record); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// decodeCSV reads task per row of CSV, columns are named by header row in any order,
+// unknown columns are ignored
+func decodeCSV(data []byte) ([]*importRow, error) {
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "failed to read CSV header-> "+err.Error())
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, status.Error(codes.InvalidArgument, "CSV header must have 'title' column")
+	}
+
+	var rows []*importRow
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "failed to read CSV-> "+err.Error())
+		}
+
+		r := &importRow{row: len(rows) + 1}
+		rows = append(rows, r)
+		get := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		if r.todo, r.err = parseCSVTodo(get); r.err == nil {
+			rowKeys(r)
+		}
+	}
+
+	return rows, nil
+}
+
+// parseCSVTodo returns task from CSV record, get returns value of column by name
+func parseCSVTodo(get func(name string) string) (*v1.Todo, error) {
+	td := &v1.Todo{
+		Title:       get("title"),
+		Description: get("description"),
+		Recurrence:  get("recurrence"),
+		TimeZone:    get("time_zone"),
+	}
+
+	var err error
+	for _, f := range []struct {
+		name string
+		dst  *int64
+	}{{"id", &td.Id}, {"list_id", &td.ListId}, {"parent_id", &td.ParentId}} {
+		if v := get(f.name); len(v) > 0 {
+			if *f.dst, err = strconv.ParseInt(v, 10, 64); err != nil {
+				return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("%s field has invalid format '%s'", f.name, v))
+			}
+		}
+	}
+
+	if v := get("status"); len(v) > 0 {
+		st, ok := v1.Todo_Status_value[strings.ToUpper(v)]
+		if !ok {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("status '%s' is unknown", v))
+		}
+		td.Status = v1.Todo_Status(st)
+	}
+	if v := get("priority"); len(v) > 0 {
+		p, ok := v1.Todo_Priority_value[strings.ToUpper(v)]
+		if !ok {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("priority '%s' is unknown", v))
+		}
+		td.Priority = v1.Todo_Priority(p)
+	}
+
+	if td.Reminder, err = parseTimestamp("reminder", get("reminder")); err != nil {
+		return nil, err
+	}
+	if td.DueDate, err = parseTimestamp("due_date", get("due_date")); err != nil {
+		return nil, err
+	}
+	if td.CompletedAt, err = parseTimestamp("completed_at", get("completed_at")); err != nil {
+		return nil, err
+	}
+
+	for _, l := range strings.Split(get("labels"), ";") {
+		if l = strings.TrimSpace(l); len(l) > 0 {
+			td.Labels = append(td.Labels, l)
+		}
+	}
+
+	return td, nil
+}
+
+// icalEncoder writes tasks as VTODO components of VCALENDAR
+type icalEncoder struct{}
+
+// calendar is VCALENDAR of exported tasks without tasks
+var calendar = func() *ical.Component {
+	c := ical.NewComponent("VCALENDAR")
+	c.Add("VERSION", "2.0")
+	c.Add("PRODID", icalProdID)
+	return c
+}()
+
+func (icalEncoder) begin(w io.Writer) error {
+	return ical.EncodeBegin(w, calendar)
+}
+
+func (icalEncoder) encode(w io.Writer, td *v1.Todo) error {
+	c, err := todoComponent(td)
+	if err != nil {
+		return err
+	}
+	return ical.Encode(w, c)
+}
+
+func (icalEncoder) end(w io.Writer) error {
+	return ical.EncodeEnd(w, calendar)
+}
+
+// todoComponent returns VTODO of task, reminder is VALARM with absolute trigger
+func todoComponent(td *v1.Todo) (*ical.Component, error) {
+	c := ical.NewComponent("VTODO")
+	c.Add("UID", strconv.FormatInt(td.Id, 10)+icalUIDSuffix)
+
+	stamp := td.UpdateTime
+	if stamp == nil {
+		stamp = ptypes.TimestampNow()
+	}
+	if err := addTime(c, "DTSTAMP", stamp); err != nil {
+		return nil, err
+	}
+	if err := addTime(c, "CREATED", td.CreateTime); err != nil {
+		return nil, err
+	}
+	if err := addTime(c, "LAST-MODIFIED", td.UpdateTime); err != nil {
+		return nil, err
+	}
+
+	c.Add("SUMMARY", ical.EscapeText(td.Title))
+	if len(td.Description) > 0 {
+		c.Add("DESCRIPTION", ical.EscapeText(td.Description))
+	}
+	if st, ok := icalStatuses[td.Status]; ok {
+		c.Add("STATUS", st)
+	}
+	if p, ok := icalPriorities[td.Priority]; ok {
+		c.Add("PRIORITY", strconv.Itoa(p))
+	}
+	if err := addTime(c, "DUE", td.DueDate); err != nil {
+		return nil, err
+	}
+	if err := addTime(c, "COMPLETED", td.CompletedAt); err != nil {
+		return nil, err
+	}
+	if len(td.Labels) > 0 {
+		c.Add("CATEGORIES", ical.JoinText(td.Labels))
+	}
+	if td.ParentId != 0 {
+		c.Add("RELATED-TO", strconv.FormatInt(td.ParentId, 10)+icalUIDSuffix)
+	}
+	if td.ListId != 0 {
+		c.Add(icalListProperty, strconv.FormatInt(td.ListId, 10))
+	}
+
+	// recurrence is expanded from reminder in time zone of the task
+	if len(td.Recurrence) > 0 && td.Reminder != nil {
+		_, loc, err := checkRecurrence(td.Recurrence, td.TimeZone)
+		if err != nil {
+			return nil, err
+		}
+		start, err := ptypes.Timestamp(td.Reminder)
+		if err != nil {
+			return nil, err
+		}
+		if len(td.TimeZone) > 0 {
+			c.Add("DTSTART", start.In(loc).Format("20060102T150405"), "TZID", td.TimeZone)
+		} else {
+			c.Add("DTSTART", ical.FormatTime(start))
+		}
+		c.Add("RRULE", td.Recurrence)
+	}
+
+	if td.Reminder != nil {
+		reminder, err := ptypes.Timestamp(td.Reminder)
+		if err != nil {
+			return nil, err
+		}
+		alarm := ical.NewComponent("VALARM")
+		alarm.Add("ACTION", "DISPLAY")
+		alarm.Add("DESCRIPTION", ical.EscapeText(td.Title))
+		alarm.Add("TRIGGER", ical.FormatTime(reminder), "VALUE", "DATE-TIME")
+		c.Components = append(c.Components, alarm)
+	}
+
+	return c, nil
+}
+
+// addTime adds DATE-TIME property in UTC if time is set
+func addTime(c *ical.Component, name string, ts *timestamp.Timestamp) error {
+	if ts == nil {
+		return nil
+	}
+	t, err := ptypes.Timestamp(ts)
+	if err != nil {
+		return err
+	}
+	c.Add(name, ical.FormatTime(t))
+	return nil
+}
+
+// decodeICal reads VTODO components of all VCALENDAR components in data
+func decodeICal(data []byte) ([]*importRow, error) {
+	var rows []*importRow
+
+	d := ical.NewDecoder(bytes.NewReader(data))
+	for {
+		cal, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "failed to read iCalendar-> "+err.Error())
+		}
+		if cal.Name != "VCALENDAR" {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("expected VCALENDAR, got %s", cal.Name))
+		}
+
+		for _, c := range cal.Children("VTODO") {
+			r := &importRow{row: len(rows) + 1, key: c.Value("UID")}
+			rows = append(rows, r)
+			if rel := c.Get("RELATED-TO"); rel != nil && (rel.Param("RELTYPE") == "" || strings.EqualFold(rel.Param("RELTYPE"), "PARENT")) {
+				r.parentKey = rel.Value
+			}
+			r.todo, r.err = parseTodoComponent(c)
+		}
+	}
+
+	return rows, nil
+}
+
+// parseTodoComponent returns task from VTODO. Reminder is the trigger of the first VALARM,
+// DTSTART is used for recurring task without alarms
+func parseTodoComponent(c *ical.Component) (*v1.Todo, error) {
+	td := &v1.Todo{
+		Title:       ical.UnescapeText(c.Value("SUMMARY")),
+		Description: ical.UnescapeText(c.Value("DESCRIPTION")),
+		Recurrence:  c.Value("RRULE"),
+	}
+
+	for st, v := range icalStatuses {
+		if strings.EqualFold(c.Value("STATUS"), v) {
+			td.Status = st
+		}
+	}
+	if v := c.Value("PRIORITY"); len(v) > 0 {
+		p, err := strconv.Atoi(v)
+		if err != nil || p < 0 || p > 9 {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("PRIORITY '%s' is invalid", v))
+		}
+		switch {
+		case p == 0:
+		case p <= 2:
+			td.Priority = v1.Todo_URGENT
+		case p <= 4:
+			td.Priority = v1.Todo_HIGH
+		case p == 5:
+			td.Priority = v1.Todo_MEDIUM
+		default:
+			td.Priority = v1.Todo_LOW
+		}
+	}
+	if v := c.Value("CATEGORIES"); len(v) > 0 {
+		td.Labels = ical.SplitText(v)
+	}
+
+	// parent is resolved by RELATED-TO, ID is used if the parent is not imported with the task
+	if rel := c.Value("RELATED-TO"); strings.HasSuffix(rel, icalUIDSuffix) {
+		td.ParentId, _ = strconv.ParseInt(strings.TrimSuffix(rel, icalUIDSuffix), 10, 64)
+	}
+	if v := c.Value(icalListProperty); len(v) > 0 {
+		td.ListId, _ = strconv.ParseInt(v, 10, 64)
+	}
+
+	times := map[string]*time.Time{}
+	for _, name := range []string{"DTSTART", "DUE", "COMPLETED"} {
+		if p := c.Get(name); p != nil {
+			t, err := ical.ParseTime(p, time.UTC)
+			if err != nil {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
+			times[name] = &t
+		}
+	}
+	if p := c.Get("DTSTART"); p != nil {
+		td.TimeZone = p.Param("TZID")
+	}
+
+	var err error
+	if t := times["DUE"]; t != nil {
+		if td.DueDate, err = ptypes.TimestampProto(*t); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "DUE is invalid-> "+err.Error())
+		}
+	}
+	if t := times["COMPLETED"]; t != nil {
+		if td.CompletedAt, err = ptypes.TimestampProto(*t); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "COMPLETED is invalid-> "+err.Error())
+		}
+	}
+
+	// task without alarm isn't reminded, except recurring one, its series starts at DTSTART
+	reminder, err := alarmTime(c, times["DTSTART"], times["DUE"])
+	if err != nil {
+		return nil, err
+	}
+	if reminder == nil && len(td.Recurrence) > 0 {
+		reminder = times["DTSTART"]
+	}
+	if reminder != nil {
+		if td.Reminder, err = ptypes.TimestampProto(*reminder); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "reminder is invalid-> "+err.Error())
+		}
+	}
+
+	return td, nil
+}
+
+// alarmTime returns time of the first VALARM of task, nil if the task has no alarms.
+// Relative trigger is from DTSTART or from DUE if RELATED=END
+func alarmTime(c *ical.Component, start, due *time.Time) (*time.Time, error) {
+	alarms := c.Children("VALARM")
+	if len(alarms) == 0 {
+		return nil, nil
+	}
+
+	p := alarms[0].Get("TRIGGER")
+	if p == nil {
+		return nil, status.Error(codes.InvalidArgument, "VALARM must have TRIGGER")
+	}
+
+	if strings.EqualFold(p.Param("VALUE"), "DATE-TIME") {
+		t, err := ical.ParseTime(p, time.UTC)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return &t, nil
+	}
+
+	d, err := ical.ParseDuration(p.Value)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "TRIGGER is invalid-> "+err.Error())
+	}
+	base, related := start, "DTSTART"
+	if strings.EqualFold(p.Param("RELATED"), "END") {
+		base, related = due, "DUE"
+	}
+	if base == nil {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("TRIGGER is relative to %s, but VTODO has no %s", related, related))
+	}
+
+	t := base.Add(d)
+	return &t, nil
+}
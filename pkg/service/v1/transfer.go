@@ -0,0 +1,281 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// exportBatchSize is number of tasks read from database and sent in one chunk of export
+	exportBatchSize = 500
+	// maxImportSize is maximum size of imported data in bytes
+	maxImportSize = 32 << 20
+	// maxImportRows is maximum number of tasks in one import
+	maxImportRows = 10000
+)
+
+// Export todo tasks in chunks of data
+func (s *todoServiceServer) ExportTodos(req *v1.ExportTodosRequest, stream v1.TodoService_ExportTodosServer) error {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return err
+	}
+
+	enc, err := newTodoEncoder(req.Format)
+	if err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if req.ListId != 0 {
+		if err := checkList(ctx, c, req.ListId); err != nil {
+			return err
+		}
+	}
+
+	send := func(buf *bytes.Buffer) error {
+		if buf.Len() == 0 {
+			return nil
+		}
+		return stream.Send(&v1.ExportTodosResponse{
+			Api:  API_VERSION,
+			Data: buf.Bytes(),
+		})
+	}
+
+	buf := &bytes.Buffer{}
+	if err := enc.begin(buf); err != nil {
+		return status.Error(codes.Internal, "failed to encode export-> "+err.Error())
+	}
+
+	var last int64
+	for {
+		list, err := queryTodos(ctx, c, "SELECT "+todoColumns+" FROM todo WHERE deleted_at IS NULL AND id > ? AND (? = 0 OR list_id = ?) ORDER BY id LIMIT ?",
+			last, req.ListId, req.ListId, exportBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, td := range list {
+			if err := enc.encode(buf, td); err != nil {
+				return status.Error(codes.Internal, fmt.Sprintf("failed to encode Todo with ID='%d'-> %v", td.Id, err))
+			}
+			last = td.Id
+		}
+		if len(list) < exportBatchSize {
+			break
+		}
+
+		if err := send(buf); err != nil {
+			return err
+		}
+		buf = &bytes.Buffer{}
+	}
+
+	if err := enc.end(buf); err != nil {
+		return status.Error(codes.Internal, "failed to encode export-> "+err.Error())
+	}
+	return send(buf)
+}
+
+// Import todo tasks from chunks of data
+func (s *todoServiceServer) ImportTodos(stream v1.TodoService_ImportTodosServer) error {
+	ctx := stream.Context()
+
+	first, data, err := receiveImport(stream)
+	if err != nil {
+		return err
+	}
+
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(first.Api); err != nil {
+		return err
+	}
+
+	rows, err := decodeTodos(first.Format, data)
+	if err != nil {
+		return err
+	}
+	if len(rows) > maxImportRows {
+		return status.Error(codes.InvalidArgument, fmt.Sprintf("import can't have more than %d tasks", maxImportRows))
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if first.ListId != 0 {
+		if err := checkList(ctx, c, first.ListId); err != nil {
+			return err
+		}
+	}
+
+	tx, err := c.BeginTx(ctx, nil)
+	if err != nil {
+		return status.Error(codes.Unknown, "failed to start transaction-> "+err.Error())
+	}
+	defer tx.Rollback()
+
+	imported, errs, err := importRows(ctx, tx, rows, first.ListId)
+	if err != nil {
+		return err
+	}
+
+	if !first.DryRun && imported > 0 {
+		if err := tx.Commit(); err != nil {
+			return status.Error(codes.Unknown, "failed to commit transaction-> "+err.Error())
+		}
+		s.events.notify()
+	}
+
+	return stream.SendAndClose(&v1.ImportTodosResponse{
+		Api:      API_VERSION,
+		Imported: imported,
+		Errors:   errs,
+		DryRun:   first.DryRun,
+	})
+}
+
+// receiveImport returns the first chunk of import with options and the whole imported data
+func receiveImport(stream v1.TodoService_ImportTodosServer) (*v1.ImportTodosRequest, []byte, error) {
+	var (
+		first *v1.ImportTodosRequest
+		data  []byte
+	)
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if first == nil {
+			first = req
+		}
+		if len(data)+len(req.Data) > maxImportSize {
+			return nil, nil, status.Error(codes.InvalidArgument, fmt.Sprintf("import data can't be larger than %d bytes", maxImportSize))
+		}
+		data = append(data, req.Data...)
+	}
+
+	if first == nil {
+		return nil, nil, status.Error(codes.InvalidArgument, "import request is empty")
+	}
+
+	return first, data, nil
+}
+
+// importRows creates tasks of rows in transaction and returns number of created tasks and errors of the others.
+// Every task is created in its own savepoint, so failed task doesn't affect the others.
+// Subtasks are created after their parents if parents are imported with them, parent IDs are replaced
+// with IDs of created parents. Tasks are created in listID if it's not 0, subtasks in the list of their parents
+func importRows(ctx context.Context, tx *sql.Tx, rows []*importRow, listID int64) (int64, []*v1.ImportError, error) {
+	byKey := map[string]*importRow{}
+	for _, r := range rows {
+		if len(r.key) > 0 && r.err == nil {
+			if prev, ok := byKey[r.key]; ok {
+				r.err = status.Error(codes.InvalidArgument, fmt.Sprintf("task has the same identifier '%s' as task in row %d", r.key, prev.row))
+				continue
+			}
+			byKey[r.key] = r
+		}
+	}
+
+	created := map[*importRow]int64{}
+	var imported int64
+
+	pending := rows
+	for len(pending) > 0 {
+		var deferred []*importRow
+		for _, r := range pending {
+			if r.err != nil {
+				continue
+			}
+
+			if parent, ok := byKey[r.parentKey]; ok && len(r.parentKey) > 0 {
+				if parent.err != nil {
+					r.err = status.Error(codes.FailedPrecondition, fmt.Sprintf("parent task in row %d failed to import", parent.row))
+					continue
+				}
+				id, ok := created[parent]
+				if !ok {
+					deferred = append(deferred, r)
+					continue
+				}
+				r.todo.ParentId = id
+			}
+
+			if listID != 0 {
+				r.todo.ListId = listID
+			}
+			if r.todo.ParentId != 0 {
+				r.todo.ListId = 0
+			}
+
+			// completion time is kept, so completed tasks are imported as they were exported
+			completedAt, err := nullTime(r.todo.CompletedAt)
+			if err != nil {
+				r.err = status.Error(codes.InvalidArgument, "completed_at field has invalid format-> "+err.Error())
+				continue
+			}
+
+			if _, err := tx.ExecContext(ctx, "SAVEPOINT import_row"); err != nil {
+				return 0, nil, status.Error(codes.Unknown, "failed to create savepoint-> "+err.Error())
+			}
+			id, err := insertTodo(ctx, tx, r.todo, completedAt)
+			if err != nil {
+				if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT import_row"); err != nil {
+					return 0, nil, status.Error(codes.Unknown, "failed to rollback to savepoint-> "+err.Error())
+				}
+				r.err = err
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT import_row"); err != nil {
+				return 0, nil, status.Error(codes.Unknown, "failed to release savepoint-> "+err.Error())
+			}
+			created[r] = id
+			imported++
+		}
+
+		// parents of the rest are subtasks of each other
+		if len(deferred) == len(pending) {
+			for _, r := range deferred {
+				r.err = status.Error(codes.InvalidArgument, "task is in a cycle of parent tasks")
+			}
+			break
+		}
+		pending = deferred
+	}
+
+	var errs []*v1.ImportError
+	for _, r := range rows {
+		if r.err != nil {
+			errs = append(errs, &v1.ImportError{
+				Row:    int64(r.row),
+				Status: status.Convert(r.err).Proto(),
+			})
+		}
+	}
+
+	return imported, errs, nil
+}
@@ -0,0 +1,93 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/protobuf/ptypes"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+func TestTransferRoundTrip(t *testing.T) {
+	reminder, _ := ptypes.TimestampProto(testTime.Add(time.Hour))
+	completedAt, _ := ptypes.TimestampProto(testTime.Add(-24 * time.Hour))
+
+	exported := []*v1.Todo{
+		{Id: 1, Title: "Call Bob", Status: v1.Todo_DONE, CompletedAt: completedAt, ListId: 2},
+		{Id: 2, Title: "Buy milk", Status: v1.Todo_OPEN, Reminder: reminder, ListId: 2},
+	}
+
+	for _, format := range []v1.TodoFormat{v1.TodoFormat_JSON_LINES, v1.TodoFormat_CSV, v1.TodoFormat_ICALENDAR} {
+		t.Run(format.String(), func(t *testing.T) {
+			enc, err := newTodoEncoder(format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := enc.begin(&buf); err != nil {
+				t.Fatal(err)
+			}
+			for _, td := range exported {
+				if err := enc.encode(&buf, td); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := enc.end(&buf); err != nil {
+				t.Fatal(err)
+			}
+
+			rows, err := decodeTodos(format, buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// imported tasks are inserted with completion time and reminder they were exported with
+			s, mock := newMock(t)
+			mock.ExpectBegin()
+			for i, td := range exported {
+				mock.ExpectExec("SAVEPOINT import_row").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("SELECT id FROM todo_list WHERE id=?").WithArgs(td.ListId).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(td.ListId))
+				mock.ExpectExec("INSERT INTO todo(").
+					WithArgs(td.Title, "", nullableTime(td.Reminder), int32(td.Status), nullableTime(td.CompletedAt), nil,
+						int32(0), sqlmock.AnyArg(), sqlmock.AnyArg(), td.ListId, int64(0), "", "", nil).
+					WillReturnResult(sqlmock.NewResult(int64(10+i), 1))
+				expectEmit(mock, v1.TodoEvent_CREATED, &v1.Todo{Id: int64(10 + i), ListId: td.ListId})
+				mock.ExpectExec("RELEASE SAVEPOINT import_row").WillReturnResult(sqlmock.NewResult(0, 0))
+			}
+			mock.ExpectRollback()
+
+			tx, err := s.db.Begin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tx.Rollback()
+
+			imported, errs, err := importRows(context.Background(), tx, rows, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if imported != int64(len(exported)) || len(errs) > 0 {
+				t.Errorf("importRows() = %d, %v, want %d tasks without errors", imported, errs, len(exported))
+			}
+		})
+	}
+}
+
+func TestParseTodoComponentWithoutReminder(t *testing.T) {
+	rows, err := decodeICal([]byte("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VTODO\r\nUID:a\r\nSUMMARY:Call Bob\r\n" +
+		"DUE:20211001T090000Z\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].err != nil {
+		t.Fatalf("decodeICal() = %v, want one task", rows)
+	}
+	if td := rows[0].todo; td.Reminder != nil || td.DueDate == nil {
+		t.Errorf("decodeICal() task reminder = %v, due date = %v, want due date only", td.Reminder, td.DueDate)
+	}
+}
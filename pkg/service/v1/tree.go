@@ -0,0 +1,311 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+	"github.com/go-sql-driver/mysql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// MaxTreeDepth is maximum number of levels in a tree of todo tasks including the top-level task
+	MaxTreeDepth = 5
+
+	// errDeadlock is MySQL error number of transaction rolled back by deadlock detection
+	errDeadlock = 1213
+)
+
+// inClause returns "(?,?,...)" for the number of arguments
+func inClause(n int) string {
+	return "(?" + strings.Repeat(",?", n-1) + ")"
+}
+
+// int64Args converts IDs to query arguments
+func int64Args(ids []int64) []interface{} {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return args
+}
+
+// queryIDs returns IDs selected by query
+func queryIDs(ctx context.Context, c queryer, query string, args ...interface{}) ([]int64, error) {
+	rows, err := c.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to select from todo-> "+err.Error())
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, status.Error(codes.Unknown, "failed to retrieve field values from todo row-> "+err.Error())
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to retrieve data from todo-> "+err.Error())
+	}
+
+	return ids, nil
+}
+
+// descendants returns IDs of subtasks of todo task at any level matched by condition
+// and the number of levels below the task
+func descendants(ctx context.Context, c queryer, id int64, cond string, args ...interface{}) ([]int64, int, error) {
+	var all []int64
+	height := 0
+	level := []int64{id}
+	for len(level) > 0 && height < MaxTreeDepth {
+		ids, err := queryIDs(ctx, c, "SELECT id FROM todo WHERE parent_id IN "+inClause(len(level))+" AND "+cond,
+			append(int64Args(level), args...)...)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(ids) > 0 {
+			height++
+		}
+		all = append(all, ids...)
+		level = ids
+	}
+
+	return all, height, nil
+}
+
+// lockError returns error of locking query, deadlock of concurrent tree changes is Aborted
+// so clients can retry the call
+func lockError(err error) error {
+	if e, ok := err.(*mysql.MySQLError); ok && e.Number == errDeadlock {
+		return status.Error(codes.Aborted, "concurrent change of todo tree, retry the call-> "+err.Error())
+	}
+	return status.Error(codes.Unknown, "failed to select from todo-> "+err.Error())
+}
+
+// checkParent checks todo task (0 for a new one) with subtasks height levels deep
+// can be a subtask of parent. It returns list ID of the parent.
+// Ancestors are locked until the transaction ends, so concurrent changes can't make a cycle
+func checkParent(ctx context.Context, c queryer, id, parentID int64, height int) (int64, error) {
+	if parentID == id && id != 0 {
+		return 0, status.Error(codes.InvalidArgument, "Todo can't be subtask of itself")
+	}
+
+	var listID int64
+	d := 0
+	for current := parentID; current != 0; d++ {
+		if current == id && id != 0 {
+			return 0, status.Error(codes.FailedPrecondition, fmt.Sprintf("Todo with ID='%d' is subtask of Todo with ID='%d', it can't be its parent", parentID, id))
+		}
+		if d >= MaxTreeDepth {
+			break
+		}
+
+		var next, l int64
+		err := c.QueryRowContext(ctx, "SELECT parent_id, list_id FROM todo WHERE id=? AND deleted_at IS NULL FOR UPDATE", current).Scan(&next, &l)
+		if err == sql.ErrNoRows {
+			if current == parentID {
+				return 0, status.Error(codes.NotFound, fmt.Sprintf("parent Todo with ID='%d' is not found", parentID))
+			}
+			// ancestor is deleted or purged, the tree is cut here
+			break
+		}
+		if err != nil {
+			return 0, lockError(err)
+		}
+		if current == parentID {
+			listID = l
+		}
+		current = next
+	}
+
+	if d+height+1 > MaxTreeDepth {
+		return 0, status.Error(codes.FailedPrecondition, fmt.Sprintf("tree of todo tasks can't be deeper than %d levels", MaxTreeDepth))
+	}
+
+	return listID, nil
+}
+
+// loadProgress counts done and total subtasks of todo tasks, cancelled subtasks are not counted
+func loadProgress(ctx context.Context, c queryer, todos []*v1.Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*v1.Todo, len(todos))
+	ids := make([]int64, 0, len(todos))
+	for _, td := range todos {
+		td.Progress = &v1.Progress{}
+		byID[td.Id] = td
+		ids = append(ids, td.Id)
+	}
+
+	rows, err := c.QueryContext(ctx, "SELECT parent_id, SUM(status = ?), COUNT(*) FROM todo WHERE parent_id IN "+inClause(len(ids))+
+		" AND deleted_at IS NULL AND status != ? GROUP BY parent_id",
+		append(append([]interface{}{int32(v1.Todo_DONE)}, int64Args(ids)...), int32(v1.Todo_CANCELLED))...)
+	if err != nil {
+		return status.Error(codes.Unknown, "failed to select from todo-> "+err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var done, total int32
+		if err := rows.Scan(&id, &done, &total); err != nil {
+			return status.Error(codes.Unknown, "failed to retrieve field values from todo row-> "+err.Error())
+		}
+		if td, ok := byID[id]; ok {
+			td.Progress.Done = done
+			td.Progress.Total = total
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return status.Error(codes.Unknown, "failed to retrieve data from todo-> "+err.Error())
+	}
+
+	return nil
+}
+
+// Read todo task with its subtasks
+func (s *todoServiceServer) GetTree(ctx context.Context, req *v1.GetTreeRequest) (*v1.GetTreeResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	maxDepth := int(req.MaxDepth)
+	if maxDepth <= 0 || maxDepth > MaxTreeDepth {
+		maxDepth = MaxTreeDepth
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	list, err := queryTodos(ctx, c, "SELECT "+todoColumns+" FROM todo WHERE id=? AND deleted_at IS NULL", req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("Todo with ID='%d' is not found", req.Id))
+	}
+
+	// Read the tree level by level
+	root := &v1.TodoNode{Todo: list[0]}
+	level := map[int64]*v1.TodoNode{root.Todo.Id: root}
+	for d := 1; d < maxDepth && len(level) > 0; d++ {
+		ids := make([]int64, 0, len(level))
+		for id := range level {
+			ids = append(ids, id)
+		}
+
+		children, err := queryTodos(ctx, c, "SELECT "+todoColumns+" FROM todo WHERE parent_id IN "+inClause(len(ids))+
+			" AND deleted_at IS NULL ORDER BY id", int64Args(ids)...)
+		if err != nil {
+			return nil, err
+		}
+
+		next := make(map[int64]*v1.TodoNode, len(children))
+		for _, td := range children {
+			node := &v1.TodoNode{Todo: td}
+			level[td.ParentId].Children = append(level[td.ParentId].Children, node)
+			next[td.Id] = node
+		}
+		level = next
+	}
+
+	return &v1.GetTreeResponse{
+		Api:  API_VERSION,
+		Root: root,
+	}, nil
+}
+
+// Make todo task subtask of another task or top-level task
+func (s *todoServiceServer) SetParent(ctx context.Context, req *v1.SetParentRequest) (*v1.SetParentResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := s.checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	tx, err := c.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to start transaction-> "+err.Error())
+	}
+	defer tx.Rollback()
+
+	if err := setParent(ctx, tx, req.Id, req.ParentId); err != nil {
+		return nil, err
+	}
+
+	if err := emit(ctx, tx, v1.TodoEvent_UPDATED, req.Id); err != nil {
+		return nil, err
+	}
+
+	list, err := queryTodos(ctx, tx, "SELECT "+todoColumns+" FROM todo WHERE id=?", req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to commit transaction-> "+err.Error())
+	}
+	s.events.notify()
+
+	return &v1.SetParentResponse{
+		Api:  API_VERSION,
+		Todo: list[0],
+	}, nil
+}
+
+// setParent makes todo task subtask of parent or top-level task if parent is 0 in transaction,
+// the task is locked before its ancestors like in checkParent
+func setParent(ctx context.Context, tx *sql.Tx, id, parentID int64) error {
+	var listID int64
+	if err := tx.QueryRowContext(ctx, "SELECT list_id FROM todo WHERE id=? AND deleted_at IS NULL FOR UPDATE", id).Scan(&listID); err != nil {
+		if err == sql.ErrNoRows {
+			return status.Error(codes.NotFound, fmt.Sprintf("Todo with ID='%d' is not found", id))
+		}
+		return lockError(err)
+	}
+
+	if parentID != 0 {
+		_, height, err := descendants(ctx, tx, id, "deleted_at IS NULL")
+		if err != nil {
+			return err
+		}
+
+		parentListID, err := checkParent(ctx, tx, id, parentID, height)
+		if err != nil {
+			return err
+		}
+		if parentListID != listID {
+			return status.Error(codes.FailedPrecondition, "parent Todo is in another list, use MoveTodo first")
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE todo SET parent_id=?, update_time=? WHERE id=?",
+		parentID, time.Now().In(time.UTC), id); err != nil {
+		return status.Error(codes.Unknown, "failed to update Todo-> "+err.Error())
+	}
+
+	return nil
+}
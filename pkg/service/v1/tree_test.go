@@ -0,0 +1,139 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ancestorQuery is query of checkParent, ancestors must be locked
+const ancestorQuery = `SELECT parent_id, list_id FROM todo WHERE id=\? AND deleted_at IS NULL FOR UPDATE`
+
+func TestCheckParent(t *testing.T) {
+	// ancestor is parent ID and list ID of a task
+	type ancestor struct {
+		id, parentID, listID int64
+	}
+
+	tests := []struct {
+		name      string
+		id        int64
+		parentID  int64
+		height    int
+		ancestors []ancestor
+		missing   int64
+		lockErr   error
+		want      int64
+		wantCode  codes.Code
+	}{
+		{
+			name:      "top-level parent",
+			id:        1,
+			parentID:  2,
+			ancestors: []ancestor{{2, 0, 7}},
+			want:      7,
+		},
+		{
+			name:      "new task",
+			parentID:  2,
+			ancestors: []ancestor{{2, 3, 7}, {3, 0, 7}},
+			want:      7,
+		},
+		{name: "itself", id: 1, parentID: 1, wantCode: codes.InvalidArgument},
+		{
+			name:      "cycle",
+			id:        1,
+			parentID:  3,
+			ancestors: []ancestor{{3, 2, 7}, {2, 1, 7}},
+			wantCode:  codes.FailedPrecondition,
+		},
+		{name: "parent not found", id: 1, parentID: 2, missing: 2, wantCode: codes.NotFound},
+		{
+			name:      "deleted ancestor cuts tree",
+			id:        1,
+			parentID:  2,
+			ancestors: []ancestor{{2, 3, 7}},
+			missing:   3,
+			want:      7,
+		},
+		{
+			name:      "too deep",
+			id:        1,
+			parentID:  2,
+			height:    MaxTreeDepth - 2,
+			ancestors: []ancestor{{2, 3, 7}, {3, 0, 7}},
+			wantCode:  codes.FailedPrecondition,
+		},
+		{
+			name:     "deadlock",
+			id:       1,
+			parentID: 2,
+			lockErr:  &mysql.MySQLError{Number: errDeadlock, Message: "Deadlock found when trying to get lock"},
+			wantCode: codes.Aborted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			for _, a := range tt.ancestors {
+				mock.ExpectQuery(ancestorQuery).WithArgs(a.id).
+					WillReturnRows(sqlmock.NewRows([]string{"parent_id", "list_id"}).AddRow(a.parentID, a.listID))
+			}
+			if tt.missing != 0 {
+				mock.ExpectQuery(ancestorQuery).WithArgs(tt.missing).WillReturnRows(sqlmock.NewRows([]string{"parent_id", "list_id"}))
+			}
+			if tt.lockErr != nil {
+				mock.ExpectQuery(ancestorQuery).WithArgs(tt.parentID).WillReturnError(tt.lockErr)
+			}
+
+			got, err := checkParent(context.Background(), db, tt.id, tt.parentID, tt.height)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("checkParent() error = %v, want code %s", err, tt.wantCode)
+			}
+			if got != tt.want {
+				t.Errorf("checkParent() = %d, want %d", got, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestDescendants(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT id FROM todo WHERE parent_id IN \(\?\) AND deleted_at IS NULL`).WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(3))
+	mock.ExpectQuery(`SELECT id FROM todo WHERE parent_id IN \(\?,\?\) AND deleted_at IS NULL`).WithArgs(2, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectQuery(`SELECT id FROM todo WHERE parent_id IN \(\?\) AND deleted_at IS NULL`).WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	ids, height, err := descendants(context.Background(), db, 1, "deleted_at IS NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 3 || ids[0] != 2 || ids[1] != 3 || ids[2] != 4 {
+		t.Errorf("descendants() = %v, want [2 3 4]", ids)
+	}
+	if height != 2 {
+		t.Errorf("descendants() height = %d, want 2", height)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
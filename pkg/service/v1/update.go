@@ -0,0 +1,79 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UpdateTodo changes todo task in one transaction, list of the task isn't changed
+func (s *todoServiceServer) UpdateTodo(ctx context.Context, listID, id int64, change func(td *v1.Todo) error) (*v1.Todo, error) {
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	tx, err := c.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to start transaction-> "+err.Error())
+	}
+	defer tx.Rollback()
+
+	// Lock the task, so it isn't changed between read and write
+	td, err := lockTodo(ctx, tx, listID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	parentID, labels := td.ParentId, td.Labels
+	if err := change(td); err != nil {
+		return nil, err
+	}
+	td.Id = id
+
+	if td.ParentId != parentID {
+		if err := setParent(ctx, tx, id, td.ParentId); err != nil {
+			return nil, err
+		}
+	}
+	if err := replaceLabels(ctx, tx, id, labels, td.Labels); err != nil {
+		return nil, err
+	}
+
+	// the event of update has the task with new labels and parent
+	if _, err := updateTodo(ctx, tx, td); err != nil {
+		return nil, err
+	}
+
+	list, err := queryTodos(ctx, tx, "SELECT "+todoColumns+" FROM todo WHERE id=?", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to commit transaction-> "+err.Error())
+	}
+	s.events.notify()
+
+	return list[0], nil
+}
+
+// lockTodo reads todo task locked until the end of transaction,
+// the task is not found if list ID isn't 0 and the task is in another list
+func lockTodo(ctx context.Context, tx *sql.Tx, listID, id int64) (*v1.Todo, error) {
+	list, err := queryTodos(ctx, tx, "SELECT "+todoColumns+" FROM todo WHERE id=? AND (? = 0 OR list_id = ?) AND deleted_at IS NULL FOR UPDATE",
+		id, listID, listID)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("Todo with ID='%d' is not found", id))
+	}
+	return list[0], nil
+}
@@ -0,0 +1,104 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+// lockQuery is query of UpdateTodo locking the task
+const lockQuery = "SELECT " + todoColumns + " FROM todo WHERE id=? AND (? = 0 OR list_id = ?) AND deleted_at IS NULL FOR UPDATE"
+
+func TestUpdateTodo(t *testing.T) {
+	old := &v1.Todo{Id: 1, ListId: 2, Title: "Call Bob"}
+
+	tests := []struct {
+		name     string
+		listID   int64
+		found    bool
+		change   func(td *v1.Todo) error
+		expect   func(mock sqlmock.Sqlmock)
+		wantCode codes.Code
+	}{
+		{
+			name:  "fields and labels in one transaction",
+			found: true,
+			change: func(td *v1.Todo) error {
+				td.Title, td.Labels = "Call Alice", []string{"home", " home"}
+				return nil
+			},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("DELETE FROM todo_label WHERE todo_id=?").WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT IGNORE INTO todo_label").WithArgs(int64(1), "home").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE todo SET title=?").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name:  "parent in the same list",
+			found: true,
+			change: func(td *v1.Todo) error {
+				td.ParentId = 3
+				return nil
+			},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("SELECT list_id FROM todo WHERE id=?").WithArgs(int64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"list_id"}).AddRow(2))
+				mock.ExpectQuery("SELECT id FROM todo WHERE parent_id IN").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectQuery("SELECT parent_id, list_id FROM todo WHERE id=?").WithArgs(int64(3)).
+					WillReturnRows(sqlmock.NewRows([]string{"parent_id", "list_id"}).AddRow(0, 2))
+				mock.ExpectExec("UPDATE todo SET parent_id=?").WithArgs(int64(3), sqlmock.AnyArg(), int64(1)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE todo SET title=?").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{
+			name:  "change fails",
+			found: true,
+			change: func(td *v1.Todo) error {
+				return status.Error(codes.InvalidArgument, "invalid parent")
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{name: "not found in list", listID: 5, wantCode: codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newMock(t)
+
+			mock.ExpectBegin()
+			if tt.found {
+				expectTodos(mock, lockQuery, old)
+			} else {
+				expectTodos(mock, lockQuery)
+			}
+			if tt.expect != nil {
+				tt.expect(mock)
+				expectEmit(mock, v1.TodoEvent_UPDATED, old)
+				expectTodos(mock, "SELECT "+todoColumns+" FROM todo WHERE id=?", old)
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			change := tt.change
+			if change == nil {
+				change = func(td *v1.Todo) error {
+					t.Error("change is called for missing task")
+					return nil
+				}
+			}
+			got, err := s.UpdateTodo(context.Background(), tt.listID, 1, change)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("UpdateTodo() error = %v, want code %s", err, tt.wantCode)
+			}
+			if err == nil && got.Id != 1 {
+				t.Errorf("UpdateTodo() = %v, want task 1", got)
+			}
+		})
+	}
+}
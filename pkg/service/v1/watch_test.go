@@ -0,0 +1,113 @@
+package v1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+// watchStream is WatchTodos stream recording sent events, it's cancelled after want events
+type watchStream struct {
+	grpc.ServerStream
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	want   int
+	events []*v1.TodoEvent
+}
+
+func newWatchStream(want int) *watchStream {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	return &watchStream{ctx: ctx, cancel: cancel, want: want}
+}
+
+func (s *watchStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *watchStream) Send(res *v1.WatchTodosResponse) error {
+	s.events = append(s.events, res.Event)
+	if len(s.events) == s.want {
+		s.cancel()
+	}
+	return nil
+}
+
+// expectEvents expects batch of events of list after the event, event ID is ID of its task
+func expectEvents(t *testing.T, mock sqlmock.Sqlmock, after, listID int64, ids ...int64) {
+	rows := sqlmock.NewRows([]string{"id", "type", "todo", "create_time"})
+	for _, id := range ids {
+		data, err := proto.Marshal(&v1.Todo{Id: id, ListId: listID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.AddRow(id, int32(v1.TodoEvent_UPDATED), data, testTime)
+	}
+	mock.ExpectQuery("SELECT id, type, todo, create_time FROM todo_event WHERE id > ?").
+		WithArgs(after, listID, listID, watchBatchSize).WillReturnRows(rows)
+}
+
+func TestWatchTodosResume(t *testing.T) {
+	s, mock := newMock(t)
+	stream := newWatchStream(2)
+	defer stream.cancel()
+
+	// watch resumed after event 4 gets events of the list from event 5
+	mock.ExpectQuery("SELECT 1 FROM todo_event WHERE id=?").WithArgs(int64(4)).WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	expectEvents(t, mock, 4, 2, 5, 6)
+
+	if err := s.WatchTodos(&v1.WatchTodosRequest{Api: API_VERSION, ListId: 2, ResumeToken: "4"}, stream); err != nil {
+		t.Fatal(err)
+	}
+	if len(stream.events) != 2 || stream.events[0].ResumeToken != "5" || stream.events[1].ResumeToken != "6" ||
+		stream.events[1].Todo.Id != 6 {
+		t.Errorf("WatchTodos() sent %v, want events 5 and 6", stream.events)
+	}
+}
+
+func TestWatchTodosWakeUp(t *testing.T) {
+	s, mock := newMock(t)
+	stream := newWatchStream(1)
+	defer stream.cancel()
+
+	// new watch starts after the last event and wakes up on commit of the next one
+	mock.ExpectQuery("SELECT next_id - 1 FROM todo_event_seq WHERE id=1").WillReturnRows(sqlmock.NewRows([]string{"last"}).AddRow(7))
+	expectEvents(t, mock, 7, 0)
+	expectEvents(t, mock, 7, 0, 8)
+
+	go func() {
+		for stream.ctx.Err() == nil {
+			s.events.notify()
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	if err := s.WatchTodos(&v1.WatchTodosRequest{Api: API_VERSION}, stream); err != nil {
+		t.Fatal(err)
+	}
+	if len(stream.events) != 1 || stream.events[0].ResumeToken != "8" {
+		t.Errorf("WatchTodos() sent %v, want event 8", stream.events)
+	}
+}
+
+func TestWatchTodosResumeToken(t *testing.T) {
+	s, mock := newMock(t)
+
+	if err := s.WatchTodos(&v1.WatchTodosRequest{Api: API_VERSION, ResumeToken: "x"}, newWatchStream(1)); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("WatchTodos() error = %v, want code %s", err, codes.InvalidArgument)
+	}
+
+	// purged event can't be resumed from
+	mock.ExpectQuery("SELECT 1 FROM todo_event WHERE id=?").WithArgs(int64(4)).WillReturnRows(sqlmock.NewRows([]string{"1"}))
+	if err := s.WatchTodos(&v1.WatchTodosRequest{Api: API_VERSION, ResumeToken: "4"}, newWatchStream(1)); status.Code(err) != codes.OutOfRange {
+		t.Errorf("WatchTodos() error = %v, want code %s", err, codes.OutOfRange)
+	}
+}
@@ -0,0 +1,364 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	// WebhookSignatureHeader is header of webhook request with signature in format "t={unix time},v1={hex HMAC-SHA256}",
+	// HMAC is calculated with webhook secret over "{unix time}.{payload}"
+	WebhookSignatureHeader = "X-Todo-Signature"
+	// WebhookEventHeader is header of webhook request with event type name
+	WebhookEventHeader = "X-Todo-Event"
+	// WebhookDeliveryHeader is header of webhook request with unique integer identifier of delivery,
+	// it is the same for retries of the delivery
+	WebhookDeliveryHeader = "X-Todo-Delivery"
+
+	// webhookBatchSize is maximum number of deliveries attempted at once
+	webhookBatchSize = 100
+	// webhookClaimTime is how long delivery is claimed for attempt, it must be longer than Client timeout
+	webhookClaimTime = time.Minute
+)
+
+// SignWebhookPayload returns signature of webhook payload sent at time t
+func SignWebhookPayload(secret string, t time.Time, payload []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(payload)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks signature of webhook payload received at now,
+// signatures older than tolerance are rejected to protect from replay
+func VerifyWebhookSignature(secret, signature string, payload []byte, now time.Time, tolerance time.Duration) error {
+	var ts int64
+	var sig string
+	for _, part := range strings.Split(signature, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			ts, _ = strconv.ParseInt(kv[1], 10, 64)
+		case "v1":
+			sig = kv[1]
+		}
+	}
+	if ts == 0 || len(sig) == 0 {
+		return fmt.Errorf("signature '%s' has invalid format", signature)
+	}
+
+	t := time.Unix(ts, 0)
+	if d := now.Sub(t); d > tolerance || d < -tolerance {
+		return fmt.Errorf("signature time %s is out of tolerance", t.Format(time.RFC3339))
+	}
+
+	expected := strings.TrimPrefix(SignWebhookPayload(secret, t, payload), "t="+strconv.FormatInt(ts, 10)+",v1=")
+	if !hmac.Equal([]byte(expected), []byte(sig)) {
+		return fmt.Errorf("signature doesn't match payload")
+	}
+
+	return nil
+}
+
+// WebhookDispatcher posts change events of todo tasks to registered webhooks.
+// It fans out events recorded for WatchTodos to deliveries of matching webhooks
+// and posts pending deliveries retrying failed ones with exponential backoff.
+// Deliveries which fail MaxAttempts times are moved to dead letter log
+type WebhookDispatcher struct {
+	db  *sql.DB
+	now func() time.Time
+
+	// Client posts payloads
+	Client *http.Client
+	// Interval is how often new events and pending deliveries are checked
+	Interval time.Duration
+	// MaxAttempts is number of delivery attempts before delivery is moved to dead letter log
+	MaxAttempts int
+	// RetryDelay is delay before the first retry, it doubles with every attempt up to MaxRetryDelay
+	RetryDelay time.Duration
+	// MaxRetryDelay is maximum delay between retries
+	MaxRetryDelay time.Duration
+}
+
+// NewWebhookDispatcher returns WebhookDispatcher with default settings
+func NewWebhookDispatcher(db *sql.DB) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		db:            db,
+		now:           time.Now,
+		Client:        &http.Client{Timeout: 10 * time.Second},
+		Interval:      5 * time.Second,
+		MaxAttempts:   8,
+		RetryDelay:    30 * time.Second,
+		MaxRetryDelay: time.Hour,
+	}
+}
+
+// Run dispatches events every Interval until context is done
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := d.Dispatch(ctx); err != nil {
+			log.Printf("failed to dispatch webhooks: %v", err)
+		} else if n > 0 {
+			log.Printf("attempted %d webhook deliveries", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch fans out new events to deliveries and attempts due deliveries once,
+// it returns number of delivery attempts
+func (d *WebhookDispatcher) Dispatch(ctx context.Context) (int, error) {
+	for {
+		n, err := d.fanOut(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if n < watchBatchSize {
+			break
+		}
+	}
+
+	return d.deliver(ctx)
+}
+
+// matches returns true if webhook is interested in event
+func (d *WebhookDispatcher) matches(wh *v1.Webhook, ev *v1.TodoEvent) bool {
+	if wh.ListId != 0 && wh.ListId != ev.Todo.ListId {
+		return false
+	}
+	if len(wh.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range wh.EventTypes {
+		if t == ev.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// fanOut creates deliveries of the next batch of events to matching webhooks
+// and returns number of processed events
+func (d *WebhookDispatcher) fanOut(ctx context.Context) (int, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the cursor, so events are fanned out once by one of server instances
+	var last int64
+	if err := tx.QueryRowContext(ctx, "SELECT last_event_id FROM webhook_cursor WHERE id=1 FOR UPDATE").Scan(&last); err != nil {
+		return 0, fmt.Errorf("failed to select from webhook_cursor: %v", err)
+	}
+
+	events, next, err := queryEvents(ctx, tx, last, 0)
+	if err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT "+webhookColumns+", start_event_id FROM webhook")
+	if err != nil {
+		return 0, fmt.Errorf("failed to select from webhook: %v", err)
+	}
+	webhooks := map[*v1.Webhook]int64{}
+	for rows.Next() {
+		wh := new(v1.Webhook)
+		var types string
+		var createTime time.Time
+		var start int64
+		if err := rows.Scan(&wh.Id, &wh.Url, &types, &wh.ListId, &createTime, &start); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to retrieve field values from webhook row: %v", err)
+		}
+		if wh.EventTypes, err = parseEventTypes(types); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("event_types field of Webhook with ID='%d' has invalid format: %v", wh.Id, err)
+		}
+		webhooks[wh] = start
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to retrieve data from webhook: %v", err)
+	}
+
+	now := d.now().In(time.UTC)
+	for _, ev := range events {
+		id, err := parseResumeToken(ev.ResumeToken)
+		if err != nil {
+			return 0, err
+		}
+
+		var payload []byte
+		for wh, start := range webhooks {
+			if id <= start || !d.matches(wh, ev) {
+				continue
+			}
+			if payload == nil {
+				if payload, err = protojson.Marshal(ev); err != nil {
+					return 0, fmt.Errorf("failed to encode event: %v", err)
+				}
+			}
+			if _, err := tx.ExecContext(ctx, "INSERT IGNORE INTO webhook_delivery(webhook_id, event_id, event_type, todo_id, state, attempts, "+
+				"response_code, last_error, payload, next_attempt_time, create_time, update_time) VALUES(?,?,?,?,?,0,0,'',?,?,?,?)",
+				wh.Id, id, int32(ev.Type), ev.Todo.Id, int32(v1.WebhookDelivery_PENDING), string(payload), now, now, now); err != nil {
+				return 0, fmt.Errorf("failed to insert into webhook_delivery: %v", err)
+			}
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE webhook_cursor SET last_event_id=? WHERE id=1", next); err != nil {
+		return 0, fmt.Errorf("failed to update webhook_cursor: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return len(events), nil
+}
+
+// pendingDelivery is delivery due for attempt
+type pendingDelivery struct {
+	id          int64
+	eventType   v1.TodoEvent_Type
+	payload     string
+	attempts    int32
+	nextAttempt time.Time
+	url         string
+	secret      string
+}
+
+// deliver attempts due pending deliveries and returns number of attempts
+func (d *WebhookDispatcher) deliver(ctx context.Context) (int, error) {
+	now := d.now().In(time.UTC)
+
+	rows, err := d.db.QueryContext(ctx, "SELECT d.id, d.event_type, d.payload, d.attempts, d.next_attempt_time, w.url, w.secret "+
+		"FROM webhook_delivery d JOIN webhook w ON w.id = d.webhook_id WHERE d.state=? AND d.next_attempt_time <= ? ORDER BY d.id LIMIT ?",
+		int32(v1.WebhookDelivery_PENDING), now, webhookBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select from webhook_delivery: %v", err)
+	}
+	var list []pendingDelivery
+	for rows.Next() {
+		var p pendingDelivery
+		var eventType int32
+		if err := rows.Scan(&p.id, &eventType, &p.payload, &p.attempts, &p.nextAttempt, &p.url, &p.secret); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to retrieve field values from webhook_delivery row: %v", err)
+		}
+		p.eventType = v1.TodoEvent_Type(eventType)
+		list = append(list, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to retrieve data from webhook_delivery: %v", err)
+	}
+
+	attempted := 0
+	for _, p := range list {
+		// Claim the delivery for the time of attempt, so other server instances skip it
+		// and it is retried if the server stops during the attempt
+		res, err := d.db.ExecContext(ctx, "UPDATE webhook_delivery SET next_attempt_time=? WHERE id=? AND state=? AND next_attempt_time=?",
+			now.Add(webhookClaimTime), p.id, int32(v1.WebhookDelivery_PENDING), p.nextAttempt)
+		if err != nil {
+			return attempted, fmt.Errorf("failed to update webhook_delivery: %v", err)
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			continue
+		}
+
+		code, err := d.post(ctx, p)
+		attempted++
+		p.attempts++
+
+		st := v1.WebhookDelivery_DELIVERED
+		var lastError string
+		var next sql.NullTime
+		if err != nil {
+			lastError = err.Error()
+			if int(p.attempts) >= d.MaxAttempts {
+				st = v1.WebhookDelivery_DEAD
+				log.Printf("webhook delivery with ID='%d' to '%s' moved to dead letter log after %d attempts: %v", p.id, p.url, p.attempts, err)
+			} else {
+				st = v1.WebhookDelivery_PENDING
+				next = sql.NullTime{Time: now.Add(d.backoff(p.attempts)), Valid: true}
+			}
+		}
+
+		if _, err := d.db.ExecContext(ctx, "UPDATE webhook_delivery SET state=?, attempts=?, response_code=?, last_error=?, next_attempt_time=?, update_time=? WHERE id=?",
+			int32(st), p.attempts, code, lastError, next, d.now().In(time.UTC), p.id); err != nil {
+			return attempted, fmt.Errorf("failed to update webhook_delivery: %v", err)
+		}
+	}
+
+	return attempted, nil
+}
+
+// backoff returns delay before retry after attempts failed attempts
+func (d *WebhookDispatcher) backoff(attempts int32) time.Duration {
+	delay := d.RetryDelay
+	for i := int32(1); i < attempts && delay < d.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > d.MaxRetryDelay {
+		delay = d.MaxRetryDelay
+	}
+	return delay
+}
+
+// post sends signed payload of delivery and returns response status code,
+// any status except 2xx is error
+func (d *WebhookDispatcher) post(ctx context.Context, p pendingDelivery) (int, error) {
+	body := []byte(p.payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, p.eventType.String())
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatInt(p.id, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(p.secret, d.now(), body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,137 @@
+package v1
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	payload := []byte(`{"type":"CREATED"}`)
+	signature := SignWebhookPayload("secret", testTime, payload)
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		payload   []byte
+		now       time.Time
+		wantErr   bool
+	}{
+		{name: "valid", secret: "secret", signature: signature, payload: payload, now: testTime.Add(time.Minute)},
+		{name: "wrong secret", secret: "other", signature: signature, payload: payload, now: testTime, wantErr: true},
+		{name: "changed payload", secret: "secret", signature: signature, payload: []byte(`{"type":"DELETED"}`), now: testTime, wantErr: true},
+		{name: "replayed", secret: "secret", signature: signature, payload: payload, now: testTime.Add(time.Hour), wantErr: true},
+		{name: "from future", secret: "secret", signature: signature, payload: payload, now: testTime.Add(-time.Hour), wantErr: true},
+		{name: "invalid format", secret: "secret", signature: "v1=abc", payload: payload, now: testTime, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyWebhookSignature(tt.secret, tt.signature, tt.payload, tt.now, 5*time.Minute)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyWebhookSignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	d := &WebhookDispatcher{RetryDelay: 30 * time.Second, MaxRetryDelay: 5 * time.Minute}
+	for attempts, want := range map[int32]time.Duration{
+		1: 30 * time.Second,
+		2: time.Minute,
+		3: 2 * time.Minute,
+		4: 4 * time.Minute,
+		5: 5 * time.Minute,
+		9: 5 * time.Minute,
+	} {
+		if got := d.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestWebhookDeliver(t *testing.T) {
+	const payload = `{"type":"UPDATED"}`
+	pendingColumns := []string{"id", "event_type", "payload", "attempts", "next_attempt_time", "url", "secret"}
+
+	tests := []struct {
+		name     string
+		status   int
+		attempts int32
+		claimed  bool
+		want     v1.WebhookDelivery_State
+		wantNext sql.NullTime
+	}{
+		{name: "delivered", status: http.StatusNoContent, claimed: true, want: v1.WebhookDelivery_DELIVERED},
+		{
+			name:     "retried with backoff",
+			status:   http.StatusBadGateway,
+			attempts: 2,
+			claimed:  true,
+			want:     v1.WebhookDelivery_PENDING,
+			wantNext: sql.NullTime{Time: testTime.Add(2 * time.Minute), Valid: true},
+		},
+		{name: "moved to dead letter log", status: http.StatusInternalServerError, attempts: 7, claimed: true, want: v1.WebhookDelivery_DEAD},
+		{name: "claimed by another instance", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posted := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				posted++
+				body, _ := io.ReadAll(r.Body)
+				if err := VerifyWebhookSignature("secret", r.Header.Get(WebhookSignatureHeader), body, testTime, time.Minute); err != nil {
+					t.Errorf("invalid signature: %v", err)
+				}
+				if ev := r.Header.Get(WebhookEventHeader); ev != "UPDATED" {
+					t.Errorf("%s = %s, want UPDATED", WebhookEventHeader, ev)
+				}
+				if id := r.Header.Get(WebhookDeliveryHeader); id != "5" {
+					t.Errorf("%s = %s, want 5", WebhookDeliveryHeader, id)
+				}
+				if string(body) != payload {
+					t.Errorf("payload = %s, want %s", body, payload)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			s, mock := newMock(t)
+			d := NewWebhookDispatcher(s.db)
+			d.now = func() time.Time { return testTime }
+
+			mock.ExpectQuery("SELECT d.id, d.event_type, d.payload, d.attempts, d.next_attempt_time, w.url, w.secret FROM webhook_delivery").
+				WillReturnRows(sqlmock.NewRows(pendingColumns).
+					AddRow(5, int32(v1.TodoEvent_UPDATED), payload, tt.attempts, testTime, srv.URL, "secret"))
+			claimed := int64(0)
+			if tt.claimed {
+				claimed = 1
+			}
+			mock.ExpectExec("UPDATE webhook_delivery SET next_attempt_time=?").
+				WithArgs(testTime.Add(webhookClaimTime), int64(5), int32(v1.WebhookDelivery_PENDING), testTime).
+				WillReturnResult(sqlmock.NewResult(0, claimed))
+			if tt.claimed {
+				mock.ExpectExec("UPDATE webhook_delivery SET state=?").
+					WithArgs(int32(tt.want), tt.attempts+1, tt.status, sqlmock.AnyArg(), tt.wantNext, testTime, int64(5)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			got, err := d.deliver(context.Background())
+			if err != nil {
+				t.Fatalf("deliver() error = %v", err)
+			}
+			if want := int(claimed); got != want || posted != want {
+				t.Errorf("deliver() = %d, posted %d, want %d", got, posted, want)
+			}
+		})
+	}
+}
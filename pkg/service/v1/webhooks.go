@@ -0,0 +1,439 @@
+package v1
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// defaultDeliveriesLimit is number of ListWebhookDeliveries results if client doesn't ask for
+	defaultDeliveriesLimit = 50
+	// maxDeliveriesLimit is maximum number of ListWebhookDeliveries results
+	maxDeliveriesLimit = 500
+	// maxWebhookURLLength is maximum length of webhook URL
+	maxWebhookURLLength = 2048
+)
+
+// webhookServiceServer is implementation of v1.WebhookServiceServer proto interface
+type webhookServiceServer struct {
+	db *sql.DB
+	v1.UnimplementedWebhookServiceServer
+}
+
+func NewWebhookServiceServer(db *sql.DB) v1.WebhookServiceServer {
+	return &webhookServiceServer{db: db}
+}
+
+// connect returns SQL database connection from the pool
+func (s *webhookServiceServer) connect(ctx context.Context) (*sql.Conn, error) {
+	c, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to connect to database-> "+err.Error())
+	}
+
+	return c, nil
+}
+
+// webhookName returns resource name of webhook
+func webhookName(id int64) string {
+	return fmt.Sprintf("webhooks/%d", id)
+}
+
+// formatEventTypes converts event types to comma separated numbers stored in webhook table
+func formatEventTypes(types []v1.TodoEvent_Type) string {
+	items := make([]string, len(types))
+	for i, t := range types {
+		items[i] = strconv.Itoa(int(t))
+	}
+	return strings.Join(items, ",")
+}
+
+// parseEventTypes converts comma separated numbers stored in webhook table to event types
+func parseEventTypes(s string) ([]v1.TodoEvent_Type, error) {
+	types := []v1.TodoEvent_Type{}
+	if len(s) == 0 {
+		return types, nil
+	}
+	for _, item := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(item)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, v1.TodoEvent_Type(n))
+	}
+	return types, nil
+}
+
+// webhookColumns is list of webhook table columns read into Webhook entity by queryWebhooks
+const webhookColumns = "id, url, event_types, list_id, create_time"
+
+// queryWebhooks returns webhooks selected by query, secrets are not read
+func queryWebhooks(ctx context.Context, c queryer, query string, args ...interface{}) ([]*v1.Webhook, error) {
+	rows, err := c.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to select from webhook-> "+err.Error())
+	}
+	defer rows.Close()
+
+	list := []*v1.Webhook{}
+	for rows.Next() {
+		wh := new(v1.Webhook)
+		var types string
+		var createTime time.Time
+		if err := rows.Scan(&wh.Id, &wh.Url, &types, &wh.ListId, &createTime); err != nil {
+			return nil, status.Error(codes.Unknown, "failed to retrieve field values from webhook row-> "+err.Error())
+		}
+		wh.Name = webhookName(wh.Id)
+		if wh.EventTypes, err = parseEventTypes(types); err != nil {
+			return nil, status.Error(codes.Unknown, "event_types field has invalid format-> "+err.Error())
+		}
+		if wh.CreateTime, err = ptypes.TimestampProto(createTime); err != nil {
+			return nil, status.Error(codes.Unknown, "create_time field has invalid format-> "+err.Error())
+		}
+		list = append(list, wh)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to retrieve data from webhook-> "+err.Error())
+	}
+
+	return list, nil
+}
+
+// checkWebhookURL checks webhook URL is absolute HTTP or HTTPS URL
+func checkWebhookURL(s string) error {
+	if len(s) > maxWebhookURLLength {
+		return status.Error(codes.InvalidArgument, fmt.Sprintf("url is longer than %d characters", maxWebhookURLLength))
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "url field is invalid-> "+err.Error())
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return status.Error(codes.InvalidArgument, fmt.Sprintf("url '%s' is not absolute HTTP or HTTPS URL", s))
+	}
+	return nil
+}
+
+// newWebhookSecret returns random secret to sign payloads
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Register webhook, it receives events of changes made after registration
+func (s *webhookServiceServer) CreateWebhook(ctx context.Context, req *v1.CreateWebhookRequest) (*v1.CreateWebhookResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	if req.Webhook == nil {
+		return nil, status.Error(codes.InvalidArgument, "webhook must be specified")
+	}
+	wh := req.Webhook
+
+	if err := checkWebhookURL(wh.Url); err != nil {
+		return nil, err
+	}
+
+	for _, t := range wh.EventTypes {
+		if _, ok := v1.TodoEvent_Type_name[int32(t)]; !ok || t == v1.TodoEvent_TYPE_UNSPECIFIED {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("event type '%d' is invalid", t))
+		}
+	}
+
+	secret := wh.Secret
+	if len(secret) == 0 {
+		var err error
+		if secret, err = newWebhookSecret(); err != nil {
+			return nil, status.Error(codes.Unknown, "failed to generate secret-> "+err.Error())
+		}
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	if wh.ListId != 0 {
+		if err := checkList(ctx, c, wh.ListId); err != nil {
+			return nil, err
+		}
+	}
+
+	// Events recorded before registration are not posted to the webhook
+	var startEventID int64
+	if err := c.QueryRowContext(ctx, "SELECT next_id - 1 FROM todo_event_seq WHERE id=1").Scan(&startEventID); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to select from todo_event_seq-> "+err.Error())
+	}
+
+	now := time.Now().In(time.UTC)
+	res, err := c.ExecContext(ctx, "INSERT INTO webhook(url, secret, event_types, list_id, start_event_id, create_time) VALUES(?,?,?,?,?,?)",
+		wh.Url, secret, formatEventTypes(wh.EventTypes), wh.ListId, startEventID, now)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to insert into webhook-> "+err.Error())
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to retrieve id for created Webhook-> "+err.Error())
+	}
+
+	list, err := queryWebhooks(ctx, c, "SELECT "+webhookColumns+" FROM webhook WHERE id=?", id)
+	if err != nil {
+		return nil, err
+	}
+	list[0].Secret = secret
+
+	return &v1.CreateWebhookResponse{
+		Api:     API_VERSION,
+		Webhook: list[0],
+	}, nil
+}
+
+// Read webhook
+func (s *webhookServiceServer) GetWebhook(ctx context.Context, req *v1.GetWebhookRequest) (*v1.GetWebhookResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	list, err := queryWebhooks(ctx, c, "SELECT "+webhookColumns+" FROM webhook WHERE id=?", req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("Webhook with ID='%d' is not found", req.Id))
+	}
+
+	return &v1.GetWebhookResponse{
+		Api:     API_VERSION,
+		Webhook: list[0],
+	}, nil
+}
+
+// Read all webhooks
+func (s *webhookServiceServer) ListWebhooks(ctx context.Context, req *v1.ListWebhooksRequest) (*v1.ListWebhooksResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	list, err := queryWebhooks(ctx, c, "SELECT "+webhookColumns+" FROM webhook ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.ListWebhooksResponse{
+		Api:      API_VERSION,
+		Webhooks: list,
+	}, nil
+}
+
+// Unregister webhook
+func (s *webhookServiceServer) DeleteWebhook(ctx context.Context, req *v1.DeleteWebhookRequest) (*v1.DeleteWebhookResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	// Delete Webhook, its deliveries are deleted by foreign key
+	res, err := c.ExecContext(ctx, "DELETE FROM webhook WHERE id=?", req.Id)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to delete Webhook-> "+err.Error())
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to retrieve rows affected value-> "+err.Error())
+	}
+
+	if rows == 0 {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("Webhook with ID='%d' is not found", req.Id))
+	}
+
+	return &v1.DeleteWebhookResponse{
+		Api:     API_VERSION,
+		Deleted: rows,
+	}, nil
+}
+
+// deliveryColumns is list of webhook_delivery table columns read into WebhookDelivery entity by queryDeliveries
+const deliveryColumns = "id, webhook_id, event_type, todo_id, state, attempts, response_code, last_error, payload, next_attempt_time, create_time, update_time"
+
+// queryDeliveries returns webhook deliveries selected by query
+func queryDeliveries(ctx context.Context, c queryer, query string, args ...interface{}) ([]*v1.WebhookDelivery, error) {
+	rows, err := c.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to select from webhook_delivery-> "+err.Error())
+	}
+	defer rows.Close()
+
+	list := []*v1.WebhookDelivery{}
+	for rows.Next() {
+		d := new(v1.WebhookDelivery)
+		var eventType, state int32
+		var nextAttemptTime sql.NullTime
+		var createTime, updateTime time.Time
+		if err := rows.Scan(&d.Id, &d.WebhookId, &eventType, &d.TodoId, &state, &d.Attempts, &d.ResponseCode,
+			&d.LastError, &d.Payload, &nextAttemptTime, &createTime, &updateTime); err != nil {
+			return nil, status.Error(codes.Unknown, "failed to retrieve field values from webhook_delivery row-> "+err.Error())
+		}
+		d.EventType = v1.TodoEvent_Type(eventType)
+		d.State = v1.WebhookDelivery_State(state)
+
+		if d.State == v1.WebhookDelivery_PENDING {
+			if d.NextAttemptTime, err = timestampProto(nextAttemptTime); err != nil {
+				return nil, status.Error(codes.Unknown, "next_attempt_time field has invalid format-> "+err.Error())
+			}
+		}
+		if d.CreateTime, err = ptypes.TimestampProto(createTime); err != nil {
+			return nil, status.Error(codes.Unknown, "create_time field has invalid format-> "+err.Error())
+		}
+		if d.UpdateTime, err = ptypes.TimestampProto(updateTime); err != nil {
+			return nil, status.Error(codes.Unknown, "update_time field has invalid format-> "+err.Error())
+		}
+		list = append(list, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, status.Error(codes.Unknown, "failed to retrieve data from webhook_delivery-> "+err.Error())
+	}
+
+	return list, nil
+}
+
+// Read delivery history of webhook
+func (s *webhookServiceServer) ListWebhookDeliveries(ctx context.Context, req *v1.ListWebhookDeliveriesRequest) (*v1.ListWebhookDeliveriesResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultDeliveriesLimit
+	}
+	if limit > maxDeliveriesLimit {
+		limit = maxDeliveriesLimit
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	webhooks, err := queryWebhooks(ctx, c, "SELECT "+webhookColumns+" FROM webhook WHERE id=?", req.WebhookId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(webhooks) == 0 {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("Webhook with ID='%d' is not found", req.WebhookId))
+	}
+
+	where := " WHERE webhook_id=?"
+	args := []interface{}{req.WebhookId}
+	if req.DeadOnly {
+		where += " AND state=?"
+		args = append(args, int32(v1.WebhookDelivery_DEAD))
+	}
+
+	list, err := queryDeliveries(ctx, c, "SELECT "+deliveryColumns+" FROM webhook_delivery"+where+" ORDER BY id DESC LIMIT ?",
+		append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.ListWebhookDeliveriesResponse{
+		Api:        API_VERSION,
+		Deliveries: list,
+	}, nil
+}
+
+// Schedule delivery from dead letter log for retry
+func (s *webhookServiceServer) RetryWebhookDelivery(ctx context.Context, req *v1.RetryWebhookDeliveryRequest) (*v1.RetryWebhookDeliveryResponse, error) {
+	// check if the API version requested by client is supported by server
+	if err := checkAPI(req.Api); err != nil {
+		return nil, err
+	}
+
+	// Get SQL connection from pool
+	c, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	now := time.Now().In(time.UTC)
+	res, err := c.ExecContext(ctx, "UPDATE webhook_delivery SET state=?, attempts=0, next_attempt_time=?, update_time=? WHERE id=? AND state=?",
+		int32(v1.WebhookDelivery_PENDING), now, now, req.Id, int32(v1.WebhookDelivery_DEAD))
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to update WebhookDelivery-> "+err.Error())
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return nil, status.Error(codes.Unknown, "failed to retrieve rows affected value-> "+err.Error())
+	}
+
+	list, err := queryDeliveries(ctx, c, "SELECT "+deliveryColumns+" FROM webhook_delivery WHERE id=?", req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("WebhookDelivery with ID='%d' is not found", req.Id))
+	}
+
+	if rows == 0 {
+		return nil, status.Error(codes.FailedPrecondition, fmt.Sprintf("WebhookDelivery with ID='%d' is not in dead letter log", req.Id))
+	}
+
+	return &v1.RetryWebhookDeliveryResponse{
+		Api:      API_VERSION,
+		Delivery: list[0],
+	}, nil
+}
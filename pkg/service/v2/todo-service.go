@@ -0,0 +1,350 @@
+package v2
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+	v2 "github.com/devararishivian/go-grpc/pkg/api/v2"
+	service "github.com/devararishivian/go-grpc/pkg/service/v1"
+)
+
+const (
+	// defaultPageSize is number of tasks in page of ListTodos if client doesn't ask for
+	defaultPageSize = 100
+
+	// anyList is list ID of resource names matching any list
+	anyList = "-"
+)
+
+// updatableFields are fields of Todo UpdateTodo can change
+var updatableFields = []string{
+	"title", "description", "reminder_time", "status", "priority", "due_time",
+	"labels", "parent", "recurrence", "time_zone",
+}
+
+// todoServiceServer is implementation of v2.TodoServiceServer proto interface,
+// it translates resource-oriented calls to v1 service sharing its storage
+type todoServiceServer struct {
+	v1API service.TodoServer
+	v2.UnimplementedTodoServiceServer
+}
+
+// NewTodoServiceServer returns Todo service v2 built on v1 service
+func NewTodoServiceServer(v1API service.TodoServer) v2.TodoServiceServer {
+	return &todoServiceServer{v1API: v1API}
+}
+
+// listName returns resource name of list
+func listName(listID int64) string {
+	return fmt.Sprintf("lists/%d", listID)
+}
+
+// todoName returns resource name of todo task
+func todoName(listID, id int64) string {
+	return fmt.Sprintf("lists/%d/todos/%d", listID, id)
+}
+
+// parseID parses ID segment of resource name, "-" is 0 if wildcard is allowed
+func parseID(field, name, segment string, wildcard bool) (int64, error) {
+	if wildcard && segment == anyList {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(segment, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, status.Error(codes.InvalidArgument, fmt.Sprintf("%s field has invalid resource name '%s'", field, name))
+	}
+	return id, nil
+}
+
+// parseListName returns list ID of resource name "lists/{list}", 0 for any list
+func parseListName(field, name string) (int64, error) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 2 || parts[0] != "lists" {
+		return 0, status.Error(codes.InvalidArgument, fmt.Sprintf("%s field must be in format 'lists/{list}', got '%s'", field, name))
+	}
+	return parseID(field, name, parts[1], true)
+}
+
+// parseTodoName returns list ID and ID of resource name "lists/{list}/todos/{todo}", list ID is 0 for any list
+func parseTodoName(field, name string) (int64, int64, error) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 4 || parts[0] != "lists" || parts[2] != "todos" {
+		return 0, 0, status.Error(codes.InvalidArgument, fmt.Sprintf("%s field must be in format 'lists/{list}/todos/{todo}', got '%s'", field, name))
+	}
+	listID, err := parseID(field, name, parts[1], true)
+	if err != nil {
+		return 0, 0, err
+	}
+	id, err := parseID(field, name, parts[3], false)
+	if err != nil {
+		return 0, 0, err
+	}
+	return listID, id, nil
+}
+
+// toV2 returns v1 task as v2 resource
+func toV2(td *v1.Todo) *v2.Todo {
+	if td == nil {
+		return nil
+	}
+	res := &v2.Todo{
+		Name:          todoName(td.ListId, td.Id),
+		Title:         td.Title,
+		Description:   td.Description,
+		ReminderTime:  td.Reminder,
+		Status:        v2.Todo_Status(td.Status),
+		Priority:      v2.Todo_Priority(td.Priority),
+		DueTime:       td.DueDate,
+		Labels:        td.Labels,
+		Recurrence:    td.Recurrence,
+		TimeZone:      td.TimeZone,
+		SnoozeTime:    td.SnoozedUntil,
+		CompleteTime:  td.CompletedAt,
+		CreateTime:    td.CreateTime,
+		UpdateTime:    td.UpdateTime,
+		DoneSubtasks:  td.Progress.GetDone(),
+		TotalSubtasks: td.Progress.GetTotal(),
+	}
+	if td.ParentId > 0 {
+		res.Parent = todoName(td.ListId, td.ParentId)
+	}
+	return res
+}
+
+// parentID returns ID of parent task of resource, the parent must be in the list if list ID isn't 0
+func parentID(td *v2.Todo, listID int64) (int64, error) {
+	if len(td.Parent) == 0 {
+		return 0, nil
+	}
+	parentListID, id, err := parseTodoName("parent", td.Parent)
+	if err != nil {
+		return 0, err
+	}
+	if listID > 0 && parentListID > 0 && parentListID != listID {
+		return 0, status.Error(codes.InvalidArgument, fmt.Sprintf("parent task '%s' must be in list '%s'", td.Parent, listName(listID)))
+	}
+	return id, nil
+}
+
+// read reads task by ID, it's not found if it isn't in the list
+func (s *todoServiceServer) read(ctx context.Context, listID, id int64) (*v1.Todo, error) {
+	res, err := s.v1API.Read(ctx, &v1.ReadRequest{Id: id, ListId: listID})
+	if err != nil {
+		return nil, err
+	}
+	return res.Todo, nil
+}
+
+// GetTodo reads todo task
+func (s *todoServiceServer) GetTodo(ctx context.Context, req *v2.GetTodoRequest) (*v2.Todo, error) {
+	listID, id, err := parseTodoName("name", req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	td, err := s.read(ctx, listID, id)
+	if err != nil {
+		return nil, err
+	}
+	return toV2(td), nil
+}
+
+// ListTodos reads page of todo tasks of list matched by filter expression
+func (s *todoServiceServer) ListTodos(ctx context.Context, req *v2.ListTodosRequest) (*v2.ListTodosResponse, error) {
+	listID, err := parseListName("parent", req.Parent)
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := req.PageSize
+	if pageSize < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page_size field can't be negative")
+	}
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+
+	// the list is passed apart from the filter, so the filter can't match tasks of other lists
+	res, err := s.v1API.ListTodosInList(ctx, listID, &v1.ListTodosRequest{
+		Filter:    req.Filter,
+		OrderBy:   req.OrderBy,
+		PageSize:  pageSize,
+		PageToken: req.PageToken,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	todos := make([]*v2.Todo, 0, len(res.Todos))
+	for _, td := range res.Todos {
+		todos = append(todos, toV2(td))
+	}
+	return &v2.ListTodosResponse{
+		Todos:         todos,
+		NextPageToken: res.NextPageToken,
+	}, nil
+}
+
+// CreateTodo creates todo task
+func (s *todoServiceServer) CreateTodo(ctx context.Context, req *v2.CreateTodoRequest) (*v2.Todo, error) {
+	listID, err := parseListName("parent", req.Parent)
+	if err != nil {
+		return nil, err
+	}
+	if req.Todo == nil {
+		return nil, status.Error(codes.InvalidArgument, "todo field is required")
+	}
+	parent, err := parentID(req.Todo, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.v1API.Create(ctx, &v1.CreateRequest{
+		Todo: &v1.Todo{
+			Title:       req.Todo.Title,
+			Description: req.Todo.Description,
+			Reminder:    req.Todo.ReminderTime,
+			Status:      v1.Todo_Status(req.Todo.Status),
+			Priority:    v1.Todo_Priority(req.Todo.Priority),
+			DueDate:     req.Todo.DueTime,
+			Labels:      req.Todo.Labels,
+			ListId:      listID,
+			ParentId:    parent,
+			Recurrence:  req.Todo.Recurrence,
+			TimeZone:    req.Todo.TimeZone,
+		},
+		RequestId: req.RequestId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	td, err := s.read(ctx, 0, res.Id)
+	if err != nil {
+		return nil, err
+	}
+	return toV2(td), nil
+}
+
+// updatePaths returns fields to update by mask, fields set in task if mask is empty
+func updatePaths(td *v2.Todo, mask *fieldmaskpb.FieldMask) ([]string, error) {
+	fields := td.ProtoReflect().Descriptor().Fields()
+
+	if len(mask.GetPaths()) == 0 {
+		var paths []string
+		for _, name := range updatableFields {
+			if td.ProtoReflect().Has(fields.ByName(protoreflect.Name(name))) {
+				paths = append(paths, name)
+			}
+		}
+		return paths, nil
+	}
+
+	if len(mask.Paths) == 1 && mask.Paths[0] == "*" {
+		return updatableFields, nil
+	}
+
+	for _, path := range mask.Paths {
+		updatable := false
+		for _, name := range updatableFields {
+			updatable = updatable || path == name
+		}
+		if !updatable {
+			if fields.ByName(protoreflect.Name(path)) != nil {
+				return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("update_mask field has path '%s' of field which can't be updated", path))
+			}
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("update_mask field has unknown path '%s'", path))
+		}
+	}
+	return mask.Paths, nil
+}
+
+// UpdateTodo updates fields of todo task
+func (s *todoServiceServer) UpdateTodo(ctx context.Context, req *v2.UpdateTodoRequest) (*v2.Todo, error) {
+	if req.Todo == nil {
+		return nil, status.Error(codes.InvalidArgument, "todo field is required")
+	}
+	listID, id, err := parseTodoName("todo.name", req.Todo.Name)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := updatePaths(req.Todo, req.UpdateMask)
+	if err != nil {
+		return nil, err
+	}
+
+	// the task is changed in one transaction of v1 storage, so concurrent updates don't overwrite
+	// fields of each other
+	td, err := s.v1API.UpdateTodo(ctx, listID, id, func(td *v1.Todo) error {
+		for _, path := range paths {
+			switch path {
+			case "title":
+				td.Title = req.Todo.Title
+			case "description":
+				td.Description = req.Todo.Description
+			case "reminder_time":
+				td.Reminder = req.Todo.ReminderTime
+			case "status":
+				td.Status = v1.Todo_Status(req.Todo.Status)
+			case "priority":
+				td.Priority = v1.Todo_Priority(req.Todo.Priority)
+			case "due_time":
+				td.DueDate = req.Todo.DueTime
+			case "labels":
+				td.Labels = req.Todo.Labels
+			case "parent":
+				parent, err := parentID(req.Todo, td.ListId)
+				if err != nil {
+					return err
+				}
+				td.ParentId = parent
+			case "recurrence":
+				td.Recurrence = req.Todo.Recurrence
+			case "time_zone":
+				td.TimeZone = req.Todo.TimeZone
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toV2(td), nil
+}
+
+// DeleteTodo deletes todo task with its subtasks
+func (s *todoServiceServer) DeleteTodo(ctx context.Context, req *v2.DeleteTodoRequest) (*emptypb.Empty, error) {
+	listID, id, err := parseTodoName("name", req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.v1API.DeleteTodo(ctx, listID, id); err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// CompleteTodo marks todo task as done
+func (s *todoServiceServer) CompleteTodo(ctx context.Context, req *v2.CompleteTodoRequest) (*v2.CompleteTodoResponse, error) {
+	listID, id, err := parseTodoName("name", req.Name)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.v1API.CompleteTodo(ctx, listID, id)
+	if err != nil {
+		return nil, err
+	}
+	return &v2.CompleteTodoResponse{
+		Todo: toV2(res.Todo),
+		Next: toV2(res.Next),
+	}, nil
+}
@@ -0,0 +1,132 @@
+package v2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	v1 "github.com/devararishivian/go-grpc/pkg/api/v1"
+	v2 "github.com/devararishivian/go-grpc/pkg/api/v2"
+	service "github.com/devararishivian/go-grpc/pkg/service/v1"
+)
+
+// fakeV1 is v1 service recording calls of v2, calls which aren't overridden panic
+type fakeV1 struct {
+	service.TodoServer
+
+	listID  int64
+	list    *v1.ListTodosRequest
+	created *v1.Todo
+	todo    *v1.Todo
+}
+
+func (f *fakeV1) ListTodosInList(ctx context.Context, listID int64, req *v1.ListTodosRequest) (*v1.ListTodosResponse, error) {
+	f.listID, f.list = listID, req
+	return &v1.ListTodosResponse{Todos: []*v1.Todo{f.todo}}, nil
+}
+
+func (f *fakeV1) Create(ctx context.Context, req *v1.CreateRequest) (*v1.CreateResponse, error) {
+	f.created = req.Todo
+	return &v1.CreateResponse{Id: f.todo.Id}, nil
+}
+
+func (f *fakeV1) Read(ctx context.Context, req *v1.ReadRequest) (*v1.ReadResponse, error) {
+	return &v1.ReadResponse{Todo: f.todo}, nil
+}
+
+func (f *fakeV1) UpdateTodo(ctx context.Context, listID, id int64, change func(td *v1.Todo) error) (*v1.Todo, error) {
+	f.listID = listID
+	if err := change(f.todo); err != nil {
+		return nil, err
+	}
+	return f.todo, nil
+}
+
+func (f *fakeV1) DeleteTodo(ctx context.Context, listID, id int64) (int64, error) {
+	f.listID = listID
+	return 1, nil
+}
+
+func (f *fakeV1) CompleteTodo(ctx context.Context, listID, id int64) (*v1.CompleteResponse, error) {
+	f.listID = listID
+	return &v1.CompleteResponse{Todo: f.todo}, nil
+}
+
+func TestListTodosPassesListApart(t *testing.T) {
+	// filter closing parenthesis of list condition must not reach tasks of other lists
+	const breakout = `title = "a") OR (id > 0`
+
+	f := &fakeV1{todo: &v1.Todo{Id: 1, ListId: 2}}
+	s := NewTodoServiceServer(f)
+
+	if _, err := s.ListTodos(context.Background(), &v2.ListTodosRequest{Parent: "lists/2", Filter: breakout}); err != nil {
+		t.Fatal(err)
+	}
+	if f.listID != 2 || f.list.Filter != breakout {
+		t.Errorf("v1 called with list %d and filter %q, want list 2 and filter as is", f.listID, f.list.Filter)
+	}
+}
+
+func TestCreateTodoWithoutReminder(t *testing.T) {
+	f := &fakeV1{todo: &v1.Todo{Id: 1, ListId: 2}}
+	s := NewTodoServiceServer(f)
+
+	if _, err := s.CreateTodo(context.Background(), &v2.CreateTodoRequest{Parent: "lists/2", Todo: &v2.Todo{Title: "Call Bob"}}); err != nil {
+		t.Fatal(err)
+	}
+	if f.created.Reminder != nil {
+		t.Errorf("reminder = %v, want none", f.created.Reminder)
+	}
+}
+
+func TestUpdateTodoMask(t *testing.T) {
+	reminder := ptypes.TimestampNow()
+	f := &fakeV1{todo: &v1.Todo{Id: 1, ListId: 2, Title: "Call Bob", Description: "about lunch", Reminder: reminder}}
+	s := NewTodoServiceServer(f)
+
+	got, err := s.UpdateTodo(context.Background(), &v2.UpdateTodoRequest{
+		Todo:       &v2.Todo{Name: "lists/2/todos/1", Title: "Call Alice", Labels: []string{"home"}, Parent: "lists/2/todos/3"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title", "labels", "parent"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.listID != 2 {
+		t.Errorf("v1 called with list %d, want 2", f.listID)
+	}
+	if got.Title != "Call Alice" || got.Description != "about lunch" || got.ReminderTime != reminder ||
+		len(got.Labels) != 1 || got.Parent != "lists/2/todos/3" {
+		t.Errorf("UpdateTodo() = %v, want title, labels and parent changed only", got)
+	}
+
+	_, err = s.UpdateTodo(context.Background(), &v2.UpdateTodoRequest{
+		Todo:       &v2.Todo{Name: "lists/2/todos/1", Parent: "lists/3/todos/3"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"parent"}},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("UpdateTodo() error = %v, want code %s for parent in another list", err, codes.InvalidArgument)
+	}
+}
+
+func TestDeleteAndCompleteInList(t *testing.T) {
+	f := &fakeV1{todo: &v1.Todo{Id: 1, ListId: 2}}
+	s := NewTodoServiceServer(f)
+
+	if _, err := s.DeleteTodo(context.Background(), &v2.DeleteTodoRequest{Name: "lists/2/todos/1"}); err != nil {
+		t.Fatal(err)
+	}
+	if f.listID != 2 {
+		t.Errorf("DeleteTodo() checked list %d, want 2", f.listID)
+	}
+
+	if _, err := s.CompleteTodo(context.Background(), &v2.CompleteTodoRequest{Name: "lists/-/todos/1"}); err != nil {
+		t.Fatal(err)
+	}
+	if f.listID != 0 {
+		t.Errorf("CompleteTodo() checked list %d, want any list", f.listID)
+	}
+}
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS todo (
+    id          BIGINT       NOT NULL AUTO_INCREMENT,
+    title       VARCHAR(200) NOT NULL,
+    description VARCHAR(1024),
+    reminder    TIMESTAMP    NULL,
+    PRIMARY KEY (id)
+);
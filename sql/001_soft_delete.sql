@@ -0,0 +1,4 @@
+-- Delete marks todo task as deleted, it is purged after retention
+ALTER TABLE todo
+    ADD COLUMN deleted_at TIMESTAMP NULL DEFAULT NULL,
+    ADD INDEX idx_todo_deleted_at (deleted_at);
@@ -0,0 +1,9 @@
+-- Progress, due date and priority of todo task
+-- status and priority keep numbers of Todo.Status and Todo.Priority enums
+ALTER TABLE todo
+    ADD COLUMN status       TINYINT   NOT NULL DEFAULT 1,
+    ADD COLUMN completed_at TIMESTAMP NULL DEFAULT NULL,
+    ADD COLUMN due_date     TIMESTAMP NULL DEFAULT NULL,
+    ADD COLUMN priority     TINYINT   NOT NULL DEFAULT 0,
+    ADD INDEX idx_todo_status (status),
+    ADD INDEX idx_todo_due_date (due_date);
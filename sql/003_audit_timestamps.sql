@@ -0,0 +1,6 @@
+-- Creation and last modification time of todo task, set by the service
+ALTER TABLE todo
+    ADD COLUMN create_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN update_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD INDEX idx_todo_create_time (create_time),
+    ADD INDEX idx_todo_update_time (update_time);
@@ -0,0 +1,3 @@
+-- Full-text index used by Search
+ALTER TABLE todo
+    ADD FULLTEXT INDEX ft_todo_title_description (title, description);
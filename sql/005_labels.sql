@@ -0,0 +1,8 @@
+-- Labels of todo tasks
+CREATE TABLE IF NOT EXISTS todo_label (
+    todo_id BIGINT      NOT NULL,
+    label   VARCHAR(64) NOT NULL,
+    PRIMARY KEY (todo_id, label),
+    INDEX idx_todo_label_label (label),
+    FOREIGN KEY (todo_id) REFERENCES todo (id) ON DELETE CASCADE
+);
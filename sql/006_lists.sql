@@ -0,0 +1,15 @@
+-- Lists (projects) of todo tasks, every task belongs to one list
+CREATE TABLE IF NOT EXISTS todo_list (
+    id          BIGINT       NOT NULL AUTO_INCREMENT,
+    title       VARCHAR(200) NOT NULL,
+    create_time TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    update_time TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id)
+);
+
+-- Default list, existing tasks are moved to it
+INSERT INTO todo_list (id, title) VALUES (1, 'Inbox');
+
+ALTER TABLE todo
+    ADD COLUMN list_id BIGINT NOT NULL DEFAULT 1,
+    ADD FOREIGN KEY fk_todo_list (list_id) REFERENCES todo_list (id);
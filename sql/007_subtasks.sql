@@ -0,0 +1,4 @@
+-- Subtasks, parent_id is 0 for top-level tasks
+ALTER TABLE todo
+    ADD COLUMN parent_id BIGINT NOT NULL DEFAULT 0,
+    ADD INDEX idx_todo_parent_id (parent_id);
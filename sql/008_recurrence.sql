@@ -0,0 +1,5 @@
+-- Recurring todo task, recurrence is iCalendar RRULE, empty for one-off task
+ALTER TABLE todo
+    ADD COLUMN recurrence       VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN time_zone        VARCHAR(64)  NOT NULL DEFAULT '',
+    ADD COLUMN recurrence_start TIMESTAMP    NULL DEFAULT NULL;
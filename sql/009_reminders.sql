@@ -0,0 +1,18 @@
+-- Reminder delivery, snoozed_until overrides reminder time until the reminder is changed
+ALTER TABLE todo
+    ADD COLUMN snoozed_until TIMESTAMP NULL DEFAULT NULL;
+
+-- Delivery state of reminders per reminder time and notifier
+-- state is 0 for delivery to retry, 1 for delivered and 2 for given up
+CREATE TABLE IF NOT EXISTS reminder_delivery (
+    todo_id           BIGINT      NOT NULL,
+    fire_time         TIMESTAMP   NOT NULL,
+    notifier          VARCHAR(32) NOT NULL,
+    state             TINYINT     NOT NULL,
+    attempts          INT         NOT NULL DEFAULT 0,
+    last_error        TEXT        NOT NULL,
+    next_attempt_time TIMESTAMP   NULL DEFAULT NULL,
+    update_time       TIMESTAMP   NOT NULL,
+    PRIMARY KEY (todo_id, fire_time, notifier),
+    FOREIGN KEY (todo_id) REFERENCES todo (id) ON DELETE CASCADE
+);
@@ -0,0 +1,22 @@
+-- Change events of todo tasks streamed by WatchTodos,
+-- type keeps numbers of TodoEvent.Type enum and todo is protobuf encoded Todo after change
+CREATE TABLE IF NOT EXISTS todo_event (
+    id          BIGINT     NOT NULL,
+    type        TINYINT    NOT NULL,
+    todo_id     BIGINT     NOT NULL,
+    list_id     BIGINT     NOT NULL,
+    todo        MEDIUMBLOB NOT NULL,
+    create_time TIMESTAMP  NOT NULL,
+    PRIMARY KEY (id),
+    INDEX idx_todo_event_list_id (list_id, id),
+    INDEX idx_todo_event_create_time (create_time)
+);
+
+-- Next event ID, the row is locked by changes until commit to keep events in commit order
+CREATE TABLE IF NOT EXISTS todo_event_seq (
+    id      TINYINT NOT NULL,
+    next_id BIGINT  NOT NULL,
+    PRIMARY KEY (id)
+);
+
+INSERT INTO todo_event_seq(id, next_id) VALUES(1, 1);
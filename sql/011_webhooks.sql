@@ -0,0 +1,42 @@
+-- Webhooks, event_types is comma separated numbers of TodoEvent.Type enum, empty for all types.
+-- Events up to start_event_id were recorded before the webhook was registered and are not posted to it
+CREATE TABLE IF NOT EXISTS webhook (
+    id             BIGINT        NOT NULL AUTO_INCREMENT,
+    url            VARCHAR(2048) NOT NULL,
+    secret         VARCHAR(128)  NOT NULL,
+    event_types    VARCHAR(64)   NOT NULL DEFAULT '',
+    list_id        BIGINT        NOT NULL DEFAULT 0,
+    start_event_id BIGINT        NOT NULL,
+    create_time    TIMESTAMP     NOT NULL,
+    PRIMARY KEY (id)
+);
+
+-- Deliveries of change events to webhooks, state keeps numbers of WebhookDelivery.State enum
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+    id                BIGINT     NOT NULL AUTO_INCREMENT,
+    webhook_id        BIGINT     NOT NULL,
+    event_id          BIGINT     NOT NULL,
+    event_type        TINYINT    NOT NULL,
+    todo_id           BIGINT     NOT NULL,
+    state             TINYINT    NOT NULL,
+    attempts          INT        NOT NULL DEFAULT 0,
+    response_code     INT        NOT NULL DEFAULT 0,
+    last_error        TEXT       NOT NULL,
+    payload           MEDIUMTEXT NOT NULL,
+    next_attempt_time TIMESTAMP  NULL DEFAULT NULL,
+    create_time       TIMESTAMP  NOT NULL,
+    update_time       TIMESTAMP  NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY uk_webhook_delivery_event (webhook_id, event_id),
+    INDEX idx_webhook_delivery_state (state, next_attempt_time),
+    FOREIGN KEY (webhook_id) REFERENCES webhook (id) ON DELETE CASCADE
+);
+
+-- ID of the last change event fanned out to webhook deliveries
+CREATE TABLE IF NOT EXISTS webhook_cursor (
+    id            TINYINT NOT NULL,
+    last_event_id BIGINT  NOT NULL,
+    PRIMARY KEY (id)
+);
+
+INSERT INTO webhook_cursor(id, last_event_id) SELECT 1, next_id - 1 FROM todo_event_seq WHERE id = 1;
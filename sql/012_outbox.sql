@@ -0,0 +1,8 @@
+-- Positions of outbox relay sinks in todo_event table, which is the outbox of change events.
+-- Events are purged after event retention only once every sink has relayed them,
+-- position of a sink which is no longer configured keeps events until outbox cursor timeout
+CREATE TABLE IF NOT EXISTS outbox_cursor (
+    name          VARCHAR(32) NOT NULL,
+    last_event_id BIGINT      NOT NULL,
+    PRIMARY KEY (name)
+);
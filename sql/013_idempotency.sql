@@ -0,0 +1,10 @@
+-- Idempotency keys of Create requests, request_hash is SHA-256 of the requested task
+-- to reject reuse of a key with different task. Keys are purged after idempotency window
+CREATE TABLE IF NOT EXISTS idempotency_key (
+    request_id   VARCHAR(128) NOT NULL,
+    request_hash CHAR(64)     NOT NULL,
+    todo_id      BIGINT       NOT NULL,
+    create_time  TIMESTAMP    NOT NULL,
+    PRIMARY KEY (request_id),
+    INDEX idx_idempotency_key_create_time (create_time)
+);
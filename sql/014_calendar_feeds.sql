@@ -0,0 +1,12 @@
+-- Secret calendar feeds, only SHA-256 of the token in the feed path is stored.
+-- list_id is 0 for feed of all lists
+CREATE TABLE IF NOT EXISTS calendar_feed (
+    id               BIGINT       NOT NULL AUTO_INCREMENT,
+    owner            VARCHAR(255) NOT NULL,
+    token_hash       CHAR(64)     NOT NULL,
+    list_id          BIGINT       NOT NULL DEFAULT 0,
+    create_time      TIMESTAMP    NOT NULL,
+    last_access_time TIMESTAMP    NULL,
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_calendar_feed_token_hash (token_hash)
+);
@@ -0,0 +1,4 @@
+-- Description is empty string instead of NULL, tasks are read into plain strings
+UPDATE todo SET description = '' WHERE description IS NULL;
+ALTER TABLE todo
+    MODIFY description VARCHAR(1024) NOT NULL DEFAULT '';
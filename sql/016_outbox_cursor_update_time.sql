@@ -0,0 +1,4 @@
+-- Time outbox relay sink last moved its position, cursor of sink which doesn't move
+-- for outbox cursor timeout is expired and doesn't keep events from purge
+ALTER TABLE outbox_cursor
+    ADD COLUMN update_time TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
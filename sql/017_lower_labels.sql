@@ -0,0 +1,2 @@
+-- Labels are stored in lower case, labels differing in case only are one label
+UPDATE todo_label SET label = LOWER(label);
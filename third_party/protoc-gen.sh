@@ -1,3 +1,5 @@
 # protoc --proto_path=api/proto/v1 --proto_path=third_party --go_out=pkg/api/v1 --go_opt=paths=source_relative --go-grpc_out=pkg/api/v1 --go-grpc_opt=paths=source_relative --grpc-gateway_out=pkg/api/v1 --grpc-gateway_opt logtostderr=true --grpc-gateway_opt paths=source_relative --grpc-gateway_opt generate_unbound_methods=true --swagger_out=logtostderr=true:api/swagger/v1 todo-service.proto
 
-protoc --proto_path=api/proto/v1 --go_out=pkg/api/v1 --go_opt=paths=source_relative --go-grpc_out=pkg/api/v1 --go-grpc_opt=paths=source_relative --grpc-gateway_out=pkg/api/v1 --grpc-gateway_opt logtostderr=true --grpc-gateway_opt paths=source_relative --grpc-gateway_opt grpc_api_configuration=api/proto/v1/todo-service.yaml todo-service.proto
\ No newline at end of file
+protoc --proto_path=api/proto/v1 --proto_path=third_party --go_out=pkg/api/v1 --go_opt=paths=source_relative --go-grpc_out=pkg/api/v1 --go-grpc_opt=paths=source_relative --grpc-gateway_out=pkg/api/v1 --grpc-gateway_opt logtostderr=true --grpc-gateway_opt paths=source_relative --grpc-gateway_opt grpc_api_configuration=api/proto/v1/todo-service.yaml --openapiv2_out=logtostderr=true,grpc_api_configuration=api/proto/v1/todo-service.yaml,openapi_configuration=api/proto/v1/todo-service.openapi.yaml:api/swagger/v1 todo-service.proto
+# v2 is generated from proto path of all versions, so its file is registered as "v2/todo-service.proto" and doesn't conflict with v1
+protoc --proto_path=api/proto --go_out=pkg/api --go_opt=paths=source_relative --go-grpc_out=pkg/api --go-grpc_opt=paths=source_relative --grpc-gateway_out=pkg/api --grpc-gateway_opt logtostderr=true --grpc-gateway_opt paths=source_relative --grpc-gateway_opt grpc_api_configuration=api/proto/v2/todo-service.yaml --openapiv2_out=logtostderr=true,grpc_api_configuration=api/proto/v2/todo-service.yaml,openapi_configuration=api/proto/v2/todo-service.openapi.yaml:api/swagger v2/todo-service.proto